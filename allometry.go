@@ -0,0 +1,189 @@
+package carbon_calc
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// Tree measurement used by the allometric equation
+// radius - radius of tree (m)
+// height - height of tree (m)
+// density - basic wood density (g/cm^3 or t/m^3)
+// form - form factor of the tree, form factor model only
+// biomass - biomass expansion factor, form factor model only
+type TreeMeasurement struct {
+	Radius  decimal.Decimal
+	Height  decimal.Decimal
+	Density decimal.Decimal
+	Form    decimal.Decimal
+	Biomass decimal.Decimal
+}
+
+// Diameter at breast height (cm)
+func (m TreeMeasurement) Diameter() float64 {
+	return m.Radius.InexactFloat64() * 200
+}
+
+// Equation to estimate above-ground biomass of the tree
+type AllometricEquation interface {
+	Name() string
+	// Above-ground biomass of the tree (t d.m.)
+	AboveGroundBiomass(m TreeMeasurement) decimal.Decimal
+}
+
+// Volume model used by CarbonPerTree:
+// AGB = CircleArea(radius) * height * form * 1.2 * density * biomass
+type FormFactorEquation struct{}
+
+func (FormFactorEquation) Name() string {
+	return "form-factor"
+}
+
+func (FormFactorEquation) AboveGroundBiomass(m TreeMeasurement) decimal.Decimal {
+	form := m.Form
+	if form.Equal(decimal.Zero) {
+		form = decimal.NewFromFloat(0.25)
+	}
+	return CircleArea(m.Radius).
+		Mul(m.Height).
+		Mul(form).
+		Mul(decimal.NewFromFloat(1.2)).
+		Mul(m.Density).
+		Mul(m.Biomass)
+}
+
+// Pantropical equation of Chave et al. 2014:
+// AGB (kg) = 0.0673 * (density * D^2 * height)^0.976
+type Chave2014Equation struct{}
+
+func (Chave2014Equation) Name() string {
+	return "chave-2014"
+}
+
+func (Chave2014Equation) AboveGroundBiomass(m TreeMeasurement) decimal.Decimal {
+	d := m.Diameter()
+	value := 0.0673 * math.Pow(m.Density.InexactFloat64()*d*d*m.Height.InexactFloat64(), 0.976)
+	return kilogramsToTonnes(value)
+}
+
+// Tropical equations of Brown 1997 by rainfall:
+// dry - AGB (kg) = exp(-1.996 + 2.32 * ln(D))
+// moist - AGB (kg) = exp(-2.134 + 2.53 * ln(D))
+// wet - AGB (kg) = 21.297 - 6.953 * D + 0.74 * D^2
+type Brown1997Equation struct {
+	Rainfall RainfallType
+}
+
+func (e Brown1997Equation) Name() string {
+	return "brown-1997-" + e.Rainfall.String()
+}
+
+func (e Brown1997Equation) AboveGroundBiomass(m TreeMeasurement) decimal.Decimal {
+	d := m.Diameter()
+	var value float64
+	switch e.Rainfall {
+	case RainfallTypeDry:
+		value = math.Exp(-1.996 + 2.32*math.Log(d))
+	case RainfallTypeWet:
+		value = 21.297 - 6.953*d + 0.74*d*d
+	default:
+		value = math.Exp(-2.134 + 2.53*math.Log(d))
+	}
+	return kilogramsToTonnes(value)
+}
+
+// Generic power-law equation:
+// AGB (kg) = a * D^b * height^c
+// c - 0 if height is not used
+type PowerLawEquation struct {
+	A, B, C float64
+}
+
+func (e PowerLawEquation) Name() string {
+	return "power-law"
+}
+
+func (e PowerLawEquation) AboveGroundBiomass(m TreeMeasurement) decimal.Decimal {
+	value := e.A * math.Pow(m.Diameter(), e.B) * math.Pow(m.Height.InexactFloat64(), e.C)
+	return kilogramsToTonnes(value)
+}
+
+// Generic log-log equation with correction factor for the bias of
+// log-transformation:
+// AGB (kg) = cf * exp(a + b * ln(D) + c * ln(height))
+// c - 0 if height is not used
+// cf - correction factor, usually exp(SEE^2 / 2), 0 if you want to get default
+// value 1
+type LogLogEquation struct {
+	A, B, C, CF float64
+}
+
+func (e LogLogEquation) Name() string {
+	return "log-log"
+}
+
+func (e LogLogEquation) AboveGroundBiomass(m TreeMeasurement) decimal.Decimal {
+	cf := e.CF
+	if cf == 0 {
+		cf = 1
+	}
+	value := cf * math.Exp(e.A+e.B*math.Log(m.Diameter())+e.C*math.Log(m.Height.InexactFloat64()))
+	return kilogramsToTonnes(value)
+}
+
+func kilogramsToTonnes(value float64) decimal.Decimal {
+	return decimal.NewFromFloat(value).Div(decimal.New(1000, 0))
+}
+
+// Built-in allometric equation by name, the equations with coefficients
+// (power-law, log-log) should be constructed directly
+func AllometricEquationByName(name string) (AllometricEquation, error) {
+	for _, equation := range []AllometricEquation{
+		FormFactorEquation{},
+		Chave2014Equation{},
+		Brown1997Equation{Rainfall: RainfallTypeDry},
+		Brown1997Equation{Rainfall: RainfallTypeMoist},
+		Brown1997Equation{Rainfall: RainfallTypeWet},
+	} {
+		if equation.Name() == name {
+			return equation, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown allometric equation %q.", name)
+}
+
+// Calculate the carbon stored in the tree using the allometric equation
+// fraction - carbon fraction of tree biomass, 0 if you want to get default
+// value 0.47
+// ratio - root-shoot ratio for tree depending on its specie / forest type
+func CarbonPerTreeAllometric(equation AllometricEquation, m TreeMeasurement, fraction, ratio decimal.Decimal) decimal.Decimal {
+	return carbonPerTreeAllometric(nil, equation, m, zeroDefault(fraction), ratio)
+}
+
+// Calculate the carbon stored in the tree using the allometric equation, nil
+// fraction is replaced with default value 0.47, zero fraction is used as is,
+// returns the defaults substituted for the options not provided
+func CarbonPerTreeAllometricWith(equation AllometricEquation, m TreeMeasurement, fraction *decimal.Decimal, ratio decimal.Decimal) (decimal.Decimal, Defaults) {
+	tr := NewTrace("CarbonPerTreeAllometricWith")
+	result := carbonPerTreeAllometric(tr, equation, m, fraction, ratio)
+	return result, tr.defaults()
+}
+
+func carbonPerTreeAllometric(tr *Trace, equation AllometricEquation, m TreeMeasurement, fractionOption *decimal.Decimal, ratio decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("CarbonPerTreeAllometric").
+		Of(equation.Name())
+	fraction := tr.optional("fraction", fractionOption, decimal.NewFromFloat(0.47))
+	tr.Input("radius", m.Radius).
+		Input("height", m.Height).
+		Input("density", m.Density).
+		Input("ratio", ratio)
+	agb := tr.Step("AboveGroundBiomass").
+		Of(equation.Name()).
+		Result(equation.AboveGroundBiomass(m))
+	return tr.Result(decimal.NewFromFloat(44.0 / 12.0).
+		Mul(fraction).
+		Mul(agb).
+		Mul(decimal.New(1, 0).Add(ratio)))
+}
@@ -0,0 +1,168 @@
+package carbon_calc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAllometricEquation(t *testing.T) {
+	type Test struct {
+		equation AllometricEquation
+		result   float64 // precision = 5
+	}
+	m := TreeMeasurement{
+		Radius:  decimal.NewFromFloat(0.05),
+		Height:  decimal.NewFromFloat(10),
+		Density: decimal.NewFromFloat(0.6),
+	}
+	tests := []Test{
+		{Chave2014Equation{}, 0.03463},
+		{Brown1997Equation{Rainfall: RainfallTypeDry}, 0.02839},
+		{Brown1997Equation{Rainfall: RainfallTypeMoist}, 0.04011},
+		{Brown1997Equation{Rainfall: RainfallTypeWet}, 0.02577},
+		{PowerLawEquation{A: 0.1, B: 2.5, C: 0.5}, 0.1},
+		{LogLogEquation{A: -2, B: 2.4, C: 0.3, CF: 1.05}, 0.07122},
+	}
+	for i, tt := range tests {
+		result := tt.equation.AboveGroundBiomass(m)
+		rounded, err := strconv.ParseFloat(fmt.Sprintf("%.5f", result.InexactFloat64()), 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rounded != tt.result {
+			t.Fatalf("Test number %d (%s), expect: %f, have: %f", i, tt.equation.Name(), tt.result, result.InexactFloat64())
+		}
+	}
+}
+
+func TestCarbonPerTreeAllometricFormFactor(t *testing.T) {
+	type Test struct {
+		fraction, radius, height, form, density, biomass, ratio float64
+	}
+	tests := []Test{
+		{0.47, 0.05, 5, 0.25, 0.55, 1.15, 0.3},
+		{0, 0.03, 1.53, 0, 0.55, 1.15, 0.3},
+		{0.5, 0.036, 1.9, 0.3, 0.45, 1.3, 0.24},
+	}
+	for i, tt := range tests {
+		m := TreeMeasurement{
+			Radius:  decimal.NewFromFloat(tt.radius),
+			Height:  decimal.NewFromFloat(tt.height),
+			Density: decimal.NewFromFloat(tt.density),
+			Form:    decimal.NewFromFloat(tt.form),
+			Biomass: decimal.NewFromFloat(tt.biomass),
+		}
+		result := CarbonPerTreeAllometric(FormFactorEquation{}, m, decimal.NewFromFloat(tt.fraction), decimal.NewFromFloat(tt.ratio))
+		expect := CarbonPerTree(
+			decimal.NewFromFloat(tt.fraction),
+			m.Radius,
+			m.Height,
+			m.Form,
+			m.Density,
+			m.Biomass,
+			decimal.NewFromFloat(tt.ratio))
+		if !result.Round(12).Equal(expect.Round(12)) {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, expect, result)
+		}
+	}
+}
+
+func TestAllometricEquationByName(t *testing.T) {
+	for _, name := range []string{"form-factor", "chave-2014", "brown-1997-dry", "brown-1997-moist", "brown-1997-wet"} {
+		equation, err := AllometricEquationByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if equation.Name() != name {
+			t.Fatalf("expect: %s, have: %s", name, equation.Name())
+		}
+	}
+	if _, err := AllometricEquationByName("unknown"); err == nil {
+		t.Fatalf("Unknown equation should return error")
+	}
+}
+
+func TestStageAllometricEquation(t *testing.T) {
+	stage := testStage()
+	stage.Zones[1].Equation = Chave2014Equation{}
+	stage.Zones[1].Plots[0].Trees[0].ScientificName = "Tectona grandis"
+	stage.Equations = map[string]AllometricEquation{
+		"tectona  Grandis L.f.": Brown1997Equation{Rainfall: RainfallTypeMoist},
+	}
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type Test struct {
+		tree     Tree
+		equation AllometricEquation
+		result   decimal.Decimal
+	}
+	tests := []Test{
+		{stage.Zones[1].Plots[0].Trees[0], Brown1997Equation{Rainfall: RainfallTypeMoist}, result.Zones[1].Plots[0].Trees[0].Carbon},
+		{stage.Zones[1].Plots[0].Trees[1], Chave2014Equation{}, result.Zones[1].Plots[0].Trees[1].Carbon},
+		{stage.Zones[0].Plots[0].Trees[0], FormFactorEquation{}, result.Zones[0].Plots[0].Trees[0].Carbon},
+	}
+	for i, tt := range tests {
+		m := TreeMeasurement{
+			Radius:  tt.tree.Radius,
+			Height:  tt.tree.Height,
+			Density: *tt.tree.Density,
+			Form:    *tt.tree.Form,
+			Biomass: *tt.tree.Biomass,
+		}
+		expect := CarbonPerTreeAllometric(tt.equation, m, *tt.tree.Fraction, *tt.tree.Ratio)
+		if !tt.result.Round(12).Equal(expect.Round(12)) {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, expect, tt.result)
+		}
+	}
+}
+
+func TestStageEquationNames(t *testing.T) {
+	stage := testStage()
+	stage.Zones[1].Equation = Chave2014Equation{}
+	stage.Zones[1].Plots[0].Trees[0].ScientificName = "Tectona grandis"
+	stage.Equations = map[string]AllometricEquation{
+		"Tectona grandis": Brown1997Equation{Rainfall: RainfallTypeMoist},
+	}
+	expect, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage.Zones[1].Equation = nil
+	stage.Zones[1].EquationName = "chave-2014"
+	stage.Equations = nil
+	stage.EquationNames = map[string]string{"TECTONA GRANDIS": "brown-1997-moist"}
+	data, err := json.Marshal(stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Stage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	result, err := decoded.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tree := range result.Zones[1].Plots[0].Trees {
+		if have := tree.Carbon; !have.Equal(expect.Zones[1].Plots[0].Trees[i].Carbon) {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, expect.Zones[1].Plots[0].Trees[i].Carbon, have)
+		}
+	}
+
+	decoded.Zones[1].EquationName = "unknown"
+	decoded.EquationNames["tectona grandis"] = "brown-1997-moist"
+	var errs ValidationErrors
+	if _, err := decoded.Calculate(); !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expect 2 validation errors, have: %v", err)
+	}
+	if errs[0].Rule != RuleUnique || errs[1].Rule != RuleKnown {
+		t.Fatalf("Wrong validation errors: %v", errs)
+	}
+}
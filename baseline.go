@@ -0,0 +1,272 @@
+package carbon_calc
+
+import (
+	"errors"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrNoGrowthModel = errors.New("Baseline curve should have a growth model.")
+
+var ErrNegativeYears = errors.New("Length of the baseline curve should not be negative.")
+
+// Default annual above-ground biomass growth of the natural forests older than
+// 20 years (t d.m./ha/yr), representative values of IPCC 2006 Volume 4 Table
+// 4.9, use the regional values of the table where available
+var BaselineGrowthDict map[ForestType]float64 = map[ForestType]float64{
+	ForestTypeTropicalSubtropical: 2,
+	ForestTypeTemperate:           3,
+	ForestTypeBoreal:              1,
+}
+
+// Default annual above-ground biomass growth of the pre-existing trees
+// depending on forest type (t d.m./ha/yr)
+func BaselineGrowthRate(forestType ForestType) decimal.Decimal {
+	return decimal.NewFromFloat(BaselineGrowthDict[forestType])
+}
+
+// Growth model of the pre-existing trees
+type GrowthModel interface {
+	Name() string
+	// Above-ground biomass (t d.m./ha) after the years of growth from the
+	// initial above-ground biomass (t d.m./ha)
+	Biomass(initial, years decimal.Decimal) decimal.Decimal
+}
+
+// Constant annual growth
+// rate - annual above-ground biomass growth (t d.m./ha/yr)
+// max - biomass the growth stops at (t d.m./ha), 0 if growth is not limited
+type LinearGrowth struct {
+	Rate decimal.Decimal `json:"rate"`
+	Max  decimal.Decimal `json:"max"`
+}
+
+func (g LinearGrowth) Name() string {
+	return "linear"
+}
+
+// Check the growth model, returns ValidationErrors with all problems found
+func (g LinearGrowth) Validate() error {
+	v := &validator{}
+	v.validateGrowth("growth", g)
+	return v.errs.err()
+}
+
+func (g LinearGrowth) Biomass(initial, years decimal.Decimal) decimal.Decimal {
+	biomass := initial.Add(g.Rate.Mul(years))
+	if g.Max.IsPositive() && biomass.GreaterThan(g.Max) {
+		return decimal.Max(initial, g.Max)
+	}
+	return biomass
+}
+
+// Chapman-Richards growth curve B(age) = max * (1 - e^(-rate*age))^shape, the
+// age of the pre-existing trees is derived from the initial biomass
+// max - asymptotic above-ground biomass (t d.m./ha)
+// rate, shape - parameters of the curve
+type ChapmanRichards struct {
+	Max   float64 `json:"max"`
+	Rate  float64 `json:"rate"`
+	Shape float64 `json:"shape"`
+}
+
+func (g ChapmanRichards) Name() string {
+	return "chapman-richards"
+}
+
+// Check the growth model, the curve is not defined for the parameters that are
+// not positive, returns ValidationErrors with all problems found
+func (g ChapmanRichards) Validate() error {
+	v := &validator{}
+	v.validateGrowth("growth", g)
+	return v.errs.err()
+}
+
+func (g ChapmanRichards) Biomass(initial, years decimal.Decimal) decimal.Decimal {
+	b := initial.InexactFloat64()
+	if b >= g.Max {
+		return initial
+	}
+	age := -math.Log(1-math.Pow(b/g.Max, 1/g.Shape)) / g.Rate
+	age += years.InexactFloat64()
+	return decimal.NewFromFloat(g.Max * math.Pow(1-math.Exp(-g.Rate*age), g.Shape))
+}
+
+// Check the growth model if it can be validated
+func (v *validator) validateGrowth(field string, growth GrowthModel) {
+	switch g := growth.(type) {
+	case LinearGrowth:
+		v.nonNegative(field+" rate", g.Rate)
+		v.nonNegative(field+" max", g.Max)
+	case ChapmanRichards:
+		v.positiveFinite(field+" max", g.Max)
+		v.positiveFinite(field+" rate", g.Rate)
+		v.positiveFinite(field+" shape", g.Shape)
+	case interface{ Validate() error }:
+		v.merge(field, growth.Name(), g.Validate())
+	}
+}
+
+// Baseline scenario of the monitoring zone modeled from the pre-project tree
+// inventory instead of the manual baseline, see CDM AR-TOOL14
+// plots - sample plots of the pre-existing trees measured before the project
+// growth - growth model of the pre-existing trees, nil if you want to get
+// chapmanRichards or LinearGrowth with growthRate and maxBiomass
+// chapmanRichards - Chapman-Richards curve of the pre-existing trees, the way
+// to select the curve in JSON, used if growth is nil
+// growthRate - annual above-ground biomass growth (t d.m./ha/yr), 0 if you want
+// to get BaselineGrowthRate of the forest type
+// maxBiomass - see LinearGrowth
+// nonTreeRemovals - expected baseline removals of the non-planted vegetation,
+// e.g. natural regeneration (t CO2-e/ha/yr)
+// fraction - carbon fraction of tree biomass, 0 if you want to get default
+// value 0.47
+type BaselineModel struct {
+	Plots           []Plot           `json:"plots"`
+	Growth          GrowthModel      `json:"-"`
+	ChapmanRichards *ChapmanRichards `json:"chapmanRichards,omitempty"`
+	GrowthRate      decimal.Decimal  `json:"growthRate"`
+	MaxBiomass      decimal.Decimal  `json:"maxBiomass"`
+	NonTreeRemovals decimal.Decimal  `json:"nonTreeRemovals"`
+	Fraction        decimal.Decimal  `json:"fraction"`
+}
+
+// Baseline of the monitoring zone at the year since the start of the project
+// stock - carbon stock in the pre-existing trees (t CO2-e)
+// removals - baseline removals accumulated since the start of the project
+// (t CO2-e)
+type BaselinePoint struct {
+	Year     int             `json:"year"`
+	Stock    decimal.Decimal `json:"stock"`
+	Removals decimal.Decimal `json:"removals"`
+}
+
+// Baseline of the monitoring zone by year, starting with year 0
+type BaselineCurve []BaselinePoint
+
+// Baseline removals accumulated until the year (t CO2-e), linearly
+// interpolated between the points, the last point is used after the end of
+// the curve
+func (c BaselineCurve) Removals(year decimal.Decimal) decimal.Decimal {
+	if len(c) == 0 || !year.IsPositive() {
+		return decimal.Zero
+	}
+	for i := 1; i < len(c); i++ {
+		if year.LessThanOrEqual(decimal.NewFromInt(int64(c[i].Year))) {
+			previous := c[i-1]
+			step := year.Sub(decimal.NewFromInt(int64(previous.Year)))
+			return previous.Removals.Add(c[i].Removals.Sub(previous.Removals).Mul(step))
+		}
+	}
+	return c[len(c)-1].Removals
+}
+
+// Baseline removals between the years since the start of the project
+// (t CO2-e), the baseline argument of Baseline
+func (c BaselineCurve) Interval(from, to decimal.Decimal) decimal.Decimal {
+	return c.Removals(to).Sub(c.Removals(from))
+}
+
+// Calculate the baseline curve of the monitoring zone
+// initialBiomass - above-ground biomass of the pre-existing trees at the start
+// of the project (t d.m./ha)
+// fraction - carbon fraction of tree biomass
+// rootShoot - root-shoot ratio of the pre-existing trees
+// area - area of monitoring zone (ha)
+// nonTreeRemovals - baseline removals of the non-planted vegetation
+// (t CO2-e/ha/yr)
+// growth - growth model of the pre-existing trees
+// years - length of the curve (years)
+// Returns ErrNoGrowthModel if growth is nil and ErrNegativeYears if years is
+// negative
+func BaselineCurveInMonitoringZone(initialBiomass, fraction, rootShoot, area, nonTreeRemovals decimal.Decimal, growth GrowthModel, years int) (BaselineCurve, error) {
+	if growth == nil {
+		return nil, ErrNoGrowthModel
+	}
+	if years < 0 {
+		return nil, ErrNegativeYears
+	}
+	return baselineCurveInMonitoringZone(nil, initialBiomass, fraction, rootShoot, area, nonTreeRemovals, growth, years), nil
+}
+
+func baselineCurveInMonitoringZone(tr *Trace, initialBiomass, fraction, rootShoot, area, nonTreeRemovals decimal.Decimal, growth GrowthModel, years int) BaselineCurve {
+	tr = tr.Step("BaselineCurveInMonitoringZone").
+		Of(growth.Name()).
+		Input("initialBiomass", initialBiomass).
+		Input("fraction", fraction).
+		Input("rootShoot", rootShoot).
+		Input("area", area).
+		Input("nonTreeRemovals", nonTreeRemovals).
+		Input("years", decimal.NewFromInt(int64(years)))
+	toCarbon := decimal.NewFromFloat(44.0 / 12.0).
+		Mul(fraction).
+		Mul(decimal.New(1, 0).Add(rootShoot)).
+		Mul(area)
+	curve := make(BaselineCurve, 0, years+1)
+	initial := initialBiomass.Mul(toCarbon)
+	for year := 0; year <= years; year++ {
+		elapsed := decimal.NewFromInt(int64(year))
+		stock := growth.Biomass(initialBiomass, elapsed).Mul(toCarbon)
+		curve = append(curve, BaselinePoint{
+			Year:     year,
+			Stock:    stock,
+			Removals: stock.Sub(initial).Add(nonTreeRemovals.Mul(area).Mul(elapsed)),
+		})
+	}
+	tr.Result(curve[years].Removals)
+	return curve
+}
+
+// Baseline curve of the zone long enough for the year since the start of the
+// project
+func (s Stage) baselineCurve(tr *Trace, ps *ParameterSet, z MonitoringZone, until decimal.Decimal) (BaselineCurve, error) {
+	model := z.BaselineModel
+	tr = tr.Step("BaselineModel")
+	plots := make([]decimal.Decimal, 0, len(model.Plots))
+	for _, plot := range model.Plots {
+		plotTrace := tr.Step("Plot").Of(plot.ID)
+		carbon := decimal.Zero
+		for _, tree := range plot.Trees {
+			treeTrace := plotTrace.Step("Tree").Of(tree.ID)
+			treeCarbon, _, err := s.treeCarbon(treeTrace, ps, z, tree)
+			if err == NotEnoughHeight {
+				treeTrace.Result(decimal.Zero)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			carbon = carbon.Add(treeTrace.Result(treeCarbon))
+		}
+		carbonPerHa, err := validateCarbonStoredInPlot(plotTrace, carbon, plot.Area)
+		if err != nil {
+			return nil, err
+		}
+		plots = append(plots, plotTrace.Result(carbonPerHa))
+	}
+	carbonPerHa := SumDecimal(plots).Div(decimal.NewFromInt(int64(len(plots))))
+	fraction := tr.inputOrDefault("fraction", model.Fraction, decimal.NewFromFloat(0.47))
+	rootShoot := z.rootShootRatio(tr, ps)
+	initialBiomass := aboveGroundBiomass(tr, AboveGroundBiomassOptions{
+		AreaConsCarbon: carbonPerHa,
+		Ratio:          rootShoot,
+		CfTree:         &fraction,
+		Area:           decimal.New(1, 0),
+	})
+	growth := model.Growth
+	if growth == nil && model.ChapmanRichards != nil {
+		growth = *model.ChapmanRichards
+	}
+	if growth == nil {
+		rate := model.GrowthRate
+		if rate.IsZero() {
+			rate = tr.Step("BaselineGrowthRate").
+				Of(z.ForestType.String()).
+				Result(BaselineGrowthRate(z.ForestType))
+		}
+		growth = LinearGrowth{Rate: rate, Max: model.MaxBiomass}
+	}
+	years := int(until.Ceil().IntPart())
+	return baselineCurveInMonitoringZone(tr, initialBiomass, fraction, rootShoot, z.Area, model.NonTreeRemovals, growth, years), nil
+}
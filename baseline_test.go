@@ -0,0 +1,181 @@
+package carbon_calc
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestGrowthModel(t *testing.T) {
+	type Test struct {
+		growth         GrowthModel
+		initial, years float64
+		result         float64 // precision = 6
+	}
+	chapmanRichards := ChapmanRichards{Max: 200, Rate: 0.05, Shape: 2}
+	tests := []Test{
+		{LinearGrowth{Rate: decimal.New(2, 0)}, 10, 3, 16},
+		{LinearGrowth{Rate: decimal.New(2, 0), Max: decimal.New(14, 0)}, 10, 3, 14},
+		// Biomass above the maximum does not decrease
+		{LinearGrowth{Rate: decimal.New(2, 0), Max: decimal.New(14, 0)}, 20, 3, 20},
+		{chapmanRichards, 0, 0, 0},
+		// 200 * (1 - e^(-0.05 * 10))^2
+		{chapmanRichards, 0, 10, 30.963624},
+		{chapmanRichards, 250, 10, 250},
+	}
+	for i, tt := range tests {
+		result := tt.growth.Biomass(decimal.NewFromFloat(tt.initial), decimal.NewFromFloat(tt.years))
+		if !result.Round(6).Equal(decimal.NewFromFloat(tt.result)) {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, tt.result, result)
+		}
+	}
+
+	// Growth from the biomass of the 10 years old trees continues the curve
+	initial := chapmanRichards.Biomass(decimal.Zero, decimal.New(10, 0))
+	expect := chapmanRichards.Biomass(decimal.Zero, decimal.New(15, 0))
+	if result := chapmanRichards.Biomass(initial, decimal.New(5, 0)); !result.Round(6).Equal(expect.Round(6)) {
+		t.Fatalf("expect: %s, have: %s", expect, result)
+	}
+}
+
+func TestBaselineCurve(t *testing.T) {
+	// 44/12 * 0.5 * 1.2 * 2 ha = 4.4 t CO2-e per t d.m./ha, removals of the
+	// trees 3 * 4.4 and of the other vegetation 1 * 2 per year
+	curve, err := BaselineCurveInMonitoringZone(decimal.New(10, 0), decimal.NewFromFloat(0.5), decimal.NewFromFloat(0.2),
+		decimal.New(2, 0), decimal.New(1, 0), LinearGrowth{Rate: decimal.New(3, 0)}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(curve) != 4 {
+		t.Fatalf("expect 4 points, have: %v", curve)
+	}
+	for year, point := range curve {
+		stock := decimal.NewFromFloat(4.4).Mul(decimal.NewFromInt(int64(10 + 3*year)))
+		removals := decimal.NewFromFloat(15.2).Mul(decimal.NewFromInt(int64(year)))
+		if point.Year != year || !point.Stock.Round(9).Equal(stock) || !point.Removals.Round(9).Equal(removals) {
+			t.Fatalf("Year %d, expect: %s, %s, have: %v", year, stock, removals, point)
+		}
+	}
+
+	type Test struct {
+		from, to float64
+		result   float64 // precision = 6
+	}
+	tests := []Test{
+		{0, 1, 15.2},
+		{0.5, 2.5, 30.4},
+		{2, 2, 0},
+		// The last point is used after the end of the curve
+		{2, 10, 15.2},
+		{-1, 0, 0},
+	}
+	for i, tt := range tests {
+		result := curve.Interval(decimal.NewFromFloat(tt.from), decimal.NewFromFloat(tt.to))
+		if !result.Round(6).Equal(decimal.NewFromFloat(tt.result)) {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, tt.result, result)
+		}
+	}
+
+	if _, err := BaselineCurveInMonitoringZone(decimal.New(10, 0), decimal.NewFromFloat(0.5), decimal.NewFromFloat(0.2),
+		decimal.New(2, 0), decimal.New(1, 0), nil, 3); err != ErrNoGrowthModel {
+		t.Fatalf("expect: %v, have: %v", ErrNoGrowthModel, err)
+	}
+	if _, err := BaselineCurveInMonitoringZone(decimal.New(10, 0), decimal.NewFromFloat(0.5), decimal.NewFromFloat(0.2),
+		decimal.New(2, 0), decimal.New(1, 0), LinearGrowth{}, -1); err != ErrNegativeYears {
+		t.Fatalf("expect: %v, have: %v", ErrNegativeYears, err)
+	}
+}
+
+func TestStageBaselineModel(t *testing.T) {
+	stage := testStage()
+	stage.DeltaTime = decimal.New(2, 0)
+	stage.Zones[0].BaselineModel = &BaselineModel{
+		Plots:           stage.Zones[0].Plots[:2],
+		GrowthRate:      decimal.New(1, 0),
+		NonTreeRemovals: decimal.NewFromFloat(0.5),
+	}
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := result.Zone("zone-1")
+	if !result.Year.Equal(decimal.New(2, 0)) || len(zone.BaselineCurve) != 3 {
+		t.Fatalf("expect year 2 and 3 points, have: %s, %v", result.Year, zone.BaselineCurve)
+	}
+	if !zone.Baseline.Equal(zone.BaselineCurve[2].Removals) || !zone.Baseline.IsPositive() {
+		t.Fatalf("expect: %s, have: %s", zone.BaselineCurve[2].Removals, zone.Baseline)
+	}
+	if !result.Baseline.Equal(zone.Baseline.Add(result.Zone("zone-2").Baseline)) {
+		t.Fatalf("expect: %s, have: %s", zone.Baseline, result.Baseline)
+	}
+
+	// Next stage takes the next interval of the curve
+	stage.Previous = &result
+	next, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextZone := next.Zone("zone-1")
+	expect := nextZone.BaselineCurve.Interval(decimal.New(2, 0), decimal.New(4, 0))
+	if !next.Year.Equal(decimal.New(4, 0)) || len(nextZone.BaselineCurve) != 5 || !nextZone.Baseline.Equal(expect) {
+		t.Fatalf("expect: %s, have: %s, %v", expect, nextZone.Baseline, nextZone.BaselineCurve)
+	}
+
+	stage.Zones[0].Baseline = decimal.New(1, 0)
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "baseline" {
+		t.Fatalf("expect baseline error, have: %v", err)
+	}
+
+	// Curve that is not defined is rejected instead of the panic on NaN
+	stage.Zones[0].Baseline = decimal.Zero
+	stage.Zones[0].BaselineModel.Growth = ChapmanRichards{Max: 200, Rate: 0, Shape: math.NaN()}
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 2 || errs[0].Field != "baselineModel growth rate" {
+		t.Fatalf("expect growth rate and shape errors, have: %v", err)
+	}
+
+	// Chapman-Richards curve selected in JSON
+	stage.Previous = nil
+	stage.Zones[0].BaselineModel.Growth = ChapmanRichards{Max: 200, Rate: 0.05, Shape: 2}
+	expect2, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage.Zones[0].BaselineModel.Growth = nil
+	if err := json.Unmarshal([]byte(`{"chapmanRichards": {"max": 200, "rate": 0.05, "shape": 2}}`), stage.Zones[0].BaselineModel); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = stage.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Zone("zone-1").Baseline.Equal(expect2.Zone("zone-1").Baseline) || result.Zone("zone-1").Baseline.Equal(zone.Baseline) {
+		t.Fatalf("expect: %s, have: %s", expect2.Zone("zone-1").Baseline, result.Zone("zone-1").Baseline)
+	}
+	stage.Zones[0].BaselineModel.ChapmanRichards.Shape = 0
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "baselineModel chapmanRichards shape" {
+		t.Fatalf("expect chapmanRichards shape error, have: %v", err)
+	}
+}
+
+func TestGrowthModelValidate(t *testing.T) {
+	tests := []struct {
+		growth GrowthModel
+		errs   int
+	}{
+		{ChapmanRichards{Max: 200, Rate: 0.05, Shape: 2}, 0},
+		{ChapmanRichards{Max: 0, Rate: 0.05, Shape: 2}, 1},
+		{ChapmanRichards{Max: math.Inf(1), Rate: -0.05, Shape: 0}, 3},
+		{LinearGrowth{Rate: decimal.New(2, 0)}, 0},
+		{LinearGrowth{Rate: decimal.New(-2, 0), Max: decimal.New(-1, 0)}, 2},
+	}
+	for i, tt := range tests {
+		var errs ValidationErrors
+		err := tt.growth.(interface{ Validate() error }).Validate()
+		if errors.As(err, &errs); len(errs) != tt.errs {
+			t.Fatalf("Test number %d, expect %d ValidationErrors, have: %v", i, tt.errs, err)
+		}
+	}
+}
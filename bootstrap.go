@@ -0,0 +1,235 @@
+package carbon_calc
+
+import (
+	"math"
+	"sort"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Bootstrap resampling of the sample plots within each monitoring zone,
+// alternative to the analytic variance of UncertaintyCarbonStored for zones
+// with skewed plot distribution
+// iterations - number of bootstrap samples, 0 if you want to get default value
+// 2000
+// seed - seed of the random number generator, the same seed gives the same
+// result
+// confidence - two-sided confidence level, 0 if you want to get default value
+// 0.9 used by TDistribution, Run takes the confidence of the stage then
+type Bootstrap struct {
+	Iterations int     `json:"iterations"`
+	Seed       uint64  `json:"seed"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Confidence interval of the mean carbon stock (t CO2-e/ha)
+// uncertainty - half-width of the interval as a fraction of the estimate, can
+// be used instead of UncertaintyCarbonStored
+type BootstrapInterval struct {
+	Lower       decimal.Decimal `json:"lower"`
+	Upper       decimal.Decimal `json:"upper"`
+	Uncertainty decimal.Decimal `json:"uncertainty"`
+}
+
+// estimate - area weighted mean carbon stock of the zones (t CO2-e/ha)
+// percentile - percentile interval of the bootstrap distribution
+// bca - bias-corrected and accelerated interval, the percentile interval if
+// all bootstrap samples are on one side of the estimate
+// uncertaintyDiscount - see UncertaintyDiscount of the BCa uncertainty, Run
+// uses the discount schedule of the stage
+// conservativeCarbon - see ConservativeTotalCarbon of the total carbon of the
+// stage with the BCa uncertainty and the discount schedule of the stage, the
+// shrubs are not discounted, 0 if the result is not calculated by Run
+type BootstrapResult struct {
+	Iterations          int               `json:"iterations"`
+	Confidence          float64           `json:"confidence"`
+	Estimate            decimal.Decimal   `json:"estimate"`
+	Percentile          BootstrapInterval `json:"percentile"`
+	BCa                 BootstrapInterval `json:"bca"`
+	UncertaintyDiscount decimal.Decimal   `json:"uncertaintyDiscount"`
+	ConservativeCarbon  decimal.Decimal   `json:"conservativeCarbon"`
+}
+
+func (b Bootstrap) iterations() int {
+	if b.Iterations == 0 {
+		return 2000
+	}
+	return b.Iterations
+}
+
+func (b Bootstrap) confidence() float64 {
+	if b.Confidence == 0 {
+		return defaultConfidence
+	}
+	return b.Confidence
+}
+
+// Check the bootstrap configuration, returns ValidationErrors with all problems
+// found
+func (b Bootstrap) Validate() error {
+	v := &validator{}
+	v.check(b.Iterations == 0 || b.Iterations >= 2, "iterations", b.Iterations, RuleMinIterations, nil)
+	v.check(b.Confidence >= 0 && b.Confidence < 1, "confidence", b.Confidence, RuleFraction, nil)
+	return v.errs.err()
+}
+
+// Calculate the bootstrap confidence intervals of the carbon stock
+// tArea - area of all monitoring zones (sum of all areas of monitoring zones)
+// zones - array of zones with area and array of carbon in each plot (t CO2-e/ha)
+func (b Bootstrap) Interval(tArea decimal.Decimal, zones []CarbonedZone) (BootstrapResult, error) {
+	return b.interval(tArea, zones, DefaultDiscountTable())
+}
+
+func (b Bootstrap) interval(tArea decimal.Decimal, zones []CarbonedZone, schedule DiscountSchedule) (BootstrapResult, error) {
+	if err := b.Validate(); err != nil {
+		return BootstrapResult{}, err
+	}
+	if err := validateCarbonedZones(tArea, zones); err != nil {
+		return BootstrapResult{}, err
+	}
+	weights := make([]float64, len(zones))
+	plots := make([][]float64, len(zones))
+	for i, zone := range zones {
+		weights[i] = zone.Area.Div(tArea).InexactFloat64()
+		plots[i] = make([]float64, len(zone.Plots))
+		for j, plot := range zone.Plots {
+			plots[i][j] = plot.InexactFloat64()
+		}
+	}
+	estimate := weightedMean(weights, plots)
+
+	// Resample the plots within each zone
+	rnd := rand.New(rand.NewSource(b.Seed))
+	iterations := b.iterations()
+	samples := make([]float64, iterations)
+	resampled := make([][]float64, len(plots))
+	for i := range plots {
+		resampled[i] = make([]float64, len(plots[i]))
+	}
+	for k := range samples {
+		for i, zonePlots := range plots {
+			for j := range zonePlots {
+				resampled[i][j] = zonePlots[rnd.Intn(len(zonePlots))]
+			}
+		}
+		samples[k] = weightedMean(weights, resampled)
+	}
+	sort.Float64s(samples)
+
+	confidence := b.confidence()
+	alpha := (1 - confidence) / 2
+	result := BootstrapResult{
+		Iterations: iterations,
+		Confidence: confidence,
+		Estimate:   decimal.NewFromFloat(estimate),
+	}
+	result.Percentile = bootstrapInterval(estimate,
+		stat.Quantile(alpha, stat.Empirical, samples, nil),
+		stat.Quantile(1-alpha, stat.Empirical, samples, nil))
+
+	// Bias correction from the share of samples below the estimate
+	less, equal := 0, 0
+	for _, sample := range samples {
+		if sample < estimate {
+			less++
+		} else if sample == estimate {
+			equal++
+		}
+	}
+	z0 := distuv.UnitNormal.Quantile((float64(less) + float64(equal)/2) / float64(iterations))
+	a := jackknifeAcceleration(weights, plots)
+	bca := func(p float64) float64 {
+		z := distuv.UnitNormal.Quantile(p)
+		return distuv.UnitNormal.CDF(z0 + (z0+z)/(1-a*(z0+z)))
+	}
+	lower, upper := bca(alpha), bca(1-alpha)
+	if isProbability(lower) && isProbability(upper) {
+		result.BCa = bootstrapInterval(estimate,
+			stat.Quantile(lower, stat.Empirical, samples, nil),
+			stat.Quantile(upper, stat.Empirical, samples, nil))
+	} else {
+		// All samples are on one side of the estimate, the bias correction is
+		// infinite
+		result.BCa = result.Percentile
+	}
+	result.UncertaintyDiscount = scheduleDiscount(schedule, result.BCa.Uncertainty)
+	return result, nil
+}
+
+// Calculate the bootstrap confidence intervals of the carbon stock of the stage
+// Returns ValidationErrors if the stage is not valid
+func (b Bootstrap) Run(s Stage) (BootstrapResult, error) {
+	s.Explain = false
+	stageResult, err := s.Calculate()
+	if err != nil {
+		return BootstrapResult{}, err
+	}
+	if b.Confidence == 0 {
+		b.Confidence = s.Confidence
+	}
+	zones := make([]CarbonedZone, len(s.Zones))
+	for i, zone := range stageResult.Zones {
+		zones[i] = CarbonedZone{Area: s.Zones[i].Area}
+		for _, plot := range zone.Plots {
+			zones[i].Plots = append(zones[i].Plots, plot.CarbonPerHa)
+		}
+	}
+	result, err := b.interval(stageResult.TotalArea, zones, s.discount())
+	if err != nil {
+		return BootstrapResult{}, err
+	}
+	// The shrubs are not sampled by plot, see StageResult.Shrubs
+	sampled := stageResult.TotalCarbon.Sub(stageResult.Shrubs)
+	result.ConservativeCarbon = conservativeTotalCarbon(nil, sampled, result.BCa.Uncertainty, s.discount()).Add(stageResult.Shrubs)
+	return result, nil
+}
+
+func isProbability(p float64) bool {
+	return p >= 0 && p <= 1
+}
+
+func bootstrapInterval(estimate, lower, upper float64) BootstrapInterval {
+	return BootstrapInterval{
+		Lower:       decimal.NewFromFloat(lower),
+		Upper:       decimal.NewFromFloat(upper),
+		Uncertainty: decimal.NewFromFloat((upper - lower) / 2 / math.Abs(estimate)),
+	}
+}
+
+// Area weighted mean of the plot means
+func weightedMean(weights []float64, plots [][]float64) float64 {
+	sum := 0.0
+	for i, zonePlots := range plots {
+		sum += weights[i] * stat.Mean(zonePlots, nil)
+	}
+	return sum
+}
+
+// Acceleration of the BCa interval from the jackknife estimates leaving one
+// plot out at a time
+func jackknifeAcceleration(weights []float64, plots [][]float64) float64 {
+	var estimates []float64
+	left := make([][]float64, len(plots))
+	copy(left, plots)
+	for i, zonePlots := range plots {
+		for j := range zonePlots {
+			left[i] = append(append([]float64(nil), zonePlots[:j]...), zonePlots[j+1:]...)
+			estimates = append(estimates, weightedMean(weights, left))
+		}
+		left[i] = zonePlots
+	}
+	mean := stat.Mean(estimates, nil)
+	num, den := 0.0, 0.0
+	for _, estimate := range estimates {
+		d := mean - estimate
+		num += d * d * d
+		den += d * d
+	}
+	if den == 0 {
+		return 0
+	}
+	return num / (6 * math.Pow(den, 1.5))
+}
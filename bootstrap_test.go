@@ -0,0 +1,162 @@
+package carbon_calc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func testCarbonedZones() []CarbonedZone {
+	plots := func(values ...float64) []decimal.Decimal {
+		result := make([]decimal.Decimal, len(values))
+		for i, value := range values {
+			result[i] = decimal.NewFromFloat(value)
+		}
+		return result
+	}
+	return []CarbonedZone{
+		{Area: decimal.New(8, 0), Plots: plots(12, 15, 9, 11, 14, 10, 48, 13)},
+		{Area: decimal.New(2, 0), Plots: plots(21, 25, 19, 23)},
+	}
+}
+
+func equalBootstrap(a, b BootstrapResult) bool {
+	equalInterval := func(a, b BootstrapInterval) bool {
+		return a.Lower.Equal(b.Lower) && a.Upper.Equal(b.Upper) && a.Uncertainty.Equal(b.Uncertainty)
+	}
+	return a.Iterations == b.Iterations &&
+		a.Estimate.Equal(b.Estimate) &&
+		equalInterval(a.Percentile, b.Percentile) &&
+		equalInterval(a.BCa, b.BCa)
+}
+
+func TestBootstrapInterval(t *testing.T) {
+	zones := testCarbonedZones()
+	b := Bootstrap{Seed: 1}
+	result, err := b.Interval(decimal.New(10, 0), zones)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0.8 * 16.5 + 0.2 * 22
+	if !result.Estimate.Round(9).Equal(decimal.NewFromFloat(17.6)) {
+		t.Fatalf("expect: 17.6, have: %s", result.Estimate)
+	}
+	if result.Iterations != 2000 || result.Confidence != 0.9 {
+		t.Fatalf("Wrong defaults: %d, %f", result.Iterations, result.Confidence)
+	}
+	for _, interval := range []BootstrapInterval{result.Percentile, result.BCa} {
+		if !interval.Lower.LessThan(result.Estimate) || !interval.Upper.GreaterThan(result.Estimate) {
+			t.Fatalf("Interval should contain the estimate: %v", interval)
+		}
+	}
+	// Right skewed zone moves the BCa interval to the right
+	if !result.BCa.Upper.GreaterThan(result.Percentile.Upper) {
+		t.Fatalf("BCa upper bound should be above percentile one: %s, %s", result.BCa.Upper, result.Percentile.Upper)
+	}
+	if !result.UncertaintyDiscount.Equal(UncertaintyDiscount(result.BCa.Uncertainty)) {
+		t.Fatalf("Wrong uncertainty discount: %s", result.UncertaintyDiscount)
+	}
+
+	again, err := b.Interval(decimal.New(10, 0), zones)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalBootstrap(again, result) {
+		t.Fatalf("Same seed should give the same result")
+	}
+}
+
+func TestBootstrapConstantPlots(t *testing.T) {
+	zones := []CarbonedZone{
+		{Area: decimal.New(1, 0), Plots: []decimal.Decimal{decimal.New(5, 0), decimal.New(5, 0)}},
+	}
+	result, err := Bootstrap{Iterations: 100}.Interval(decimal.New(1, 0), zones)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.BCa.Uncertainty.IsZero() || !result.Percentile.Uncertainty.IsZero() {
+		t.Fatalf("Constant plots should give zero uncertainty: %v", result)
+	}
+}
+
+func TestBootstrapRun(t *testing.T) {
+	stage := testStage()
+	result, err := Bootstrap{Seed: 2}.Run(stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageResult, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones := make([]CarbonedZone, len(stage.Zones))
+	for i, zone := range stageResult.Zones {
+		zones[i].Area = stage.Zones[i].Area
+		for _, plot := range zone.Plots {
+			zones[i].Plots = append(zones[i].Plots, plot.CarbonPerHa)
+		}
+	}
+	expect, err := Bootstrap{Seed: 2}.Interval(stageResult.TotalArea, zones)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalBootstrap(result, expect) {
+		t.Fatalf("expect: %v, have: %v", expect, result)
+	}
+	conservative := ConservativeTotalCarbon(stageResult.TotalCarbon, result.BCa.Uncertainty)
+	if !result.ConservativeCarbon.Equal(conservative) {
+		t.Fatalf("expect: %s, have: %s", conservative, result.ConservativeCarbon)
+	}
+
+	// Confidence and discount schedule of the stage
+	stage.Confidence = 0.8
+	stage.LinearDiscount = &LinearDiscount{Name: "linear"}
+	if result, err = (Bootstrap{Seed: 2}).Run(stage); err != nil {
+		t.Fatal(err)
+	}
+	if expect, err = (Bootstrap{Seed: 2, Confidence: 0.8}).Interval(stageResult.TotalArea, zones); err != nil {
+		t.Fatal(err)
+	}
+	if result.Confidence != 0.8 || !equalBootstrap(result, expect) || !result.UncertaintyDiscount.Equal(decimal.New(1, 0)) {
+		t.Fatalf("expect: 0.8 %v 1, have: %v %v %s", expect.BCa, result.Confidence, result.BCa, result.UncertaintyDiscount)
+	}
+	conservative = stageResult.TotalCarbon.Mul(decimal.New(1, 0).Sub(result.BCa.Uncertainty))
+	if !result.ConservativeCarbon.Equal(conservative) {
+		t.Fatalf("expect: %s, have: %s", conservative, result.ConservativeCarbon)
+	}
+}
+
+func TestBootstrapErrors(t *testing.T) {
+	zones := testCarbonedZones()
+	zones[1].Plots = zones[1].Plots[:1]
+	if _, err := (Bootstrap{}).Interval(decimal.New(10, 0), zones); err != ErrSinglePlotZone {
+		t.Fatalf("expect: %v, have: %v", ErrSinglePlotZone, err)
+	}
+	if _, err := (Bootstrap{}).Interval(decimal.Zero, testCarbonedZones()); err != ErrZeroTotalArea {
+		t.Fatalf("expect: %v, have: %v", ErrZeroTotalArea, err)
+	}
+	var errs ValidationErrors
+	if _, err := (Bootstrap{Iterations: 1, Confidence: 1.5}).Interval(decimal.New(10, 0), testCarbonedZones()); !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expect 2 ValidationErrors, have: %v", err)
+	}
+}
+
+func TestBootstrapOneSided(t *testing.T) {
+	plots := make([]decimal.Decimal, 0, 8)
+	for _, value := range []float64{1, 2, 3, 4, 5, 6, 7, 200} {
+		plots = append(plots, decimal.NewFromFloat(value))
+	}
+	zones := []CarbonedZone{{Area: decimal.New(1, 0), Plots: plots}}
+	// Every sample of the seed misses the outlier and is below the estimate
+	result, err := Bootstrap{Iterations: 3, Seed: 0}.Interval(decimal.New(1, 0), zones)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Percentile.Upper.LessThan(result.Estimate) {
+		t.Fatalf("expect all samples below %s, have: %v", result.Estimate, result.Percentile)
+	}
+	if result.BCa != result.Percentile {
+		t.Fatalf("expect: %v, have: %v", result.Percentile, result.BCa)
+	}
+}
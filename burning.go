@@ -0,0 +1,92 @@
+package carbon_calc
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Emission factors of IPCC 2006 Volume 4 Table 2.5 (g/kg of dry matter
+// burnt) by forest type, tropical forest values are used for the tropical /
+// subtropical forest type, extra tropical forest values otherwise
+var EmissionFactorCH4Dict map[ForestType]float64 = map[ForestType]float64{
+	ForestTypeTropicalSubtropical: 6.8,
+	ForestTypeTemperate:           4.7,
+	ForestTypeBoreal:              4.7,
+}
+
+var EmissionFactorN2ODict map[ForestType]float64 = map[ForestType]float64{
+	ForestTypeTropicalSubtropical: 0.2,
+	ForestTypeTemperate:           0.26,
+	ForestTypeBoreal:              0.26,
+}
+
+// Burning of biomass in the project area during the stage, site preparation
+// or fire, see CDM AR-TOOL08
+// area - burnt area (ha)
+// biomass - mean biomass on the burnt area before burning (t d.m./ha)
+// combustionFactor - fraction of the biomass combusted, 0 if you want to get
+// default value 0.5
+// emissionFactorCH4, emissionFactorN2O - emission factors (g/kg of dry matter
+// burnt), 0 if you want to get the values of the forest type, see
+// EmissionFactorCH4Dict and EmissionFactorN2ODict
+// gwpCH4, gwpN2O - global warming potentials, 0 if you want to get the
+// non-fossil CH4 and N2O values of the GWP set, see GWPDict
+type BurningEvent struct {
+	ID                string          `json:"id"`
+	ForestType        ForestType      `json:"forestType"`
+	Area              decimal.Decimal `json:"area"`
+	Biomass           decimal.Decimal `json:"biomass"`
+	CombustionFactor  decimal.Decimal `json:"combustionFactor"`
+	EmissionFactorCH4 decimal.Decimal `json:"emissionFactorCH4"`
+	EmissionFactorN2O decimal.Decimal `json:"emissionFactorN2O"`
+	GWPCH4            decimal.Decimal `json:"gwpCH4"`
+	GWPN2O            decimal.Decimal `json:"gwpN2O"`
+}
+
+// Calculate the emission of the non-CO2 gas from biomass burning (t CO2-e)
+// area - burnt area (ha)
+// biomass - mean biomass on the burnt area before burning (t d.m./ha)
+// combustionFactor - fraction of the biomass combusted
+// emissionFactor - emission factor of the gas (g/kg of dry matter burnt)
+// gwp - global warming potential of the gas
+func BurningEmissions(area, biomass, combustionFactor, emissionFactor, gwp decimal.Decimal) decimal.Decimal {
+	return burningEmissions(nil, "", area, biomass, combustionFactor, emissionFactor, gwp)
+}
+
+func burningEmissions(tr *Trace, gas string, area, biomass, combustionFactor, emissionFactor, gwp decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("BurningEmissions").
+		Of(gas).
+		Input("area", area).
+		Input("biomass", biomass).
+		Input("combustionFactor", combustionFactor).
+		Input("emissionFactor", emissionFactor).
+		Input("gwp", gwp)
+	return tr.Result(area.
+		Mul(biomass).
+		Mul(combustionFactor).
+		Mul(emissionFactor).
+		Mul(decimal.New(1, -3)).
+		Mul(gwp))
+}
+
+// Calculate the CH4 and N2O emissions of the burning (t CO2-e), the result can
+// be passed as emissions to NetEmissionsRemoval
+func (b BurningEvent) Emissions() decimal.Decimal {
+	return b.emissions(nil, GWPAR5)
+}
+
+// Calculate the emissions of the burning with the defaults of the GWP set
+func (b BurningEvent) EmissionsGWP(gwp GWPSet) decimal.Decimal {
+	return b.emissions(nil, gwp)
+}
+
+func (b BurningEvent) emissions(tr *Trace, gwp GWPSet) decimal.Decimal {
+	tr = tr.Step("BurningEvent").Of(b.ID)
+	combustionFactor := tr.inputOrDefault("combustionFactor", b.CombustionFactor, decimal.NewFromFloat(0.5))
+	emissionFactorCH4 := tr.inputOrDefault("emissionFactorCH4", b.EmissionFactorCH4, decimal.NewFromFloat(EmissionFactorCH4Dict[b.ForestType]))
+	emissionFactorN2O := tr.inputOrDefault("emissionFactorN2O", b.EmissionFactorN2O, decimal.NewFromFloat(EmissionFactorN2ODict[b.ForestType]))
+	gwpCH4 := tr.inputOrDefault("gwpCH4", b.GWPCH4, gwp.CH4())
+	gwpN2O := tr.inputOrDefault("gwpN2O", b.GWPN2O, gwp.N2O())
+	ch4 := burningEmissions(tr, "CH4", b.Area, b.Biomass, combustionFactor, emissionFactorCH4, gwpCH4)
+	n2o := burningEmissions(tr, "N2O", b.Area, b.Biomass, combustionFactor, emissionFactorN2O, gwpN2O)
+	return tr.Result(ch4.Add(n2o))
+}
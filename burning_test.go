@@ -0,0 +1,66 @@
+package carbon_calc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBurningEmissions(t *testing.T) {
+	// 10 ha * 100 t/ha * 0.5 * 6.8 g/kg * 28 / 1000
+	result := BurningEmissions(decimal.New(10, 0), decimal.New(100, 0), decimal.NewFromFloat(0.5), decimal.NewFromFloat(6.8), decimal.New(28, 0))
+	if !result.Equal(decimal.NewFromFloat(95.2)) {
+		t.Fatalf("expect: 95.2, have: %s", result)
+	}
+
+	type Test struct {
+		event  BurningEvent
+		result float64
+	}
+	tests := []Test{
+		// Tropical defaults: 10 * 100 * 0.5 * (6.8 * 28 + 0.2 * 265) / 1000
+		{BurningEvent{Area: decimal.New(10, 0), Biomass: decimal.New(100, 0)}, 95.2 + 26.5},
+		// Extra tropical: 10 * 100 * 0.5 * (4.7 * 28 + 0.26 * 265) / 1000
+		{BurningEvent{ForestType: ForestTypeBoreal, Area: decimal.New(10, 0), Biomass: decimal.New(100, 0)}, 65.8 + 34.45},
+		{BurningEvent{
+			Area:              decimal.New(2, 0),
+			Biomass:           decimal.New(50, 0),
+			CombustionFactor:  decimal.NewFromFloat(0.8),
+			EmissionFactorCH4: decimal.New(5, 0),
+			EmissionFactorN2O: decimal.NewFromFloat(0.25),
+			GWPCH4:            decimal.New(25, 0),
+			GWPN2O:            decimal.New(298, 0),
+		}, 10 + 5.96},
+		{BurningEvent{Biomass: decimal.New(100, 0)}, 0},
+	}
+	for i, tt := range tests {
+		result := tt.event.Emissions()
+		if !result.Equal(decimal.NewFromFloat(tt.result)) {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, tt.result, result)
+		}
+	}
+}
+
+func TestStageBurning(t *testing.T) {
+	stage := testStage()
+	without, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := BurningEvent{ID: "site-preparation", Area: decimal.New(2, 0), Biomass: decimal.New(10, 0)}
+	stage.Burning = []BurningEvent{event}
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.BurningEmissions.Equal(event.Emissions()) || !result.Emissions.Equal(without.Emissions.Add(event.Emissions())) {
+		t.Fatalf("expect: %s, have: %s, %s", event.Emissions(), result.BurningEmissions, result.Emissions)
+	}
+
+	stage.Burning[0].CombustionFactor = decimal.New(2, 0)
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "burning combustionFactor" {
+		t.Fatalf("expect burning combustionFactor error, have: %v", err)
+	}
+}
@@ -21,37 +21,100 @@ import (
 // above-ground tree biomass, for tree l depending on tree species / forest type
 // ratio - root-shoot ratio for tree l depending on its specie / forest type
 func CarbonPerTree(fraction, radius, height, form, density, biomass, ratio decimal.Decimal) decimal.Decimal {
-	if fraction.Equal(decimal.Zero) {
-		fraction = decimal.NewFromFloat(0.47)
-	}
-	if form.Equal(decimal.Zero) {
-		form = decimal.NewFromFloat(0.25)
+	return carbonPerTree(nil, newCarbonPerTreeOptions(fraction, radius, height, form, density, biomass, ratio))
+}
+
+// Options of CarbonPerTreeWith, see CarbonPerTree
+// fraction - nil if you want to get default value 0.47
+// form - nil if you want to get default value 0.25
+type CarbonPerTreeOptions struct {
+	Fraction *decimal.Decimal `json:"fraction"`
+	Radius   decimal.Decimal  `json:"radius"`
+	Height   decimal.Decimal  `json:"height"`
+	Form     *decimal.Decimal `json:"form"`
+	Density  decimal.Decimal  `json:"density"`
+	Biomass  decimal.Decimal  `json:"biomass"`
+	Ratio    decimal.Decimal  `json:"ratio"`
+}
+
+func newCarbonPerTreeOptions(fraction, radius, height, form, density, biomass, ratio decimal.Decimal) CarbonPerTreeOptions {
+	return CarbonPerTreeOptions{
+		Fraction: zeroDefault(fraction),
+		Radius:   radius,
+		Height:   height,
+		Form:     zeroDefault(form),
+		Density:  density,
+		Biomass:  biomass,
+		Ratio:    ratio,
 	}
-	return decimal.NewFromFloat(44.0 / 12.0).
+}
+
+// Calculate the carbon stored in each tree, zero fraction or form is used as
+// is, returns the defaults substituted for the options not provided
+func CarbonPerTreeWith(o CarbonPerTreeOptions) (decimal.Decimal, Defaults) {
+	tr := NewTrace("CarbonPerTreeWith")
+	result := carbonPerTree(tr, o)
+	return result, tr.defaults()
+}
+
+func carbonPerTree(tr *Trace, o CarbonPerTreeOptions) decimal.Decimal {
+	tr = tr.Step("CarbonPerTree")
+	fraction := tr.optional("fraction", o.Fraction, decimal.NewFromFloat(0.47))
+	form := tr.optional("form", o.Form, decimal.NewFromFloat(0.25))
+	tr.Input("radius", o.Radius).
+		Input("height", o.Height).
+		Input("density", o.Density).
+		Input("biomass", o.Biomass).
+		Input("ratio", o.Ratio)
+	return tr.Result(decimal.NewFromFloat(44.0 / 12.0).
 		Mul(fraction).
-		Mul(CircleArea(radius)).
-		Mul(height).
+		Mul(CircleArea(o.Radius)).
+		Mul(o.Height).
 		Mul(form).
 		Mul(decimal.NewFromFloat(1.2)).
-		Mul(density).
-		Mul(biomass).
-		Mul((decimal.New(1, 0).Add(ratio)))
+		Mul(o.Density).
+		Mul(o.Biomass).
+		Mul((decimal.New(1, 0).Add(o.Ratio))))
 }
 
 // Calculate the carbon stored in each tree and with params validation
 // For more comments see CarbonPerTree function
 func ValidateCarbonPerTree(fraction, radius, height, form, density, biomass, ratio decimal.Decimal) (decimal.Decimal, error) {
+	return validateCarbonPerTree(nil, fraction, radius, height, form, density, biomass, ratio)
+}
+
+func validateCarbonPerTree(tr *Trace, fraction, radius, height, form, density, biomass, ratio decimal.Decimal) (decimal.Decimal, error) {
 	if height.Cmp(decimal.NewFromFloat(1.3)) == -1 {
 		return decimal.Decimal{}, NotEnoughHeight
 	}
-	return CarbonPerTree(fraction, radius, height, form, density, biomass, ratio), nil
+	return carbonPerTree(tr, newCarbonPerTreeOptions(fraction, radius, height, form, density, biomass, ratio)), nil
 }
 
 // Carbon/ha stored in sample plot p of monitoring zone
 // sum - carbon stored in tree of species in sample plot of monitoring zone
 // area - area of sample plot of monitoring zone
 func CarbonStoredInPlot(sum, area decimal.Decimal) decimal.Decimal {
-	return sum.Div(area)
+	return carbonStoredInPlot(nil, sum, area)
+}
+
+// Carbon/ha stored in sample plot with params validation
+// For more comments see CarbonStoredInPlot function
+func ValidateCarbonStoredInPlot(sum, area decimal.Decimal) (decimal.Decimal, error) {
+	return validateCarbonStoredInPlot(nil, sum, area)
+}
+
+func validateCarbonStoredInPlot(tr *Trace, sum, area decimal.Decimal) (decimal.Decimal, error) {
+	if area.Equal(decimal.Zero) {
+		return decimal.Decimal{}, ErrZeroPlotArea
+	}
+	return carbonStoredInPlot(tr, sum, area), nil
+}
+
+func carbonStoredInPlot(tr *Trace, sum, area decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("CarbonStoredInPlot").
+		Input("sum", sum).
+		Input("area", area)
+	return tr.Result(sum.Div(area))
 }
 
 // Calculate the carbon stored in each monitoring zone
@@ -59,13 +122,54 @@ func CarbonStoredInPlot(sum, area decimal.Decimal) decimal.Decimal {
 // area - area of monitoring zone
 // numPlots - number of sample plots in monitoring zone
 func CarbonStoredInMonitoringZone(sumOfPlots, numPlots, area decimal.Decimal) decimal.Decimal {
-	return sumOfPlots.Div(numPlots).Mul(area)
+	return carbonStoredInMonitoringZone(nil, sumOfPlots, numPlots, area)
+}
+
+// Calculate the carbon stored in monitoring zone with params validation
+// For more comments see CarbonStoredInMonitoringZone function
+func ValidateCarbonStoredInMonitoringZone(sumOfPlots, numPlots, area decimal.Decimal) (decimal.Decimal, error) {
+	return validateCarbonStoredInMonitoringZone(nil, sumOfPlots, numPlots, area)
+}
+
+func validateCarbonStoredInMonitoringZone(tr *Trace, sumOfPlots, numPlots, area decimal.Decimal) (decimal.Decimal, error) {
+	if numPlots.Equal(decimal.Zero) {
+		return decimal.Decimal{}, ErrNoPlots
+	}
+	return carbonStoredInMonitoringZone(tr, sumOfPlots, numPlots, area), nil
+}
+
+func carbonStoredInMonitoringZone(tr *Trace, sumOfPlots, numPlots, area decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("CarbonStoredInMonitoringZone").
+		Input("sumOfPlots", sumOfPlots).
+		Input("numPlots", numPlots).
+		Input("area", area)
+	return tr.Result(sumOfPlots.Div(numPlots).Mul(area))
 }
 
 // si^2_i
 // Variance of tree biomass per hectare across all sample plots in monitoring zone
 // carbonStoredPlots - an array of carbon in each plot in the monitoring zone
 func VarianceOfTreeBiomass(carbonStoredPlots []decimal.Decimal) decimal.Decimal {
+	return varianceOfTreeBiomass(nil, carbonStoredPlots)
+}
+
+// Variance of tree biomass with params validation
+// For more comments see VarianceOfTreeBiomass function
+func ValidateVarianceOfTreeBiomass(carbonStoredPlots []decimal.Decimal) (decimal.Decimal, error) {
+	if len(carbonStoredPlots) == 0 {
+		return decimal.Decimal{}, ErrNoPlots
+	}
+	if len(carbonStoredPlots) == 1 {
+		return decimal.Decimal{}, ErrSinglePlotZone
+	}
+	return VarianceOfTreeBiomass(carbonStoredPlots), nil
+}
+
+func varianceOfTreeBiomass(tr *Trace, carbonStoredPlots []decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("VarianceOfTreeBiomass")
+	for _, value := range carbonStoredPlots {
+		tr.Input("carbonStoredPlot", value)
+	}
 	n := decimal.NewFromInt(int64(len(carbonStoredPlots)))
 	sum := decimal.New(0, 0)
 	sumSqrt := decimal.New(0, 0)
@@ -74,9 +178,9 @@ func VarianceOfTreeBiomass(carbonStoredPlots []decimal.Decimal) decimal.Decimal
 		sumSqrt = sumSqrt.Add(value.Pow(decimal.New(2, 0)))
 	}
 	sum = sum.Pow(decimal.New(2, 0))
-	return n.Mul(sumSqrt).
+	return tr.Result(n.Mul(sumSqrt).
 		Sub(sum).
-		Div((n.Mul(n.Sub(decimal.New(1, 0)))))
+		Div((n.Mul(n.Sub(decimal.New(1, 0))))))
 }
 
 // Two-sided Student’s t-value for a confidence level of 90 percent
@@ -84,20 +188,34 @@ func VarianceOfTreeBiomass(carbonStoredPlots []decimal.Decimal) decimal.Decimal
 // of sample plots within the tree biomass monitoring zones and M is the
 // total number of tree biomass monitoring zones
 func TDistribution(freedom float64) decimal.Decimal {
+	return tDistribution(nil, freedom, defaultConfidence)
+}
+
+// Two-sided Student’s t-value for the confidence level
+// freedom - degrees of freedom, see TDistribution
+// confidence - two-sided confidence level, e.g. 0.95
+func TDistributionConfidence(freedom, confidence float64) decimal.Decimal {
+	return tDistribution(nil, freedom, confidence)
+}
+
+func tDistribution(tr *Trace, freedom, confidence float64) decimal.Decimal {
+	tr = tr.Step("TDistribution").
+		Input("freedom", decimal.NewFromFloat(freedom)).
+		Input("confidence", decimal.NewFromFloat(confidence))
 	dist1 := distuv.StudentsT{
 		Mu:    0,
 		Sigma: 1,
 		Nu:    freedom,
 		Src:   nil,
 	}
-	return decimal.NewFromFloat(dist1.Quantile(0.95))
+	return tr.Result(decimal.NewFromFloat(dist1.Quantile((1 + confidence) / 2)))
 }
 
 // plots - array contains calculated carbon in each plot
 // area - area of zone (ha)
 type CarbonedZone struct {
-	Plots []decimal.Decimal
-	Area  decimal.Decimal
+	Plots []decimal.Decimal `json:"plots"`
+	Area  decimal.Decimal   `json:"area"`
 }
 
 // Uncertainty in carbon stock in trees
@@ -105,6 +223,51 @@ type CarbonedZone struct {
 // area - area of all monitoring zones (sum of all areas of monitoring zones)
 // zones - array of zones with area and array of cabon in each plot
 func UncertaintyCarbonStored(tDelta, tArea decimal.Decimal, zones []CarbonedZone) decimal.Decimal {
+	return uncertaintyCarbonStored(nil, tDelta, tArea, zones)
+}
+
+// Uncertainty in carbon stock in trees with params validation
+// For more comments see UncertaintyCarbonStored function
+func ValidateUncertaintyCarbonStored(tDelta, tArea decimal.Decimal, zones []CarbonedZone) (decimal.Decimal, error) {
+	return validateUncertaintyCarbonStored(nil, tDelta, tArea, zones)
+}
+
+func validateUncertaintyCarbonStored(tr *Trace, tDelta, tArea decimal.Decimal, zones []CarbonedZone) (decimal.Decimal, error) {
+	if err := validateCarbonedZones(tArea, zones); err != nil {
+		return decimal.Decimal{}, err
+	}
+	return uncertaintyCarbonStored(tr, tDelta, tArea, zones), nil
+}
+
+// Check the zones can be used to estimate the variance of the carbon stock
+func validateCarbonedZones(tArea decimal.Decimal, zones []CarbonedZone) error {
+	if len(zones) == 0 {
+		return ErrNoMonitoringZones
+	}
+	if tArea.Equal(decimal.Zero) {
+		return ErrZeroTotalArea
+	}
+	sumAi := decimal.Zero
+	for _, zone := range zones {
+		if len(zone.Plots) == 0 {
+			return ErrNoPlots
+		}
+		if len(zone.Plots) == 1 {
+			return ErrSinglePlotZone
+		}
+		nI := decimal.NewFromInt(int64(len(zone.Plots)))
+		sumAi = sumAi.Add(zone.Area.Div(tArea).Mul(SumDecimal(zone.Plots).Div(nI)))
+	}
+	if sumAi.Equal(decimal.Zero) {
+		return ErrZeroCarbon
+	}
+	return nil
+}
+
+func uncertaintyCarbonStored(tr *Trace, tDelta, tArea decimal.Decimal, zones []CarbonedZone) decimal.Decimal {
+	tr = tr.Step("UncertaintyCarbonStored").
+		Input("tDelta", tDelta).
+		Input("tArea", tArea)
 	sumAi := decimal.New(0, 0)
 	sumAiPow := decimal.New(0, 0)
 	for _, zone := range zones {
@@ -112,27 +275,23 @@ func UncertaintyCarbonStored(tDelta, tArea decimal.Decimal, zones []CarbonedZone
 		aiDiv := zone.Area.Div(tArea)
 		aiDivPow := aiDiv.Pow(decimal.New(2, 0))
 		sumAi = sumAi.Add(aiDiv.Mul(SumDecimal(zone.Plots).Div(nI)))
-		sumAiPow = sumAiPow.Add(aiDivPow.Mul(VarianceOfTreeBiomass(zone.Plots).Div(nI)))
+		sumAiPow = sumAiPow.Add(aiDivPow.Mul(varianceOfTreeBiomass(tr, zone.Plots).Div(nI)))
 	}
 	sumSqrt := decimal.NewFromFloat(math.Sqrt(sumAiPow.InexactFloat64()))
-	return tDelta.Mul(sumSqrt).Div(sumAi.Abs())
+	return tr.Result(tDelta.Mul(sumSqrt).Div(sumAi.Abs()))
 }
 
 // If uncertainty > 10%, then carbon stored in monitoring zones are made
-// conservative by applying an uncertainty discount
+// conservative by applying an uncertainty discount, see DefaultDiscountTable
 func UncertaintyDiscount(uncertainty decimal.Decimal) decimal.Decimal {
-	uncrt := uncertainty.InexactFloat64()
-	if uncrt <= 0.1 {
-		return decimal.Zero
-	} else if 0.1 < uncrt && uncrt <= 0.15 {
-		return decimal.NewFromFloat(0.25)
-	} else if 0.15 < uncrt && uncrt <= 0.2 {
-		return decimal.NewFromFloat(0.5)
-	} else if 0.2 < uncrt && uncrt <= 0.3 {
-		return decimal.NewFromFloat(0.75)
-	} else {
-		return decimal.NewFromFloat(1)
-	}
+	return DefaultDiscountTable().Discount(uncertainty)
+}
+
+func uncertaintyDeduction(tr *Trace, uncertainty decimal.Decimal, schedule DiscountSchedule) decimal.Decimal {
+	tr = tr.Step("UncertaintyDeduction").
+		Of(schedule.String()).
+		Input("uncertainty", uncertainty)
+	return tr.Result(schedule.Deduction(uncertainty))
 }
 
 // Calculate the total carbon stored in all monitoring zones taking into account
@@ -140,7 +299,23 @@ func UncertaintyDiscount(uncertainty decimal.Decimal) decimal.Decimal {
 // totalCarbon - carbon stored in all monitoring zones
 // uncertainty - uncertainty in carbon stock in trees
 func ConservativeTotalCarbon(totalCarbon, uncertainty decimal.Decimal) decimal.Decimal {
-	return totalCarbon.Mul(decimal.New(1, 0).Sub(uncertainty.Mul(UncertaintyDiscount(uncertainty))))
+	return conservativeTotalCarbon(nil, totalCarbon, uncertainty, DefaultDiscountTable())
+}
+
+// Calculate the total carbon stored in all monitoring zones taking into account
+// the uncertainty with the deduction of the schedule, see Standard
+// schedule - discount schedule of the standard of the project
+// totalCarbon - carbon stored in all monitoring zones
+// uncertainty - uncertainty in carbon stock in trees
+func ConservativeTotalCarbonSchedule(schedule DiscountSchedule, totalCarbon, uncertainty decimal.Decimal) decimal.Decimal {
+	return conservativeTotalCarbon(nil, totalCarbon, uncertainty, schedule)
+}
+
+func conservativeTotalCarbon(tr *Trace, totalCarbon, uncertainty decimal.Decimal, schedule DiscountSchedule) decimal.Decimal {
+	tr = tr.Step("ConservativeTotalCarbon").
+		Input("totalCarbon", totalCarbon).
+		Input("uncertainty", uncertainty)
+	return tr.Result(totalCarbon.Mul(decimal.New(1, 0).Sub(uncertaintyDeduction(tr, uncertainty, schedule))))
 }
 
 // TODO: change names of arguments
@@ -151,7 +326,29 @@ func ConservativeTotalCarbon(totalCarbon, uncertainty decimal.Decimal) decimal.D
 // carbonArea - Carbon stock in trees in monitoring zone
 // totalAreasCarbon - Carbon stock in trees in all monitoring zones
 func AreaConservativeCarbon(conservativeCarbon, carbonArea, totalAreasCarbon decimal.Decimal) decimal.Decimal {
-	return conservativeCarbon.Mul(carbonArea.Div(totalAreasCarbon))
+	return areaConservativeCarbon(nil, conservativeCarbon, carbonArea, totalAreasCarbon)
+}
+
+// Carbon stock in trees in monitoring zone taking into account the uncertainty
+// with params validation
+// For more comments see AreaConservativeCarbon function
+func ValidateAreaConservativeCarbon(conservativeCarbon, carbonArea, totalAreasCarbon decimal.Decimal) (decimal.Decimal, error) {
+	return validateAreaConservativeCarbon(nil, conservativeCarbon, carbonArea, totalAreasCarbon)
+}
+
+func validateAreaConservativeCarbon(tr *Trace, conservativeCarbon, carbonArea, totalAreasCarbon decimal.Decimal) (decimal.Decimal, error) {
+	if totalAreasCarbon.Equal(decimal.Zero) {
+		return decimal.Decimal{}, ErrZeroCarbon
+	}
+	return areaConservativeCarbon(tr, conservativeCarbon, carbonArea, totalAreasCarbon), nil
+}
+
+func areaConservativeCarbon(tr *Trace, conservativeCarbon, carbonArea, totalAreasCarbon decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("AreaConservativeCarbon").
+		Input("conservativeCarbon", conservativeCarbon).
+		Input("carbonArea", carbonArea).
+		Input("totalAreasCarbon", totalAreasCarbon)
+	return tr.Result(conservativeCarbon.Mul(carbonArea.Div(totalAreasCarbon)))
 }
 
 // Calculate the above ground biomass
@@ -160,12 +357,40 @@ func AreaConservativeCarbon(conservativeCarbon, carbonArea, totalAreasCarbon dec
 // cfTree - carbon fraction of tree biomass
 // area - area of monitoring zone
 func AboveGroundBiomass(areaConsCarbon, ratio, cfTree, area decimal.Decimal) decimal.Decimal {
-	if cfTree.Equal(decimal.Zero) {
-		cfTree = decimal.NewFromFloat(0.47)
-	}
-	return areaConsCarbon.Mul(decimal.New(12, 0)).
+	return aboveGroundBiomass(nil, AboveGroundBiomassOptions{
+		AreaConsCarbon: areaConsCarbon,
+		Ratio:          ratio,
+		CfTree:         zeroDefault(cfTree),
+		Area:           area,
+	})
+}
+
+// Options of AboveGroundBiomassWith, see AboveGroundBiomass
+// cfTree - nil if you want to get default value 0.47
+type AboveGroundBiomassOptions struct {
+	AreaConsCarbon decimal.Decimal  `json:"areaConsCarbon"`
+	Ratio          decimal.Decimal  `json:"ratio"`
+	CfTree         *decimal.Decimal `json:"cfTree"`
+	Area           decimal.Decimal  `json:"area"`
+}
+
+// Calculate the above ground biomass, returns the defaults substituted for the
+// options not provided
+func AboveGroundBiomassWith(o AboveGroundBiomassOptions) (decimal.Decimal, Defaults) {
+	tr := NewTrace("AboveGroundBiomassWith")
+	result := aboveGroundBiomass(tr, o)
+	return result, tr.defaults()
+}
+
+func aboveGroundBiomass(tr *Trace, o AboveGroundBiomassOptions) decimal.Decimal {
+	tr = tr.Step("AboveGroundBiomass").
+		Input("areaConsCarbon", o.AreaConsCarbon).
+		Input("ratio", o.Ratio)
+	cfTree := tr.optional("cfTree", o.CfTree, decimal.NewFromFloat(0.47))
+	tr.Input("area", o.Area)
+	return tr.Result(o.AreaConsCarbon.Mul(decimal.New(12, 0)).
 		Div(decimal.New(44, 0).
 			Mul(cfTree).
-			Mul(decimal.New(1, 0).Add(ratio)).
-			Mul(area))
+			Mul(decimal.New(1, 0).Add(o.Ratio)).
+			Mul(o.Area)))
 }
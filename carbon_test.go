@@ -297,3 +297,48 @@ func TestAboveGroundBiomass(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateDivisionByZero(t *testing.T) {
+	type Test struct {
+		call func() (decimal.Decimal, error)
+		err  error
+	}
+	one := decimal.New(1, 0)
+	zone := func(plots ...float64) CarbonedZone {
+		values := []decimal.Decimal{}
+		for _, plot := range plots {
+			values = append(values, decimal.NewFromFloat(plot))
+		}
+		return CarbonedZone{Plots: values, Area: one}
+	}
+	tests := []Test{
+		{func() (decimal.Decimal, error) { return ValidateCarbonStoredInPlot(one, decimal.Zero) }, ErrZeroPlotArea},
+		{func() (decimal.Decimal, error) { return ValidateCarbonStoredInPlot(one, one) }, nil},
+		{func() (decimal.Decimal, error) { return ValidateCarbonStoredInMonitoringZone(one, decimal.Zero, one) }, ErrNoPlots},
+		{func() (decimal.Decimal, error) { return ValidateCarbonStoredInMonitoringZone(one, one, one) }, nil},
+		{func() (decimal.Decimal, error) { return ValidateVarianceOfTreeBiomass(nil) }, ErrNoPlots},
+		{func() (decimal.Decimal, error) { return ValidateVarianceOfTreeBiomass(zone(1).Plots) }, ErrSinglePlotZone},
+		{func() (decimal.Decimal, error) { return ValidateVarianceOfTreeBiomass(zone(1, 2).Plots) }, nil},
+		{func() (decimal.Decimal, error) { return ValidateUncertaintyCarbonStored(one, one, nil) }, ErrNoMonitoringZones},
+		{func() (decimal.Decimal, error) {
+			return ValidateUncertaintyCarbonStored(one, decimal.Zero, []CarbonedZone{zone(1, 2)})
+		}, ErrZeroTotalArea},
+		{func() (decimal.Decimal, error) {
+			return ValidateUncertaintyCarbonStored(one, one, []CarbonedZone{zone(1, 2), zone(3)})
+		}, ErrSinglePlotZone},
+		{func() (decimal.Decimal, error) {
+			return ValidateUncertaintyCarbonStored(one, one, []CarbonedZone{zone(0, 0), zone(0, 0)})
+		}, ErrZeroCarbon},
+		{func() (decimal.Decimal, error) {
+			return ValidateUncertaintyCarbonStored(one, one, []CarbonedZone{zone(1, 2), zone(3, 4)})
+		}, nil},
+		{func() (decimal.Decimal, error) { return ValidateAreaConservativeCarbon(one, one, decimal.Zero) }, ErrZeroCarbon},
+		{func() (decimal.Decimal, error) { return ValidateAreaConservativeCarbon(one, one, one) }, nil},
+	}
+	for i, tt := range tests {
+		_, err := tt.call()
+		if err != tt.err {
+			t.Fatalf("Test number %d, expect: %v, have: %v", i, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	carbon "github.com/nexeranet/carbon_calc"
+	"github.com/shopspring/decimal"
+)
+
+// Project configuration of the stage
+// parameters - path to the parameter set (JSON or YAML), empty for default
+// species - path to the wood density CSV, empty if not used
+// Paths are relative to the configuration file
+type config struct {
+	DeltaTime      decimal.Decimal             `json:"deltaTime"`
+	Leakage        decimal.Decimal             `json:"leakage"`
+	OtherEmissions decimal.Decimal             `json:"otherEmissions"`
+	BufferPercent  *decimal.Decimal            `json:"bufferPercent"`
+	HoldersPercent *decimal.Decimal            `json:"holdersPercent"`
+	Confidence     float64                     `json:"confidence"`
+	Standard       carbon.Standard             `json:"standard"`
+	Discount       *carbon.DiscountTable       `json:"discount"`
+	LinearDiscount *carbon.LinearDiscount      `json:"linearDiscount"`
+	Fertilizers    []carbon.Fertilizer         `json:"fertilizers"`
+	Burning        []carbon.BurningEvent       `json:"burning"`
+	Fuels          []carbon.FuelConsumption    `json:"fuels"`
+	GWP            carbon.GWPSet               `json:"gwp"`
+	Displacements  []carbon.Displacement       `json:"displacements"`
+	Fuelwood       []carbon.FuelwoodCollection `json:"fuelwood"`
+	Parameters     string                      `json:"parameters"`
+	Species        string                      `json:"species"`
+}
+
+func readConfig(path string) (config, error) {
+	var c config
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	if c.Parameters != "" && !filepath.IsAbs(c.Parameters) {
+		c.Parameters = filepath.Join(dir, c.Parameters)
+	}
+	if c.Species != "" && !filepath.IsAbs(c.Species) {
+		c.Species = filepath.Join(dir, c.Species)
+	}
+	return c, nil
+}
+
+func readPrevious(path string) (*carbon.StageResult, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	previous := &carbon.StageResult{}
+	if err := json.Unmarshal(data, previous); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return previous, nil
+}
+
+// CSV file with named columns
+type table struct {
+	path    string
+	columns map[string]int
+	records [][]string
+}
+
+func readTable(path string) (*table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseTable(path, file)
+}
+
+func parseTable(path string, r io.Reader) (*table, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: missing header", path)
+	}
+	t := &table{path: path, columns: map[string]int{}, records: records[1:]}
+	for i, name := range records[0] {
+		t.columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return t, nil
+}
+
+func (t *table) require(names ...string) error {
+	for _, name := range names {
+		if _, ok := t.columns[name]; !ok {
+			return fmt.Errorf("%s: missing column %q", t.path, name)
+		}
+	}
+	return nil
+}
+
+func (t *table) value(record []string, name string) string {
+	i, ok := t.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// Decimal value of the column, zero if the column is empty
+func (t *table) decimal(line int, record []string, name string) (decimal.Decimal, error) {
+	value := t.value(record, name)
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	result, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("%s line %d: column %q: %w", t.path, line, name, err)
+	}
+	return result, nil
+}
+
+// Value of the optional column, nil if the value is empty
+func (t *table) optionalDecimal(line int, record []string, name string) (*decimal.Decimal, error) {
+	if t.value(record, name) == "" {
+		return nil, nil
+	}
+	result, err := t.decimal(line, record, name)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (t *table) text(line int, record []string, name string, value interface{ UnmarshalText([]byte) error }) error {
+	if err := value.UnmarshalText([]byte(t.value(record, name))); err != nil {
+		return fmt.Errorf("%s line %d: column %q: %w", t.path, line, name, err)
+	}
+	return nil
+}
+
+// Read monitoring zones from zones.csv with columns zone, area, plot_area and
+// optional forest_type, species, rainfall (class name or amount in mm),
+// ecological_zone, aboveground_biomass, baseline, equation, dead_wood and litter
+// (excluded or default, the measured pools need the plot measurements), SOC is
+// accounted if one of climate, soil_type or soc_reference is present with
+// optional land_use, management and input factors, shrubs are accounted if
+// forest_biomass is present with shrub_cover and baseline_shrub_cover
+func readZones(t *table) ([]carbon.MonitoringZone, map[string]decimal.Decimal, error) {
+	if err := t.require("zone", "area", "plot_area"); err != nil {
+		return nil, nil, err
+	}
+	var zones []carbon.MonitoringZone
+	plotAreas := map[string]decimal.Decimal{}
+	for i, record := range t.records {
+		line := i + 2
+		zone := carbon.MonitoringZone{ID: t.value(record, "zone")}
+		var err error
+		if zone.Area, err = t.decimal(line, record, "area"); err != nil {
+			return nil, nil, err
+		}
+		if plotAreas[zone.ID], err = t.decimal(line, record, "plot_area"); err != nil {
+			return nil, nil, err
+		}
+		if zone.Baseline, err = t.decimal(line, record, "baseline"); err != nil {
+			return nil, nil, err
+		}
+		if t.value(record, "forest_type") != "" {
+			if err := t.text(line, record, "forest_type", &zone.ForestType); err != nil {
+				return nil, nil, err
+			}
+		}
+		if t.value(record, "species") != "" {
+			if err := t.text(line, record, "species", &zone.Species); err != nil {
+				return nil, nil, err
+			}
+		}
+		if rainfall := t.value(record, "rainfall"); rainfall != "" {
+			if amount, err := strconv.ParseInt(rainfall, 10, 64); err == nil {
+				zone.Rainfall = carbon.GetRainfallType(amount)
+			} else if err := t.text(line, record, "rainfall", &zone.Rainfall); err != nil {
+				return nil, nil, err
+			}
+		}
+		if t.value(record, "ecological_zone") != "" {
+			ecologicalZone := new(carbon.EcologicalZone)
+			if err := t.text(line, record, "ecological_zone", ecologicalZone); err != nil {
+				return nil, nil, err
+			}
+			zone.EcologicalZone = ecologicalZone
+		}
+		if biomass := t.value(record, "aboveground_biomass"); biomass != "" {
+			if zone.AbovegroundBiomass, err = strconv.ParseFloat(biomass, 64); err != nil {
+				return nil, nil, fmt.Errorf("%s line %d: column %q: %w", t.path, line, "aboveground_biomass", err)
+			}
+		}
+		if name := t.value(record, "equation"); name != "" {
+			if zone.Equation, err = carbon.AllometricEquationByName(name); err != nil {
+				return nil, nil, fmt.Errorf("%s line %d: %w", t.path, line, err)
+			}
+		}
+		for _, pool := range []struct {
+			name string
+			mode *carbon.PoolMode
+		}{{"dead_wood", &zone.DeadWood}, {"litter", &zone.Litter}} {
+			if t.value(record, pool.name) == "" {
+				continue
+			}
+			if err := t.text(line, record, pool.name, pool.mode); err != nil {
+				return nil, nil, err
+			}
+			if *pool.mode == carbon.PoolMeasured {
+				return nil, nil, fmt.Errorf("%s line %d: column %q: measured pool is not supported", t.path, line, pool.name)
+			}
+		}
+		if zone.Soil, err = readSoil(t, line, record); err != nil {
+			return nil, nil, err
+		}
+		if zone.Shrubs, err = readShrubs(t, line, record); err != nil {
+			return nil, nil, err
+		}
+		zones = append(zones, zone)
+	}
+	return zones, plotAreas, nil
+}
+
+func readSoil(t *table, line int, record []string) (*carbon.SoilCarbon, error) {
+	if t.value(record, "climate") == "" && t.value(record, "soil_type") == "" && t.value(record, "soc_reference") == "" {
+		return nil, nil
+	}
+	soil := &carbon.SoilCarbon{}
+	if t.value(record, "climate") != "" {
+		if err := t.text(line, record, "climate", &soil.Climate); err != nil {
+			return nil, err
+		}
+	}
+	if t.value(record, "soil_type") != "" {
+		if err := t.text(line, record, "soil_type", &soil.SoilType); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if soil.Reference, err = t.decimal(line, record, "soc_reference"); err != nil {
+		return nil, err
+	}
+	if soil.LandUse, err = t.decimal(line, record, "land_use"); err != nil {
+		return nil, err
+	}
+	if soil.Management, err = t.decimal(line, record, "management"); err != nil {
+		return nil, err
+	}
+	if soil.Input, err = t.decimal(line, record, "input"); err != nil {
+		return nil, err
+	}
+	return soil, nil
+}
+
+func readShrubs(t *table, line int, record []string) (*carbon.Shrubs, error) {
+	if t.value(record, "forest_biomass") == "" {
+		return nil, nil
+	}
+	shrubs := &carbon.Shrubs{}
+	var err error
+	if shrubs.ForestBiomass, err = t.decimal(line, record, "forest_biomass"); err != nil {
+		return nil, err
+	}
+	if shrubs.CrownCover, err = t.decimal(line, record, "shrub_cover"); err != nil {
+		return nil, err
+	}
+	if shrubs.BaselineCrownCover, err = t.decimal(line, record, "baseline_shrub_cover"); err != nil {
+		return nil, err
+	}
+	return shrubs, nil
+}
+
+// Read plots from plots.csv with columns zone, plot and optional area (ha),
+// plot_area of the zone by default. Every measured plot is declared, so the
+// plots without trees are kept in the zone.
+func readPlots(t *table, zones []carbon.MonitoringZone, plotAreas map[string]decimal.Decimal) error {
+	if err := t.require("zone", "plot"); err != nil {
+		return err
+	}
+	zoneIndex := map[string]int{}
+	for i, zone := range zones {
+		zoneIndex[zone.ID] = i
+	}
+	for i, record := range t.records {
+		line := i + 2
+		zoneID := t.value(record, "zone")
+		z, ok := zoneIndex[zoneID]
+		if !ok {
+			return fmt.Errorf("%s line %d: unknown zone %q", t.path, line, zoneID)
+		}
+		plot := carbon.Plot{ID: t.value(record, "plot"), Area: plotAreas[zoneID], Trees: []carbon.Tree{}}
+		area, err := t.decimal(line, record, "area")
+		if err != nil {
+			return err
+		}
+		if !area.IsZero() {
+			plot.Area = area
+		}
+		zones[z].Plots = append(zones[z].Plots, plot)
+	}
+	return nil
+}
+
+// Read trees from trees.csv with columns zone, plot, height, circumference or
+// radius (m) and optional tree, species (scientific name, scientific_name is
+// accepted as an alias), family, density
+// Trees are grouped into the plots of the zones, if the plots are declared in
+// plots.csv the trees must belong to them, otherwise the plots are taken from
+// the trees in order of appearance
+func readTrees(t *table, zones []carbon.MonitoringZone, plotAreas map[string]decimal.Decimal, declared bool) error {
+	if err := t.require("zone", "plot", "height"); err != nil {
+		return err
+	}
+	_, hasRadius := t.columns["radius"]
+	_, hasCircumference := t.columns["circumference"]
+	if !hasRadius && !hasCircumference {
+		return fmt.Errorf("%s: missing column %q or %q", t.path, "radius", "circumference")
+	}
+	zoneIndex := map[string]int{}
+	for i, zone := range zones {
+		zoneIndex[zone.ID] = i
+	}
+	plotIndex := map[[2]string]int{}
+	for _, zone := range zones {
+		for p, plot := range zone.Plots {
+			plotIndex[[2]string{zone.ID, plot.ID}] = p
+		}
+	}
+	for i, record := range t.records {
+		line := i + 2
+		zoneID, plotID := t.value(record, "zone"), t.value(record, "plot")
+		z, ok := zoneIndex[zoneID]
+		if !ok {
+			return fmt.Errorf("%s line %d: unknown zone %q", t.path, line, zoneID)
+		}
+		zone := &zones[z]
+		p, ok := plotIndex[[2]string{zoneID, plotID}]
+		if !ok && declared {
+			return fmt.Errorf("%s line %d: unknown plot %q of zone %q", t.path, line, plotID, zoneID)
+		}
+		if !ok {
+			p = len(zone.Plots)
+			plotIndex[[2]string{zoneID, plotID}] = p
+			zone.Plots = append(zone.Plots, carbon.Plot{ID: plotID, Area: plotAreas[zoneID]})
+		}
+		tree := carbon.Tree{
+			ID:             t.value(record, "tree"),
+			ScientificName: t.value(record, "species"),
+			Family:         t.value(record, "family"),
+		}
+		if tree.ID == "" {
+			tree.ID = strconv.Itoa(line)
+		}
+		if tree.ScientificName == "" {
+			tree.ScientificName = t.value(record, "scientific_name")
+		}
+		var err error
+		if tree.Height, err = t.decimal(line, record, "height"); err != nil {
+			return err
+		}
+		if tree.Density, err = t.optionalDecimal(line, record, "density"); err != nil {
+			return err
+		}
+		if tree.Radius, err = t.decimal(line, record, "radius"); err != nil {
+			return err
+		}
+		if tree.Radius.Equal(decimal.Zero) {
+			circumference, err := t.decimal(line, record, "circumference")
+			if err != nil {
+				return err
+			}
+			tree.Radius = decimal.NewFromFloat(carbon.Radius(circumference.InexactFloat64()))
+		}
+		zone.Plots[p].Trees = append(zone.Plots[p].Trees, tree)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Command carboncalc runs the full stage calculation from CSV inputs
+//
+// Usage:
+//
+//	carboncalc -trees trees.csv -zones zones.csv [-plots plots.csv]
+//	           [-config project.json] [-previous previous.json]
+//	           [-format table|json] [-explain]
+//
+// The plots without trees are only accounted if they are declared in -plots,
+// otherwise the plots are taken from trees.csv
+//
+// The JSON output of one stage can be passed as -previous of the next stage
+// to compute the minted OCC
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	carbon "github.com/nexeranet/carbon_calc"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("carboncalc", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	treesPath := flags.String("trees", "", "path to trees.csv")
+	zonesPath := flags.String("zones", "", "path to zones.csv")
+	plotsPath := flags.String("plots", "", "path to plots.csv, declares every measured plot")
+	configPath := flags.String("config", "", "path to the project config JSON")
+	previousPath := flags.String("previous", "", "path to the previous stage result JSON")
+	format := flags.String("format", "table", "output format: table or json")
+	explain := flags.Bool("explain", false, "include the calculation trace in JSON output")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *treesPath == "" || *zonesPath == "" {
+		fmt.Fprintln(stderr, "carboncalc: -trees and -zones are required")
+		flags.Usage()
+		return 2
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(stderr, "carboncalc: unknown format %q\n", *format)
+		return 2
+	}
+	stage, err := loadStage(*treesPath, *zonesPath, *plotsPath, *configPath, *previousPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "carboncalc: %v\n", err)
+		return 1
+	}
+	stage.Explain = *explain
+	result, err := stage.Calculate()
+	if err != nil {
+		fmt.Fprintf(stderr, "carboncalc: %v\n", err)
+		return 1
+	}
+	if *format == "json" {
+		err = writeJSON(stdout, result)
+	} else {
+		err = writeTable(stdout, result)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "carboncalc: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func loadStage(treesPath, zonesPath, plotsPath, configPath, previousPath string) (carbon.Stage, error) {
+	c, err := readConfig(configPath)
+	if err != nil {
+		return carbon.Stage{}, err
+	}
+	stage := carbon.Stage{
+		DeltaTime:      c.DeltaTime,
+		Leakage:        c.Leakage,
+		OtherEmissions: c.OtherEmissions,
+		BufferPercent:  c.BufferPercent,
+		HoldersPercent: c.HoldersPercent,
+		Confidence:     c.Confidence,
+		Standard:       c.Standard,
+		Discount:       c.Discount,
+		LinearDiscount: c.LinearDiscount,
+		Fertilizers:    c.Fertilizers,
+		Burning:        c.Burning,
+		Fuels:          c.Fuels,
+		GWP:            c.GWP,
+		Displacements:  c.Displacements,
+		Fuelwood:       c.Fuelwood,
+	}
+	if c.Parameters != "" {
+		if stage.Parameters, err = carbon.LoadParameterSet(c.Parameters); err != nil {
+			return stage, err
+		}
+	}
+	if c.Species != "" {
+		if stage.Species, err = carbon.LoadSpeciesRegistry(c.Species); err != nil {
+			return stage, err
+		}
+	}
+	if stage.Previous, err = readPrevious(previousPath); err != nil {
+		return stage, err
+	}
+	zonesTable, err := readTable(zonesPath)
+	if err != nil {
+		return stage, err
+	}
+	zones, plotAreas, err := readZones(zonesTable)
+	if err != nil {
+		return stage, err
+	}
+	if plotsPath != "" {
+		plotsTable, err := readTable(plotsPath)
+		if err != nil {
+			return stage, err
+		}
+		if err := readPlots(plotsTable, zones, plotAreas); err != nil {
+			return stage, err
+		}
+	}
+	treesTable, err := readTable(treesPath)
+	if err != nil {
+		return stage, err
+	}
+	if err := readTrees(treesTable, zones, plotAreas, plotsPath != ""); err != nil {
+		return stage, err
+	}
+	stage.Zones = zones
+	return stage, nil
+}
+
+func writeJSON(w io.Writer, result carbon.StageResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func writeTable(w io.Writer, result carbon.StageResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "zone\tplots\tcarbon\tconservative\tbaseline\tminted OCC\t")
+	for _, zone := range result.Zones {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t\n",
+			zone.ID,
+			len(zone.Plots),
+			zone.Carbon.StringFixed(3),
+			zone.ConservativeCarbon.StringFixed(3),
+			zone.Baseline.StringFixed(3),
+			zone.MintedOCC.StringFixed(3))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range []struct {
+		name  string
+		value string
+	}{
+		{"total area", result.TotalArea.StringFixed(3)},
+		{"total carbon", result.TotalCarbon.StringFixed(3)},
+		{"dead wood", result.DeadWood.StringFixed(3)},
+		{"litter", result.Litter.StringFixed(3)},
+		{"shrubs", result.Shrubs.StringFixed(3)},
+		{"baseline shrubs", result.BaselineShrubs.StringFixed(3)},
+		{"confidence", fmt.Sprintf("%g", result.Confidence)},
+		{"standard error", result.CarbonStandardError.StringFixed(3)},
+		{"degrees of freedom", fmt.Sprint(result.DegreesOfFreedom)},
+		{"carbon interval", result.CarbonLower.StringFixed(3) + " - " + result.CarbonUpper.StringFixed(3)},
+		{"uncertainty", result.Uncertainty.StringFixed(3)},
+		{"discount schedule", result.DiscountSchedule},
+		{"uncertainty discount", result.UncertaintyDiscount.StringFixed(3)},
+		{"uncertainty deduction", result.UncertaintyDeduction.StringFixed(3)},
+		{"conservative carbon", result.ConservativeCarbon.StringFixed(3)},
+		{"SOC change", result.SOCChange.StringFixed(3)},
+		{"SOC", result.SOC.StringFixed(3)},
+		{"baseline", result.Baseline.StringFixed(3)},
+		{"GWP set", result.EmissionsStatement.GWP.String()},
+		{"burning emissions", result.BurningEmissions.StringFixed(3)},
+		{"fuel emissions", result.FuelEmissions.StringFixed(3)},
+		{"emissions", result.Emissions.StringFixed(3)},
+		{"leakage", result.LeakageStatement.Total.StringFixed(3)},
+		{"leakage fraction", result.Leakage.StringFixed(3)},
+		{"net emissions removal", result.NetEmissionsRemoval.StringFixed(3)},
+		{"minted OCC", result.MintedOCC.StringFixed(3)},
+		{"buffer pool", result.BufferPool.StringFixed(3)},
+		{"holders", result.Holders.StringFixed(3)},
+		{"parameter set", result.ParameterSet},
+	} {
+		fmt.Fprintf(tw, "%s\t%s\n", row.name, row.value)
+	}
+	names := make([]string, 0, len(result.Defaults))
+	for name := range result.Defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(tw, "default %s\t%s\n", name, result.Defaults[name])
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	carbon "github.com/nexeranet/carbon_calc"
+	"github.com/shopspring/decimal"
+)
+
+const testZones = `zone,area,plot_area,forest_type,species,rainfall
+zone-1,8,0.1,temperate,coniferous,
+zone-2,1,0.1,tropical-subtropical,broadleaf,1500
+`
+
+const testTrees = `zone,plot,tree,species,circumference,radius,height
+zone-1,plot-1,tree-1,,,0.05,5
+zone-1,plot-1,tree-2,,,0.06,4.3
+zone-1,plot-2,tree-3,,,0.04,3
+zone-1,plot-2,tree-4,,,0.035,1.2
+zone-2,plot-3,tree-5,,0.314159,,6
+zone-2,plot-3,tree-6,,,0.06,4.5
+zone-2,plot-4,tree-7,,,0.045,3.1
+`
+
+const testConfig = `{"deltaTime": "1", "leakage": "0.05", "bufferPercent": 0.07, "holdersPercent": 0.08}`
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runJSON(t *testing.T, args ...string) carbon.StageResult {
+	var stdout, stderr bytes.Buffer
+	if code := run(append(args, "-format", "json"), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	var result carbon.StageResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestRun(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"zones.csv":    testZones,
+		"trees.csv":    testTrees,
+		"project.json": testConfig,
+	})
+	args := []string{
+		"-trees", filepath.Join(dir, "trees.csv"),
+		"-zones", filepath.Join(dir, "zones.csv"),
+		"-config", filepath.Join(dir, "project.json"),
+	}
+	result := runJSON(t, args...)
+
+	tree := func(id string, radius, height float64) carbon.Tree {
+		return carbon.Tree{ID: id, Radius: decimal.NewFromFloat(radius), Height: decimal.NewFromFloat(height)}
+	}
+	plot := func(id string, trees ...carbon.Tree) carbon.Plot {
+		return carbon.Plot{ID: id, Area: decimal.NewFromFloat(0.1), Trees: trees}
+	}
+	stage := carbon.Stage{
+		Zones: []carbon.MonitoringZone{
+			{
+				ID:         "zone-1",
+				Area:       decimal.New(8, 0),
+				ForestType: carbon.ForestTypeTemperate,
+				Species:    carbon.TreeSpeciesConiferous,
+				Plots: []carbon.Plot{
+					plot("plot-1", tree("tree-1", 0.05, 5), tree("tree-2", 0.06, 4.3)),
+					plot("plot-2", tree("tree-3", 0.04, 3), tree("tree-4", 0.035, 1.2)),
+				},
+			},
+			{
+				ID:         "zone-2",
+				Area:       decimal.New(1, 0),
+				ForestType: carbon.ForestTypeTropicalSubtropical,
+				Species:    carbon.TreeSpeciesBroadleaf,
+				Rainfall:   carbon.RainfallTypeMoist,
+				Plots: []carbon.Plot{
+					plot("plot-3", tree("tree-5", carbon.Radius(0.314159), 6), tree("tree-6", 0.06, 4.5)),
+					plot("plot-4", tree("tree-7", 0.045, 3.1)),
+				},
+			},
+		},
+		DeltaTime:      decimal.New(1, 0),
+		Leakage:        decimal.NewFromFloat(0.05),
+		BufferPercent:  carbon.Optional(decimal.NewFromFloat(0.07)),
+		HoldersPercent: carbon.Optional(decimal.NewFromFloat(0.08)),
+	}
+	expect, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.TotalCarbon.Equal(expect.TotalCarbon) || !result.MintedOCC.Equal(expect.MintedOCC) {
+		t.Fatalf("expect: %s / %s, have: %s / %s", expect.TotalCarbon, expect.MintedOCC, result.TotalCarbon, result.MintedOCC)
+	}
+	for i, zone := range expect.Zones {
+		if !result.Zones[i].Carbon.Equal(zone.Carbon) || !result.Zones[i].MintedOCC.Equal(zone.MintedOCC) {
+			t.Fatalf("Zone %s, expect: %v, have: %v", zone.ID, zone, result.Zones[i])
+		}
+	}
+	if !result.Zones[0].Plots[1].Trees[1].Excluded {
+		t.Fatalf("Tree under 1.3 m should be excluded")
+	}
+
+	// Second stage with the first one as previous
+	previous, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "previous.json"), previous, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	next := runJSON(t, append(args, "-previous", filepath.Join(dir, "previous.json"))...)
+	if !next.MintedOCC.Equal(decimal.Zero) {
+		t.Fatalf("Same stage twice should not mint OCC, have: %s", next.MintedOCC)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	for _, line := range []string{"zone-1", "zone-2", "minted OCC", expect.MintedOCC.StringFixed(3)} {
+		if !strings.Contains(stdout.String(), line) {
+			t.Fatalf("Table should contain %q:\n%s", line, stdout.String())
+		}
+	}
+}
+
+func TestRunPlots(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"zones.csv": testZones,
+		"trees.csv": testTrees,
+		// plot-5 is measured and has no trees
+		"plots.csv": "zone,plot,area\nzone-1,plot-1,\nzone-1,plot-2,\nzone-2,plot-3,\nzone-2,plot-4,\nzone-2,plot-5,0.2\n",
+	})
+	result := runJSON(t,
+		"-trees", filepath.Join(dir, "trees.csv"),
+		"-zones", filepath.Join(dir, "zones.csv"),
+		"-plots", filepath.Join(dir, "plots.csv"),
+	)
+	derived := runJSON(t,
+		"-trees", filepath.Join(dir, "trees.csv"),
+		"-zones", filepath.Join(dir, "zones.csv"),
+	)
+	zone := result.Zones[1]
+	if len(zone.Plots) != 3 || zone.Plots[2].ID != "plot-5" || !zone.Plots[2].CarbonPerHa.IsZero() {
+		t.Fatalf("Plot without trees should be kept: %v", zone.Plots)
+	}
+	// The empty plot lowers the zone mean by one third
+	expect := derived.Zones[1].Carbon.Mul(decimal.New(2, 0)).Div(decimal.New(3, 0))
+	if !zone.Carbon.Round(12).Equal(expect.Round(12)) {
+		t.Fatalf("expect: %s, have: %s", expect, zone.Carbon)
+	}
+	if !result.Zones[0].Carbon.Equal(derived.Zones[0].Carbon) {
+		t.Fatalf("expect: %s, have: %s", derived.Zones[0].Carbon, result.Zones[0].Carbon)
+	}
+
+	dir = writeTestFiles(t, map[string]string{
+		"zones.csv": testZones,
+		"trees.csv": testTrees,
+		"plots.csv": "zone,plot\nzone-1,plot-1\nzone-1,plot-2\nzone-2,plot-3\n",
+	})
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-trees", filepath.Join(dir, "trees.csv"), "-zones", filepath.Join(dir, "zones.csv"), "-plots", filepath.Join(dir, "plots.csv")}, &stdout, &stderr)
+	if message := `line 8: unknown plot "plot-4" of zone "zone-2"`; code != 1 || !strings.Contains(stderr.String(), message) {
+		t.Fatalf("expect: %s, have: %d %s", message, code, stderr.String())
+	}
+}
+
+func TestRunSpeciesColumn(t *testing.T) {
+	var results []carbon.StageResult
+	for _, column := range []string{"species", "scientific_name"} {
+		dir := writeTestFiles(t, map[string]string{
+			"zones.csv":    testZones,
+			"trees.csv":    strings.Replace(strings.Replace(testTrees, "species", column, 1), "tree-1,,", "tree-1,Tectona grandis,", 1),
+			"density.csv":  "Family,Binomial,Wood density (g/cm^3)\nLamiaceae,Tectona grandis,0.9\n",
+			"project.json": `{"species": "density.csv"}`,
+		})
+		results = append(results, runJSON(t,
+			"-trees", filepath.Join(dir, "trees.csv"),
+			"-zones", filepath.Join(dir, "zones.csv"),
+			"-config", filepath.Join(dir, "project.json"),
+		))
+	}
+	for _, result := range results {
+		if match := result.Zones[0].Plots[0].Trees[0].DensityMatch; match != carbon.DensityMatchSpecies {
+			t.Fatalf("expect: %s, have: %s", carbon.DensityMatchSpecies, match)
+		}
+	}
+	if !results[0].TotalCarbon.Equal(results[1].TotalCarbon) {
+		t.Fatalf("expect: %s, have: %s", results[0].TotalCarbon, results[1].TotalCarbon)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	type Test struct {
+		zones, trees, message string
+	}
+	tests := []Test{
+		{"zone,area\nzone-1,8\n", testTrees, `missing column "plot_area"`},
+		{testZones, "zone,plot,height\nzone-1,plot-1,5\n", `missing column "radius" or "circumference"`},
+		{testZones, "zone,plot,radius,height\nzone-3,plot-1,0.05,5\n", `line 2: unknown zone "zone-3"`},
+		{"zone,area,plot_area,rainfall\nzone-1,8,0.1,humid\n", testTrees, `column "rainfall"`},
+		{"zone,area,plot_area,litter\nzone-1,8,0.1,measured\n", testTrees, `column "litter": measured pool is not supported`},
+		{"zone,area,plot_area,climate\nzone-1,8,0.1,arctic\n", testTrees, `column "climate"`},
+		{"zone,area,plot_area,forest_biomass,shrub_cover\nzone-1,8,0.1,100,half\n", testTrees, `column "shrub_cover"`},
+		{testZones, "zone,plot,radius,height\nzone-1,plot-1,0.05,5\nzone-2,plot-2,0.05,5\n", "must contain at least two sample plots"},
+	}
+	for i, tt := range tests {
+		dir := writeTestFiles(t, map[string]string{"zones.csv": tt.zones, "trees.csv": tt.trees})
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"-trees", filepath.Join(dir, "trees.csv"), "-zones", filepath.Join(dir, "zones.csv")}, &stdout, &stderr)
+		if code != 1 || !strings.Contains(stderr.String(), tt.message) {
+			t.Fatalf("Test number %d, expect: %s, have: %d %s", i, tt.message, code, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Command carbonserver serves the HTTP JSON API of the calculation library,
+// see package server for the endpoints
+//
+// Usage:
+//
+//	carbonserver [-addr :8080] [-parameters parameters.yaml] [-species gwdd.csv]
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	carbon "github.com/nexeranet/carbon_calc"
+	"github.com/nexeranet/carbon_calc/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	parameters := flag.String("parameters", "", "path to the parameter set (JSON or YAML)")
+	species := flag.String("species", "", "path to the wood density CSV")
+	flag.Parse()
+
+	s := server.New()
+	var err error
+	if *parameters != "" {
+		if s.Parameters, err = carbon.LoadParameterSet(*parameters); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *species != "" {
+		if s.Species, err = carbon.LoadSpeciesRegistry(*species); err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
@@ -2,17 +2,36 @@ package carbon_calc
 
 import (
 	"errors"
+	"fmt"
+
 	"github.com/shopspring/decimal"
 )
 
 var NotEnoughHeight = errors.New("Trees should be more than 1.3 m tall to be considered in the carbon calculation.")
 
+var ErrNoMonitoringZones = errors.New("Stage should contain at least one monitoring zone.")
+
+var ErrNoPlots = errors.New("Monitoring zone should contain at least one sample plot.")
+
+var ErrZeroPlotArea = errors.New("Area of sample plot should be more than 0.")
+
+var ErrSinglePlotZone = errors.New("Monitoring zone should contain at least two sample plots to calculate the variance.")
+
+var ErrZeroTotalArea = errors.New("Area of all monitoring zones should be more than 0.")
+
+var ErrZeroCarbon = errors.New("Carbon stored in all monitoring zones should not be 0.")
+
+var ErrNoCarbonChange = errors.New("Carbon stored in all monitoring zones should differ from the previous stage.")
+
 type ForestType uint8
 
 type TreeSpecies uint8
 
 type RainfallType uint8
 
+// IPCC global ecological zone
+type EcologicalZone uint8
+
 const (
 	RainfallTypeDry RainfallType = iota
 	RainfallTypeMoist
@@ -33,6 +52,160 @@ const (
 	TreeSpeciesForestTundra
 	TreeSpeciesMixedConiferousAndBroadleaf
 	TreeSpeciesPines
+	TreeSpeciesOak
+	TreeSpeciesEucalyptus
+)
+
+const (
+	EcologicalZoneTropicalRainForest EcologicalZone = iota
+	EcologicalZoneTropicalMoistDeciduousForest
+	EcologicalZoneTropicalDryForest
+	EcologicalZoneTropicalShrubland
+	EcologicalZoneTropicalMountainSystems
+	EcologicalZoneSubtropicalHumidForest
+	EcologicalZoneSubtropicalDryForest
+	EcologicalZoneSubtropicalSteppe
+	EcologicalZoneSubtropicalMountainSystems
+	EcologicalZoneTemperateOceanicForest
+	EcologicalZoneTemperateContinentalForest
+	EcologicalZoneTemperateMountainSystems
+	EcologicalZoneBorealConiferousForest
+	EcologicalZoneBorealTundraWoodland
+	EcologicalZoneBorealMountainSystems
+)
+
+var rainfallTypeNames = map[RainfallType]string{
+	RainfallTypeDry:   "dry",
+	RainfallTypeMoist: "moist",
+	RainfallTypeWet:   "wet",
+}
+
+var forestTypeNames = map[ForestType]string{
+	ForestTypeTropicalSubtropical: "tropical-subtropical",
+	ForestTypeTemperate:           "temperate",
+	ForestTypeBoreal:              "boreal",
+}
+
+var treeSpeciesNames = map[TreeSpecies]string{
+	TreeSpeciesConiferous:                  "coniferous",
+	TreeSpeciesBroadleaf:                   "broadleaf",
+	TreeSpeciesForestTundra:                "forest-tundra",
+	TreeSpeciesMixedConiferousAndBroadleaf: "mixed-coniferous-and-broadleaf",
+	TreeSpeciesPines:                       "pines",
+	TreeSpeciesOak:                         "oak",
+	TreeSpeciesEucalyptus:                  "eucalyptus",
+}
+
+var ecologicalZoneNames = map[EcologicalZone]string{
+	EcologicalZoneTropicalRainForest:           "tropical-rain-forest",
+	EcologicalZoneTropicalMoistDeciduousForest: "tropical-moist-deciduous-forest",
+	EcologicalZoneTropicalDryForest:            "tropical-dry-forest",
+	EcologicalZoneTropicalShrubland:            "tropical-shrubland",
+	EcologicalZoneTropicalMountainSystems:      "tropical-mountain-systems",
+	EcologicalZoneSubtropicalHumidForest:       "subtropical-humid-forest",
+	EcologicalZoneSubtropicalDryForest:         "subtropical-dry-forest",
+	EcologicalZoneSubtropicalSteppe:            "subtropical-steppe",
+	EcologicalZoneSubtropicalMountainSystems:   "subtropical-mountain-systems",
+	EcologicalZoneTemperateOceanicForest:       "temperate-oceanic-forest",
+	EcologicalZoneTemperateContinentalForest:   "temperate-continental-forest",
+	EcologicalZoneTemperateMountainSystems:     "temperate-mountain-systems",
+	EcologicalZoneBorealConiferousForest:       "boreal-coniferous-forest",
+	EcologicalZoneBorealTundraWoodland:         "boreal-tundra-woodland",
+	EcologicalZoneBorealMountainSystems:        "boreal-mountain-systems",
+}
+
+func (r RainfallType) String() string {
+	return enumName(rainfallTypeNames, r)
+}
+
+func (r RainfallType) MarshalText() ([]byte, error) {
+	return marshalEnum(rainfallTypeNames, r)
+}
+
+func (r *RainfallType) UnmarshalText(text []byte) error {
+	return unmarshalEnum(rainfallTypeNames, text, r)
+}
+
+func (f ForestType) String() string {
+	return enumName(forestTypeNames, f)
+}
+
+func (f ForestType) MarshalText() ([]byte, error) {
+	return marshalEnum(forestTypeNames, f)
+}
+
+func (f *ForestType) UnmarshalText(text []byte) error {
+	return unmarshalEnum(forestTypeNames, text, f)
+}
+
+func (t TreeSpecies) String() string {
+	return enumName(treeSpeciesNames, t)
+}
+
+func (t TreeSpecies) MarshalText() ([]byte, error) {
+	return marshalEnum(treeSpeciesNames, t)
+}
+
+func (t *TreeSpecies) UnmarshalText(text []byte) error {
+	return unmarshalEnum(treeSpeciesNames, text, t)
+}
+
+func (e EcologicalZone) String() string {
+	return enumName(ecologicalZoneNames, e)
+}
+
+func (e EcologicalZone) MarshalText() ([]byte, error) {
+	return marshalEnum(ecologicalZoneNames, e)
+}
+
+func (e *EcologicalZone) UnmarshalText(text []byte) error {
+	return unmarshalEnum(ecologicalZoneNames, text, e)
+}
+
+// Forest type (climate domain) of the ecological zone
+func (e EcologicalZone) ForestType() ForestType {
+	switch {
+	case e <= EcologicalZoneSubtropicalMountainSystems:
+		return ForestTypeTropicalSubtropical
+	case e <= EcologicalZoneTemperateMountainSystems:
+		return ForestTypeTemperate
+	default:
+		return ForestTypeBoreal
+	}
+}
+
+func enumName[T ~uint8](names map[T]string, value T) string {
+	name, ok := names[value]
+	if !ok {
+		return fmt.Sprintf("%d", value)
+	}
+	return name
+}
+
+func marshalEnum[T ~uint8](names map[T]string, value T) ([]byte, error) {
+	name, ok := names[value]
+	if !ok {
+		return nil, fmt.Errorf("Unknown %T value %d.", value, value)
+	}
+	return []byte(name), nil
+}
+
+func unmarshalEnum[T ~uint8](names map[T]string, text []byte, value *T) error {
+	for key, name := range names {
+		if name == string(text) {
+			*value = key
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown %T name %q.", *value, text)
+}
+
+// Default values used for the forest types / tree species missing in the
+// tables below
+const (
+	DensityOverBarkOfTreesDefault = 0.55
+	BiomassExpansionFactorDefault = 1.15
+	RootShootRatioDefault         = 0.25
 )
 
 var DensityOverBarkOfTreesRainfall map[ForestType]map[RainfallType]float64 = map[ForestType]map[RainfallType]float64{
@@ -57,7 +230,7 @@ var DensityOverBarkOfTreesDict map[ForestType]map[TreeSpecies]float64 = map[Fore
 }
 
 func DensityOverBarkOfTrees(forestType ForestType, specie TreeSpecies, rainfall RainfallType) decimal.Decimal {
-	baseValue := decimal.NewFromFloat(0.55)
+	baseValue := decimal.NewFromFloat(DensityOverBarkOfTreesDefault)
 	if forestType == ForestTypeTropicalSubtropical {
 		value, ok := DensityOverBarkOfTreesRainfall[ForestTypeTropicalSubtropical][rainfall]
 		if !ok {
@@ -92,64 +265,51 @@ var BiomassExpansionFactorDict map[ForestType]map[TreeSpecies]float64 = map[Fore
 func BiomassExpansionFactor(forestType ForestType, specie TreeSpecies) decimal.Decimal {
 	value, ok := BiomassExpansionFactorDict[forestType][specie]
 	if !ok {
-		return decimal.NewFromFloat(1.15)
+		return decimal.NewFromFloat(BiomassExpansionFactorDefault)
 	}
 	return decimal.NewFromFloat(value)
 }
 
-var RootShootRatioForTreeRainfall map[ForestType]map[RainfallType]func(v float64) float64 = map[ForestType]map[RainfallType]func(v float64) float64{
+// Root-shoot ratio classes of IPCC 2006 Table 4.4, the first class is the
+// default value
+var (
+	rootShootTropicalDry   = RootShootClasses{{MaxBiomass: maxBiomass(20), Ratio: 0.56}, {Ratio: 0.28}}
+	rootShootTropicalMoist = RootShootClasses{{MaxBiomass: maxBiomass(125), Ratio: 0.2}, {Ratio: 0.24}}
+	rootShootTropicalWet   = RootShootClasses{{Ratio: 0.37}}
+	rootShootConiferous    = RootShootClasses{{MaxBiomass: maxBiomass(50), Ratio: 0.4}, {MaxBiomass: maxBiomass(150), Ratio: 0.29}, {Ratio: 0.2}}
+	// TODO: ask if this value correct @TM, because else previes one is bigger then this one
+	rootShootBroadleaf = RootShootClasses{{MaxBiomass: maxBiomass(75), Ratio: 0.46}, {MaxBiomass: maxBiomass(150), Ratio: 0.23}, {Ratio: 0.24}}
+	rootShootBoreal    = RootShootClasses{{MaxBiomass: maxBiomass(75), Ratio: 0.39}, {Ratio: 0.24}}
+)
+
+var rootShootRainfallClasses = map[ForestType]map[RainfallType]RootShootClasses{
 	ForestTypeTropicalSubtropical: {
-		RainfallTypeDry: func(v float64) float64 {
-			if v <= 20 {
-				// default value
-				return 0.56
-			} else {
-				return 0.28
-			}
-		},
-		RainfallTypeMoist: func(v float64) float64 {
-			if v <= 125 {
-				// default value
-				return 0.2
-			} else {
-				return 0.24
-			}
-		},
-		RainfallTypeWet: func(v float64) float64 {
-			return 0.37
-		},
+		RainfallTypeDry:   rootShootTropicalDry,
+		RainfallTypeMoist: rootShootTropicalMoist,
+		RainfallTypeWet:   rootShootTropicalWet,
 	},
 }
 
-var RootShootRatioForTreeDict map[ForestType]map[TreeSpecies]func(v float64) float64 = map[ForestType]map[TreeSpecies]func(v float64) float64{
+var rootShootSpeciesClasses = map[ForestType]map[TreeSpecies]RootShootClasses{
 	ForestTypeTemperate: {
-		TreeSpeciesConiferous: func(v float64) float64 {
-			if v <= 50 {
-				// default value
-				return 0.4
-			} else if v > 50 && v <= 150 {
-				return 0.29
-			} else {
-				return 0.2
-			}
-		},
-		TreeSpeciesBroadleaf: func(v float64) float64 {
-			if v <= 75 {
-				// default value
-				return 0.46
-			} else if v > 75 && v <= 150 {
-				return 0.23
-			} else {
-				// TODO: ask if this value correct @TM, because else previes one is bigger then this one
-				return 0.24
-			}
-		},
+		TreeSpeciesConiferous: rootShootConiferous,
+		TreeSpeciesBroadleaf:  rootShootBroadleaf,
 	},
 }
 
+var RootShootRatioForTreeRainfall map[ForestType]map[RainfallType]func(v float64) float64 = rootShootFuncs(rootShootRainfallClasses)
+
+var RootShootRatioForTreeDict map[ForestType]map[TreeSpecies]func(v float64) float64 = rootShootFuncs(rootShootSpeciesClasses)
+
+func rootShootFuncs[K comparable](classes map[ForestType]map[K]RootShootClasses) map[ForestType]map[K]func(v float64) float64 {
+	return mapValues(classes, func(c RootShootClasses) func(v float64) float64 {
+		return c.value
+	})
+}
+
 // abovegroundBiomass - 0 if you want to get default value
 func RootShootRatioForTree(forestType ForestType, species TreeSpecies, rainfall RainfallType, abovegroundBiomass float64) decimal.Decimal {
-	baseValue := decimal.NewFromFloat(0.25)
+	baseValue := decimal.NewFromFloat(RootShootRatioDefault)
 	if forestType == ForestTypeTropicalSubtropical {
 		calc, ok := RootShootRatioForTreeRainfall[ForestTypeTropicalSubtropical][rainfall]
 		if !ok {
@@ -158,12 +318,7 @@ func RootShootRatioForTree(forestType ForestType, species TreeSpecies, rainfall
 		return decimal.NewFromFloat(calc(abovegroundBiomass))
 	}
 	if forestType == ForestTypeBoreal {
-		if abovegroundBiomass <= 75 {
-			// default value
-			return decimal.NewFromFloat(0.39)
-		} else {
-			return decimal.NewFromFloat(0.24)
-		}
+		return decimal.NewFromFloat(rootShootBoreal.value(abovegroundBiomass))
 	}
 	calc, ok := RootShootRatioForTreeDict[forestType][species]
 	if !ok {
@@ -172,6 +327,13 @@ func RootShootRatioForTree(forestType ForestType, species TreeSpecies, rainfall
 	return decimal.NewFromFloat(calc(abovegroundBiomass))
 }
 
+// Root-shoot ratio for tree depending on the ecological zone, see
+// ParameterSet.RootShootRatioForZone
+// abovegroundBiomass - 0 if you want to get default value
+func RootShootRatioForEcologicalZone(zone EcologicalZone, species TreeSpecies, abovegroundBiomass float64) decimal.Decimal {
+	return defaultParameterSet.RootShootRatioForZone(zone, species, abovegroundBiomass)
+}
+
 func GetRainfallType(rainfallAmount int64) RainfallType {
 	if rainfallAmount >= 2000 {
 		return RainfallTypeWet
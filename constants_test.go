@@ -26,3 +26,101 @@ func TestGetRainfallType(t *testing.T) {
 		}
 	}
 }
+
+func TestForestTypeText(t *testing.T) {
+	type Test struct {
+		value ForestType
+		text  string
+	}
+	tests := []Test{
+		{ForestTypeTropicalSubtropical, "tropical-subtropical"},
+		{ForestTypeTemperate, "temperate"},
+		{ForestTypeBoreal, "boreal"},
+	}
+	for i, tt := range tests {
+		text, err := tt.value.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(text) != tt.text {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, tt.text, text)
+		}
+		var value ForestType
+		if err := value.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if value != tt.value {
+			t.Fatalf("Test number %d, expect: %d, have: %d", i, tt.value, value)
+		}
+	}
+	var value TreeSpecies
+	if err := value.UnmarshalText([]byte("teak")); err == nil {
+		t.Fatalf("Unknown name should return error")
+	}
+	if _, err := RainfallType(42).MarshalText(); err == nil {
+		t.Fatalf("Unknown value should return error")
+	}
+}
+
+func TestRootShootRatioForEcologicalZone(t *testing.T) {
+	type Test struct {
+		zone    EcologicalZone
+		species TreeSpecies
+		biomass float64
+		result  float64
+	}
+	tests := []Test{
+		{EcologicalZoneTropicalRainForest, TreeSpeciesBroadleaf, 0, 0.37},
+		{EcologicalZoneTropicalMoistDeciduousForest, TreeSpeciesBroadleaf, 125, 0.2},
+		{EcologicalZoneTropicalMoistDeciduousForest, TreeSpeciesBroadleaf, 126, 0.24},
+		{EcologicalZoneTropicalDryForest, TreeSpeciesBroadleaf, 20, 0.56},
+		{EcologicalZoneTropicalDryForest, TreeSpeciesBroadleaf, 21, 0.28},
+		{EcologicalZoneTropicalShrubland, TreeSpeciesBroadleaf, 0, 0.4},
+		{EcologicalZoneTropicalMountainSystems, TreeSpeciesPines, 300, 0.27},
+		{EcologicalZoneSubtropicalHumidForest, TreeSpeciesBroadleaf, 200, 0.24},
+		{EcologicalZoneSubtropicalDryForest, TreeSpeciesBroadleaf, 0, 0.56},
+		{EcologicalZoneSubtropicalSteppe, TreeSpeciesBroadleaf, 0, 0.32},
+		{EcologicalZoneSubtropicalMountainSystems, TreeSpeciesBroadleaf, 0, 0.27},
+		{EcologicalZoneTemperateOceanicForest, TreeSpeciesConiferous, 50, 0.4},
+		{EcologicalZoneTemperateOceanicForest, TreeSpeciesConiferous, 100, 0.29},
+		{EcologicalZoneTemperateContinentalForest, TreeSpeciesPines, 151, 0.2},
+		{EcologicalZoneTemperateContinentalForest, TreeSpeciesOak, 71, 0.3},
+		{EcologicalZoneTemperateMountainSystems, TreeSpeciesEucalyptus, 0, 0.44},
+		{EcologicalZoneTemperateMountainSystems, TreeSpeciesEucalyptus, 100, 0.28},
+		{EcologicalZoneTemperateOceanicForest, TreeSpeciesBroadleaf, 100, 0.23},
+		{EcologicalZoneTemperateOceanicForest, TreeSpeciesMixedConiferousAndBroadleaf, 0, 0.46},
+		{EcologicalZoneBorealConiferousForest, TreeSpeciesConiferous, 75, 0.39},
+		{EcologicalZoneBorealTundraWoodland, TreeSpeciesForestTundra, 76, 0.24},
+		{EcologicalZoneBorealMountainSystems, TreeSpeciesBroadleaf, 0, 0.39},
+	}
+	for i, tt := range tests {
+		result := RootShootRatioForEcologicalZone(tt.zone, tt.species, tt.biomass)
+		if result.InexactFloat64() != tt.result {
+			t.Fatalf("Test number %d, expect: %f, have: %f", i, tt.result, result.InexactFloat64())
+		}
+	}
+	for zone := range ecologicalZoneNames {
+		if _, ok := DefaultParameterSet().RootShoot.ByEcologicalZone[zone]; !ok {
+			t.Fatalf("Ecological zone %s has no root-shoot ratio", zone)
+		}
+	}
+}
+
+func TestEcologicalZoneForestType(t *testing.T) {
+	type Test struct {
+		zone   EcologicalZone
+		result ForestType
+	}
+	tests := []Test{
+		{EcologicalZoneTropicalRainForest, ForestTypeTropicalSubtropical},
+		{EcologicalZoneSubtropicalMountainSystems, ForestTypeTropicalSubtropical},
+		{EcologicalZoneTemperateOceanicForest, ForestTypeTemperate},
+		{EcologicalZoneTemperateMountainSystems, ForestTypeTemperate},
+		{EcologicalZoneBorealConiferousForest, ForestTypeBoreal},
+	}
+	for i, tt := range tests {
+		if result := tt.zone.ForestType(); result != tt.result {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, tt.result, result)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package carbon_calc
+
+import (
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// Way the dead wood or litter pool of the monitoring zone is estimated
+type PoolMode uint8
+
+const (
+	// Pool is not accounted
+	PoolExcluded PoolMode = iota
+	// Pool is estimated as a fraction of the tree carbon, see DeadWoodFactor
+	// and LitterFactor
+	PoolDefaultFactor
+	// Pool is estimated from the measurements in the sample plots
+	PoolMeasured
+)
+
+var poolModeNames = map[PoolMode]string{
+	PoolExcluded:      "excluded",
+	PoolDefaultFactor: "default",
+	PoolMeasured:      "measured",
+}
+
+func (p PoolMode) String() string {
+	return enumName(poolModeNames, p)
+}
+
+func (p PoolMode) MarshalText() ([]byte, error) {
+	return marshalEnum(poolModeNames, p)
+}
+
+func (p *PoolMode) UnmarshalText(text []byte) error {
+	return unmarshalEnum(poolModeNames, text, p)
+}
+
+// Decay class of the dead wood, reduces the density of the sound wood
+type DecayClass uint8
+
+const (
+	DecaySound DecayClass = iota
+	DecayIntermediate
+	DecayRotten
+)
+
+var decayClassNames = map[DecayClass]string{
+	DecaySound:        "sound",
+	DecayIntermediate: "intermediate",
+	DecayRotten:       "rotten",
+}
+
+func (d DecayClass) String() string {
+	return enumName(decayClassNames, d)
+}
+
+func (d DecayClass) MarshalText() ([]byte, error) {
+	return marshalEnum(decayClassNames, d)
+}
+
+func (d *DecayClass) UnmarshalText(text []byte) error {
+	return unmarshalEnum(decayClassNames, text, d)
+}
+
+// Density reduction factors of CDM AR-TOOL12 for the decay classes
+var DecayDensityReduction map[DecayClass]float64 = map[DecayClass]float64{
+	DecaySound:        1,
+	DecayIntermediate: 0.8,
+	DecayRotten:       0.45,
+}
+
+// Default dead wood and litter factors of CDM AR-TOOL12 (fraction of the tree
+// carbon) for the elevation below 2000 m, values by rainfall are used for the
+// forest types present in rainfall maps
+var DeadWoodFactorRainfall map[ForestType]map[RainfallType]float64 = map[ForestType]map[RainfallType]float64{
+	ForestTypeTropicalSubtropical: {
+		RainfallTypeDry:   0.02,
+		RainfallTypeMoist: 0.01,
+		RainfallTypeWet:   0.01,
+	},
+}
+
+var DeadWoodFactorDict map[ForestType]float64 = map[ForestType]float64{
+	ForestTypeTemperate: 0.08,
+	ForestTypeBoreal:    0.08,
+}
+
+var LitterFactorRainfall map[ForestType]map[RainfallType]float64 = map[ForestType]map[RainfallType]float64{
+	ForestTypeTropicalSubtropical: {
+		RainfallTypeDry:   0.04,
+		RainfallTypeMoist: 0.01,
+		RainfallTypeWet:   0.01,
+	},
+}
+
+var LitterFactorDict map[ForestType]float64 = map[ForestType]float64{
+	ForestTypeTemperate: 0.04,
+	ForestTypeBoreal:    0.04,
+}
+
+func poolFactor(byRainfall map[ForestType]map[RainfallType]float64, dict map[ForestType]float64, forestType ForestType, rainfall RainfallType) decimal.Decimal {
+	if values, ok := byRainfall[forestType]; ok {
+		return decimal.NewFromFloat(values[rainfall])
+	}
+	return decimal.NewFromFloat(dict[forestType])
+}
+
+// Ratio of the carbon stock in dead wood to the carbon stock in trees
+// depending on forest type / rainfall
+func DeadWoodFactor(forestType ForestType, rainfall RainfallType) decimal.Decimal {
+	return poolFactor(DeadWoodFactorRainfall, DeadWoodFactorDict, forestType, rainfall)
+}
+
+// Ratio of the carbon stock in litter to the carbon stock in trees depending on
+// forest type / rainfall
+func LitterFactor(forestType ForestType, rainfall RainfallType) decimal.Decimal {
+	return poolFactor(LitterFactorRainfall, LitterFactorDict, forestType, rainfall)
+}
+
+// Calculate the carbon stock in the pool estimated as a fraction of the carbon
+// stock in trees
+// treeCarbon - carbon stock in trees (t CO2-e or t CO2-e/ha)
+// factor - see DeadWoodFactor and LitterFactor
+func PoolCarbonByFactor(treeCarbon, factor decimal.Decimal) decimal.Decimal {
+	return poolCarbonByFactor(nil, "PoolCarbonByFactor", treeCarbon, factor)
+}
+
+func poolCarbonByFactor(tr *Trace, formula string, treeCarbon, factor decimal.Decimal) decimal.Decimal {
+	tr = tr.Step(formula).
+		Input("treeCarbon", treeCarbon).
+		Input("factor", factor)
+	return tr.Result(treeCarbon.Mul(factor))
+}
+
+// Piece of the lying dead wood crossing the transect line
+// diameter - diameter of the piece at the intersection (cm)
+type DeadWoodPiece struct {
+	Diameter decimal.Decimal `json:"diameter"`
+	Decay    DecayClass      `json:"decay"`
+}
+
+// Line-intersect sampling of the lying dead wood in the sample plot
+// length - total length of the transect lines (m)
+// density - basic density of the sound dead wood (t/m3), 0 if you want to get
+// the density of the trees of the monitoring zone
+type LineIntersect struct {
+	Length  decimal.Decimal `json:"length"`
+	Density decimal.Decimal `json:"density"`
+	Pieces  []DeadWoodPiece `json:"pieces"`
+}
+
+// Calculate the volume of the lying dead wood per ha (m3/ha)
+// length - total length of the transect lines (m)
+// diameters - diameters of the pieces at the intersection (cm)
+func VolumeLyingDeadWood(length decimal.Decimal, diameters []decimal.Decimal) decimal.Decimal {
+	sum := decimal.Zero
+	for _, diameter := range diameters {
+		sum = sum.Add(diameter.Pow(decimal.New(2, 0)))
+	}
+	return decimal.NewFromFloat(math.Pi * math.Pi).
+		Mul(sum).
+		Div(decimal.New(8, 0).Mul(length))
+}
+
+// Calculate the carbon stock in the lying dead wood per ha (t CO2-e/ha)
+// line - transect of the sample plot
+// density - basic density of the sound dead wood (t/m3)
+// fraction - carbon fraction of dead wood, 0 if you want to get default value
+// 0.5
+func CarbonLyingDeadWood(line LineIntersect, density, fraction decimal.Decimal) decimal.Decimal {
+	return carbonLyingDeadWood(nil, line, density, fraction)
+}
+
+func carbonLyingDeadWood(tr *Trace, line LineIntersect, density, fraction decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("CarbonLyingDeadWood").
+		Input("length", line.Length).
+		Input("density", density)
+	fraction = tr.inputOrDefault("fraction", fraction, decimal.NewFromFloat(0.5))
+	biomass := decimal.Zero
+	byDecay := map[DecayClass][]decimal.Decimal{}
+	for _, piece := range line.Pieces {
+		byDecay[piece.Decay] = append(byDecay[piece.Decay], piece.Diameter)
+	}
+	for decay := DecaySound; decay <= DecayRotten; decay++ {
+		diameters, ok := byDecay[decay]
+		if !ok {
+			continue
+		}
+		volume := tr.Step("VolumeLyingDeadWood").
+			Of(decay.String()).
+			Result(VolumeLyingDeadWood(line.Length, diameters))
+		biomass = biomass.Add(volume.Mul(density).Mul(decimal.NewFromFloat(DecayDensityReduction[decay])))
+	}
+	return tr.Result(decimal.NewFromFloat(44.0 / 12.0).
+		Mul(fraction).
+		Mul(biomass))
+}
+
+// Standing dead tree measured in the sample plot, its carbon is the carbon of
+// the living tree multiplied by the density reduction of the decay class
+type StandingDeadTree struct {
+	Tree
+	Decay DecayClass `json:"decay"`
+}
+
+// Litter collected in the sampling frame of the sample plot
+// area - area of the sampling frame (m2)
+// dryMass - oven-dry mass of the litter (kg)
+type LitterSample struct {
+	Area    decimal.Decimal `json:"area"`
+	DryMass decimal.Decimal `json:"dryMass"`
+}
+
+// Calculate the carbon stock in litter per ha (t CO2-e/ha) from the mean dry
+// mass of the sampling frames
+// samples - sampling frames of the sample plot
+// fraction - carbon fraction of litter, 0 if you want to get default value
+// 0.37
+func CarbonLitter(samples []LitterSample, fraction decimal.Decimal) decimal.Decimal {
+	return carbonLitter(nil, samples, fraction)
+}
+
+func carbonLitter(tr *Trace, samples []LitterSample, fraction decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("CarbonLitter")
+	fraction = tr.inputOrDefault("fraction", fraction, decimal.NewFromFloat(0.37))
+	if len(samples) == 0 {
+		return tr.Result(decimal.Zero)
+	}
+	sum := decimal.Zero
+	for _, sample := range samples {
+		tr.Input("dryMass", sample.DryMass)
+		sum = sum.Add(sample.DryMass.Div(sample.Area))
+	}
+	// kg/m2 to t/ha
+	mass := sum.Div(decimal.NewFromInt(int64(len(samples)))).Mul(decimal.New(10, 0))
+	return tr.Result(decimal.NewFromFloat(44.0 / 12.0).
+		Mul(fraction).
+		Mul(mass))
+}
+
+// Carbon stock in the dead wood and litter of the sample plot (t CO2-e/ha)
+// treeCarbon - carbon stock in trees of the sample plot (t CO2-e/ha)
+func (s Stage) plotPools(tr *Trace, ps *ParameterSet, z MonitoringZone, plot Plot, treeCarbon decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	deadWood := decimal.Zero
+	switch z.DeadWood {
+	case PoolDefaultFactor:
+		deadWood = poolCarbonByFactor(tr, "DeadWoodByFactor", treeCarbon, DeadWoodFactor(z.ForestType, z.Rainfall))
+	case PoolMeasured:
+		step := tr.Step("DeadWood")
+		standing := decimal.Zero
+		for _, tree := range plot.StandingDead {
+			treeTrace := step.Step("StandingDeadTree").Of(tree.ID)
+			carbon, _, err := s.treeCarbon(treeTrace, ps, z, tree.Tree)
+			if err == NotEnoughHeight {
+				treeTrace.Result(decimal.Zero)
+				continue
+			}
+			if err != nil {
+				return decimal.Zero, decimal.Zero, err
+			}
+			standing = standing.Add(treeTrace.Result(carbon.Mul(decimal.NewFromFloat(DecayDensityReduction[tree.Decay]))))
+		}
+		var err error
+		if deadWood, err = validateCarbonStoredInPlot(step, standing, plot.Area); err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+		if plot.LyingDeadWood != nil {
+			density := plot.LyingDeadWood.Density
+			if density.IsZero() {
+				density = step.Step("DensityOverBarkOfTrees").
+					Result(ps.DensityOverBarkOfTrees(z.ForestType, z.Species, z.Rainfall))
+			}
+			deadWood = deadWood.Add(carbonLyingDeadWood(step, *plot.LyingDeadWood, density, decimal.Zero))
+		}
+		step.Result(deadWood)
+	}
+	litter := decimal.Zero
+	switch z.Litter {
+	case PoolDefaultFactor:
+		litter = poolCarbonByFactor(tr, "LitterByFactor", treeCarbon, LitterFactor(z.ForestType, z.Rainfall))
+	case PoolMeasured:
+		litter = carbonLitter(tr, plot.Litter, decimal.Zero)
+	}
+	return deadWood, litter, nil
+}
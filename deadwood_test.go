@@ -0,0 +1,139 @@
+package carbon_calc
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPoolFactor(t *testing.T) {
+	type Test struct {
+		forestType       ForestType
+		rainfall         RainfallType
+		deadWood, litter float64
+	}
+	tests := []Test{
+		{ForestTypeTropicalSubtropical, RainfallTypeDry, 0.02, 0.04},
+		{ForestTypeTropicalSubtropical, RainfallTypeWet, 0.01, 0.01},
+		{ForestTypeTemperate, RainfallTypeDry, 0.08, 0.04},
+		{ForestTypeBoreal, RainfallTypeMoist, 0.08, 0.04},
+	}
+	for i, tt := range tests {
+		deadWood := DeadWoodFactor(tt.forestType, tt.rainfall)
+		litter := LitterFactor(tt.forestType, tt.rainfall)
+		if deadWood.InexactFloat64() != tt.deadWood || litter.InexactFloat64() != tt.litter {
+			t.Fatalf("Test number %d, expect: %f, %f, have: %s, %s", i, tt.deadWood, tt.litter, deadWood, litter)
+		}
+	}
+	if result := PoolCarbonByFactor(decimal.New(50, 0), decimal.NewFromFloat(0.08)); !result.Equal(decimal.New(4, 0)) {
+		t.Fatalf("expect: 4, have: %s", result)
+	}
+}
+
+func TestCarbonLyingDeadWood(t *testing.T) {
+	line := LineIntersect{
+		Length: decimal.New(100, 0),
+		Pieces: []DeadWoodPiece{
+			{Diameter: decimal.New(20, 0)},
+			{Diameter: decimal.New(10, 0), Decay: DecayRotten},
+		},
+	}
+	volume := VolumeLyingDeadWood(line.Length, []decimal.Decimal{decimal.New(20, 0), decimal.New(10, 0)})
+	// pi^2 * (400 + 100) / 800
+	if math.Abs(volume.InexactFloat64()-math.Pi*math.Pi*500/800) > 1e-9 {
+		t.Fatalf("expect: %f, have: %s", math.Pi*math.Pi*500/800, volume)
+	}
+	result := CarbonLyingDeadWood(line, decimal.NewFromFloat(0.5), decimal.Zero)
+	// 44/12 * 0.5 * 0.5 * pi^2 / 800 * (400 + 0.45 * 100)
+	expect := 44.0 / 12 * 0.5 * 0.5 * math.Pi * math.Pi / 800 * (400 + 0.45*100)
+	if math.Abs(result.InexactFloat64()-expect) > 1e-9 {
+		t.Fatalf("expect: %f, have: %s", expect, result)
+	}
+}
+
+func TestCarbonLitter(t *testing.T) {
+	samples := []LitterSample{
+		{Area: decimal.NewFromFloat(0.25), DryMass: decimal.NewFromFloat(0.1)},
+		{Area: decimal.NewFromFloat(0.25), DryMass: decimal.NewFromFloat(0.2)},
+	}
+	// 0.6 kg/m2 = 6 t/ha
+	result := CarbonLitter(samples, decimal.Zero)
+	expect := 44.0 / 12 * 0.37 * 6
+	if math.Abs(result.InexactFloat64()-expect) > 1e-9 {
+		t.Fatalf("expect: %f, have: %s", expect, result)
+	}
+	if result := CarbonLitter(nil, decimal.Zero); !result.IsZero() {
+		t.Fatalf("expect: 0, have: %s", result)
+	}
+}
+
+func TestStagePools(t *testing.T) {
+	trees, err := testStage().Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stage := testStage()
+	stage.Zones[0].DeadWood = PoolDefaultFactor
+	stage.Zones[0].Litter = PoolDefaultFactor
+	factor, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tropical dry zone: 2% dead wood and 4% litter
+	zone := trees.Zones[0].Carbon
+	if !factor.Zones[0].DeadWood.Round(9).Equal(zone.Mul(decimal.NewFromFloat(0.02)).Round(9)) ||
+		!factor.Zones[0].Litter.Round(9).Equal(zone.Mul(decimal.NewFromFloat(0.04)).Round(9)) {
+		t.Fatalf("Wrong pools: %s, %s", factor.Zones[0].DeadWood, factor.Zones[0].Litter)
+	}
+	total := trees.TotalCarbon.Add(factor.DeadWood).Add(factor.Litter)
+	if !factor.TotalCarbon.Round(9).Equal(total.Round(9)) {
+		t.Fatalf("expect: %s, have: %s", total, factor.TotalCarbon)
+	}
+	if !factor.Zones[1].DeadWood.IsZero() || !factor.Zones[1].AbovegroundBiomass.GreaterThan(decimal.Zero) {
+		t.Fatalf("Zone without pools: %v", factor.Zones[1])
+	}
+
+	stage = testStage()
+	stage.Zones[1].DeadWood = PoolMeasured
+	stage.Zones[1].Litter = PoolMeasured
+	plot := &stage.Zones[1].Plots[0]
+	plot.StandingDead = []StandingDeadTree{{Tree: testTree("dead-1", 0.05, 5), Decay: DecayIntermediate}}
+	plot.LyingDeadWood = &LineIntersect{Length: decimal.New(100, 0), Pieces: []DeadWoodPiece{{Diameter: decimal.New(20, 0)}}}
+	plot.Litter = []LitterSample{{Area: decimal.NewFromFloat(0.25), DryMass: decimal.NewFromFloat(0.1)}}
+	measured, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := CarbonPerTree(decimal.NewFromFloat(0.47), decimal.NewFromFloat(0.05), decimal.New(5, 0), decimal.NewFromFloat(0.25),
+		decimal.NewFromFloat(0.55), decimal.NewFromFloat(1.15), decimal.NewFromFloat(0.3)).
+		Mul(decimal.NewFromFloat(0.8)).Div(plot.Area)
+	lying := CarbonLyingDeadWood(*plot.LyingDeadWood, DensityOverBarkOfTrees(ForestTypeTropicalSubtropical, TreeSpeciesPines, RainfallTypeDry), decimal.Zero)
+	plotResult := measured.Zones[1].Plots[0]
+	if !plotResult.DeadWood.Round(9).Equal(dead.Add(lying).Round(9)) {
+		t.Fatalf("expect: %s, have: %s", dead.Add(lying), plotResult.DeadWood)
+	}
+	if !plotResult.Litter.Equal(CarbonLitter(plot.Litter, decimal.Zero)) || !measured.Zones[1].Plots[1].Litter.IsZero() {
+		t.Fatalf("Wrong litter: %s, %s", plotResult.Litter, measured.Zones[1].Plots[1].Litter)
+	}
+
+	plot.LyingDeadWood.Length = decimal.Zero
+	plot.Litter[0].Area = decimal.Zero
+	stage.Zones[0].Litter = PoolMode(7)
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expect 3 ValidationErrors, have: %v", err)
+	}
+
+	// Standing dead wood of the plot without area is not a silent zero
+	stage = testStage()
+	stage.Zones[1].DeadWood = PoolMeasured
+	plot = &stage.Zones[1].Plots[0]
+	plot.StandingDead = []StandingDeadTree{{Tree: testTree("dead-1", 0.05, 5), Decay: DecayIntermediate}}
+	plot.Area = decimal.Zero
+	if _, _, err := stage.plotPools(nil, stage.parameters(), stage.Zones[1], *plot, decimal.Zero); err != ErrZeroPlotArea {
+		t.Fatalf("expect: %v, have: %v", ErrZeroPlotArea, err)
+	}
+}
@@ -15,114 +15,240 @@ import (
 // validated stage (years) - take into account the end months and years of each
 // stage
 func BaselineInMonitoringZone(manual, area, deltaTime decimal.Decimal) decimal.Decimal {
-	return manual.Mul(area).Mul(deltaTime)
+	return baselineInMonitoringZone(nil, manual, area, deltaTime)
+}
+
+func baselineInMonitoringZone(tr *Trace, manual, area, deltaTime decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("BaselineInMonitoringZone").
+		Input("manual", manual).
+		Input("area", area).
+		Input("deltaTime", deltaTime)
+	return tr.Result(manual.Mul(area).Mul(deltaTime))
 }
 
 // Calculate the baseline
 func Baseline(baselines []decimal.Decimal) decimal.Decimal {
-	return SumDecimal(baselines)
+	return baseline(nil, baselines)
+}
+
+func baseline(tr *Trace, baselines []decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("Baseline")
+	for _, value := range baselines {
+		tr.Input("baseline", value)
+	}
+	return tr.Result(SumDecimal(baselines))
 }
 
 // Net GHG emissions from nitrogen fertilizer in the project in year
 func NetGHGEmissions(fertilizes, cO2eNdirectt, cO2eNindirectt decimal.Decimal) decimal.Decimal {
-	return fertilizes.Mul(cO2eNdirectt.Add(cO2eNindirectt))
+	return netGHGEmissions(nil, fertilizes, cO2eNdirectt, cO2eNindirectt)
+}
+
+func netGHGEmissions(tr *Trace, fertilizes, cO2eNdirectt, cO2eNindirectt decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("NetGHGEmissions").
+		Input("fertilizes", fertilizes).
+		Input("cO2eNdirectt", cO2eNdirectt).
+		Input("cO2eNindirectt", cO2eNindirectt)
+	return tr.Result(fertilizes.Mul(cO2eNdirectt.Add(cO2eNindirectt)))
+}
+
+// Inputs of the nitrogen fertilizer shared by CO2eNdirecttOptions,
+// NfertVolatITOptions and NfertLeachITOptions
+// nContSynthFertz, nContOrgFertz - nil if you want to get 0.1 of the mass
+// gWarmingPotentl - nil if you want to get the N2O value of the GWP set
+type FertilizerInputs struct {
+	MassSynthFertz  decimal.Decimal  `json:"massSynthFertz"`
+	NContSynthFertz *decimal.Decimal `json:"nContSynthFertz"`
+	MassOrgFertz    decimal.Decimal  `json:"massOrgFertz"`
+	NContOrgFertz   *decimal.Decimal `json:"nContOrgFertz"`
+	GWarmingPotentl *decimal.Decimal `json:"gWarmingPotentl"`
+	GWP             GWPSet           `json:"gwp"`
+}
+
+func newFertilizerInputs(massSynthFertz, nContSynthFertz, massOrgFertz, nContOrgFertz, gWarmingPotentl decimal.Decimal) FertilizerInputs {
+	return FertilizerInputs{
+		MassSynthFertz:  massSynthFertz,
+		NContSynthFertz: zeroDefault(nContSynthFertz),
+		MassOrgFertz:    massOrgFertz,
+		NContOrgFertz:   zeroDefault(nContOrgFertz),
+		GWarmingPotentl: zeroDefault(gWarmingPotentl),
+	}
+}
+
+// N applied in the synthetic and organic fertilizer
+func (f FertilizerInputs) nitrogen(tr *Trace) (decimal.Decimal, decimal.Decimal) {
+	tr.Input("massSynthFertz", f.MassSynthFertz)
+	synth := f.MassSynthFertz.Mul(tr.optional("nContSynthFertz", f.NContSynthFertz, f.MassSynthFertz.Mul(decimal.NewFromFloat(0.1))))
+	tr.Input("massOrgFertz", f.MassOrgFertz)
+	org := f.MassOrgFertz.Mul(tr.optional("nContOrgFertz", f.NContOrgFertz, f.MassOrgFertz.Mul(decimal.NewFromFloat(0.1))))
+	return synth, org
+}
+
+func (f FertilizerInputs) gWarmingPotentl(tr *Trace) decimal.Decimal {
+	return tr.optional("gWarmingPotentl", f.GWarmingPotentl, f.GWP.N2O())
 }
 
 // TODO:
 func CO2eNdirectt(massSynthFertz, nContSynthFertz, massOrgFertz, nContOrgFertz, nitrOxdEmissSOC, gWarmingPotentl decimal.Decimal) decimal.Decimal {
-	if nContSynthFertz.Equal(decimal.Zero) {
-		nContSynthFertz = massSynthFertz.Mul(decimal.NewFromFloat(0.1))
-	}
-	if nContOrgFertz.Equal(decimal.Zero) {
-		nContOrgFertz = massOrgFertz.Mul(decimal.NewFromFloat(0.1))
-	}
-	if nitrOxdEmissSOC.Equal(decimal.Zero) {
-		nitrOxdEmissSOC = decimal.NewFromFloat(0.01)
-	}
-	if gWarmingPotentl.Equal(decimal.Zero) {
-		gWarmingPotentl = decimal.New(265, 0)
-	}
+	return cO2eNdirectt(nil, CO2eNdirecttOptions{
+		FertilizerInputs: newFertilizerInputs(massSynthFertz, nContSynthFertz, massOrgFertz, nContOrgFertz, gWarmingPotentl),
+		NitrOxdEmissSOC:  zeroDefault(nitrOxdEmissSOC),
+	})
+}
+
+// Options of CO2eNdirecttWith, see CO2eNdirectt
+// nitrOxdEmissSOC - nil if you want to get default value 0.01
+type CO2eNdirecttOptions struct {
+	FertilizerInputs
+	NitrOxdEmissSOC *decimal.Decimal `json:"nitrOxdEmissSOC"`
+}
+
+// CO2eNdirectt with zero as a legal value of the options, returns the defaults
+// substituted for the options not provided
+func CO2eNdirecttWith(o CO2eNdirecttOptions) (decimal.Decimal, Defaults) {
+	tr := NewTrace("CO2eNdirecttWith")
+	result := cO2eNdirectt(tr, o)
+	return result, tr.defaults()
+}
+
+func cO2eNdirectt(tr *Trace, o CO2eNdirecttOptions) decimal.Decimal {
+	tr = tr.Step("CO2eNdirectt")
+	synth, org := o.nitrogen(tr)
+	nitrOxdEmissSOC := tr.optional("nitrOxdEmissSOC", o.NitrOxdEmissSOC, decimal.NewFromFloat(0.01))
+	gWarmingPotentl := o.gWarmingPotentl(tr)
 	b := decimal.NewFromFloat(44.0 / 28.0)
-	return massSynthFertz.Mul(nContSynthFertz).
-		Add(massOrgFertz.
-			Mul(nContOrgFertz)).
+	return tr.Result(synth.
+		Add(org).
 		Mul(nitrOxdEmissSOC).
 		Mul(b).
-		Mul(gWarmingPotentl)
+		Mul(gWarmingPotentl))
 }
 
 func CO2eNdirecttDefault(massSynthFertz, massOrgFertz decimal.Decimal) decimal.Decimal {
-	b := decimal.NewFromFloat(1.1 * 0.001 * (44.0 / 28.0) * 265)
+	return CO2eNdirecttDefaultGWP(massSynthFertz, massOrgFertz, GWPAR5)
+}
+
+// CO2eNdirecttDefault with the N2O global warming potential of the set
+func CO2eNdirecttDefaultGWP(massSynthFertz, massOrgFertz decimal.Decimal, gwp GWPSet) decimal.Decimal {
+	b := decimal.NewFromFloat(1.1 * 0.001 * (44.0 / 28.0) * GWPDict[gwp].N2O)
 	return massSynthFertz.Add(massOrgFertz).Mul(b)
 }
 
 func CO2eNindirectt(nfertVolatIT, nfertLeachIT decimal.Decimal) decimal.Decimal {
-	return nfertVolatIT.Add(nfertLeachIT)
+	return cO2eNindirectt(nil, nfertVolatIT, nfertLeachIT)
+}
+
+func cO2eNindirectt(tr *Trace, nfertVolatIT, nfertLeachIT decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("CO2eNindirectt").
+		Input("nfertVolatIT", nfertVolatIT).
+		Input("nfertLeachIT", nfertLeachIT)
+	return tr.Result(nfertVolatIT.Add(nfertLeachIT))
 }
 
 func NfertVolatIT(massSynthFertz, nContSynthFertz, massOrgFertz, nContOrgFertz, allFractSynth, allFractOrg, nitrOxdEmissWS, gWarmingPotentl decimal.Decimal) decimal.Decimal {
-	if nContSynthFertz.Equal(decimal.Zero) {
-		nContSynthFertz = massSynthFertz.Mul(decimal.NewFromFloat(0.1))
-	}
-	if nContOrgFertz.Equal(decimal.Zero) {
-		nContOrgFertz = massOrgFertz.Mul(decimal.NewFromFloat(0.1))
-	}
-	if nitrOxdEmissWS.Equal(decimal.Zero) {
-		nitrOxdEmissWS = decimal.NewFromFloat(0.01)
-	}
-	if gWarmingPotentl.Equal(decimal.Zero) {
-		gWarmingPotentl = decimal.New(265, 0)
-	}
-	if allFractSynth.Equal(decimal.Zero) {
-		allFractSynth = decimal.NewFromFloat(0.1)
-	}
-	if allFractOrg.Equal(decimal.Zero) {
-		allFractOrg = decimal.NewFromFloat(0.3)
-	}
+	return nfertVolatIT(nil, NfertVolatITOptions{
+		FertilizerInputs: newFertilizerInputs(massSynthFertz, nContSynthFertz, massOrgFertz, nContOrgFertz, gWarmingPotentl),
+		AllFractSynth:    zeroDefault(allFractSynth),
+		AllFractOrg:      zeroDefault(allFractOrg),
+		NitrOxdEmissWS:   zeroDefault(nitrOxdEmissWS),
+	})
+}
+
+// Options of NfertVolatITWith, see NfertVolatIT
+// allFractSynth, allFractOrg - nil if you want to get default values 0.1 and
+// 0.3
+// nitrOxdEmissWS - nil if you want to get default value 0.01
+type NfertVolatITOptions struct {
+	FertilizerInputs
+	AllFractSynth  *decimal.Decimal `json:"allFractSynth"`
+	AllFractOrg    *decimal.Decimal `json:"allFractOrg"`
+	NitrOxdEmissWS *decimal.Decimal `json:"nitrOxdEmissWS"`
+}
+
+// NfertVolatIT with zero as a legal value of the options, returns the defaults
+// substituted for the options not provided
+func NfertVolatITWith(o NfertVolatITOptions) (decimal.Decimal, Defaults) {
+	tr := NewTrace("NfertVolatITWith")
+	result := nfertVolatIT(tr, o)
+	return result, tr.defaults()
+}
+
+func nfertVolatIT(tr *Trace, o NfertVolatITOptions) decimal.Decimal {
+	tr = tr.Step("NfertVolatIT")
+	synth, org := o.nitrogen(tr)
+	nitrOxdEmissWS := tr.optional("nitrOxdEmissWS", o.NitrOxdEmissWS, decimal.NewFromFloat(0.01))
+	gWarmingPotentl := o.gWarmingPotentl(tr)
+	allFractSynth := tr.optional("allFractSynth", o.AllFractSynth, decimal.NewFromFloat(0.1))
+	allFractOrg := tr.optional("allFractOrg", o.AllFractOrg, decimal.NewFromFloat(0.3))
 	b := decimal.NewFromFloat(44.0 / 28.0)
-	return massSynthFertz.Mul(nContSynthFertz).
+	return tr.Result(synth.
 		Mul(allFractSynth).
-		Add(massOrgFertz.Mul(nContOrgFertz).Mul(allFractOrg)).
+		Add(org.Mul(allFractOrg)).
 		Abs().
 		Mul(nitrOxdEmissWS).
 		Mul(b).
-		Mul(gWarmingPotentl)
+		Mul(gWarmingPotentl))
 }
 
 func NfertVolatITDefault(massSynthFertz, massOrgFertz decimal.Decimal) decimal.Decimal {
+	return NfertVolatITDefaultGWP(massSynthFertz, massOrgFertz, GWPAR5)
+}
+
+// NfertVolatITDefault with the N2O global warming potential of the set
+func NfertVolatITDefaultGWP(massSynthFertz, massOrgFertz decimal.Decimal, gwp GWPSet) decimal.Decimal {
 	return massSynthFertz.Mul(decimal.NewFromFloat(1.1 * 0.1)).
 		Add(massOrgFertz.Mul(decimal.NewFromFloat(1.1 * 0.3))).
-		Mul(decimal.NewFromFloat(0.01 * (44.0 / 28.0) * 265))
+		Mul(decimal.NewFromFloat(0.01 * (44.0 / 28.0) * GWPDict[gwp].N2O))
 }
 
 func NfertLeachIT(massSynthFertz, nContSynthFertz, massOrgFertz, nContOrgFertz, nFractSoil, nitrOxdEmissLR, gWarmingPotentl decimal.Decimal) decimal.Decimal {
-	if nContSynthFertz.Equal(decimal.Zero) {
-		nContSynthFertz = massSynthFertz.Mul(decimal.NewFromFloat(0.1))
-	}
-	if nContOrgFertz.Equal(decimal.Zero) {
-		nContOrgFertz = massOrgFertz.Mul(decimal.NewFromFloat(0.1))
-	}
-	if gWarmingPotentl.Equal(decimal.Zero) {
-		gWarmingPotentl = decimal.New(265, 0)
-	}
-	if nFractSoil.Equal(decimal.Zero) {
-		nFractSoil = decimal.NewFromFloat(0.3)
-	}
-	if nitrOxdEmissLR.Equal(decimal.Zero) {
-		nitrOxdEmissLR = decimal.NewFromFloat(0.0075)
-	}
+	return nfertLeachIT(nil, NfertLeachITOptions{
+		FertilizerInputs: newFertilizerInputs(massSynthFertz, nContSynthFertz, massOrgFertz, nContOrgFertz, gWarmingPotentl),
+		NFractSoil:       zeroDefault(nFractSoil),
+		NitrOxdEmissLR:   zeroDefault(nitrOxdEmissLR),
+	})
+}
+
+// Options of NfertLeachITWith, see NfertLeachIT
+// nFractSoil - nil if you want to get default value 0.3
+// nitrOxdEmissLR - nil if you want to get default value 0.0075
+type NfertLeachITOptions struct {
+	FertilizerInputs
+	NFractSoil     *decimal.Decimal `json:"nFractSoil"`
+	NitrOxdEmissLR *decimal.Decimal `json:"nitrOxdEmissLR"`
+}
+
+// NfertLeachIT with zero as a legal value of the options, e.g. the fertilizer
+// without leaching, returns the defaults substituted for the options not
+// provided
+func NfertLeachITWith(o NfertLeachITOptions) (decimal.Decimal, Defaults) {
+	tr := NewTrace("NfertLeachITWith")
+	result := nfertLeachIT(tr, o)
+	return result, tr.defaults()
+}
+
+func nfertLeachIT(tr *Trace, o NfertLeachITOptions) decimal.Decimal {
+	tr = tr.Step("NfertLeachIT")
+	synth, org := o.nitrogen(tr)
+	gWarmingPotentl := o.gWarmingPotentl(tr)
+	nFractSoil := tr.optional("nFractSoil", o.NFractSoil, decimal.NewFromFloat(0.3))
+	nitrOxdEmissLR := tr.optional("nitrOxdEmissLR", o.NitrOxdEmissLR, decimal.NewFromFloat(0.0075))
 	b := decimal.NewFromFloat(44.0 / 28.0)
-	return massSynthFertz.
-		Mul(nContSynthFertz).
-		Add(massOrgFertz.Mul(nContOrgFertz)).
+	return tr.Result(synth.
+		Add(org).
 		Mul(nFractSoil).
 		Mul(nitrOxdEmissLR).
 		Mul(b).
-		Mul(gWarmingPotentl)
+		Mul(gWarmingPotentl))
 }
 
 func NfertLeachITDefault(massSynthFertz, massOrgFertz decimal.Decimal) decimal.Decimal {
-	b := decimal.NewFromFloat(1.1 * 0.3 * 0.0075 * (44.0 / 28.0) * 265)
+	return NfertLeachITDefaultGWP(massSynthFertz, massOrgFertz, GWPAR5)
+}
+
+// NfertLeachITDefault with the N2O global warming potential of the set
+func NfertLeachITDefaultGWP(massSynthFertz, massOrgFertz decimal.Decimal, gwp GWPSet) decimal.Decimal {
+	b := decimal.NewFromFloat(1.1 * 0.3 * 0.0075 * (44.0 / 28.0) * GWPDict[gwp].N2O)
 	return massSynthFertz.Add(massOrgFertz).Mul(b)
 }
 
@@ -133,7 +259,109 @@ func NfertLeachITDefault(massSynthFertz, massOrgFertz decimal.Decimal) decimal.D
 // baseline -
 // leakeage -
 func NetEmissionsRemoval(cTotalCarbon, baseline, leakeage, emissions decimal.Decimal) decimal.Decimal {
-	return cTotalCarbon.Mul(decimal.New(1, 0).Sub(leakeage)).
+	return netEmissionsRemoval(nil, cTotalCarbon, baseline, leakeage, emissions)
+}
+
+func netEmissionsRemoval(tr *Trace, cTotalCarbon, baseline, leakeage, emissions decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("NetEmissionsRemoval").
+		Input("cTotalCarbon", cTotalCarbon).
+		Input("baseline", baseline).
+		Input("leakeage", leakeage).
+		Input("emissions", emissions)
+	return tr.Result(cTotalCarbon.Mul(decimal.New(1, 0).Sub(leakeage)).
 		Sub(baseline).
-		Sub(emissions)
+		Sub(emissions))
+}
+
+// Source of the project emissions in the emissions statement
+type EmissionSource uint8
+
+const (
+	EmissionFertilizer EmissionSource = iota
+	EmissionBurning
+	EmissionFuel
+	EmissionOther
+)
+
+var emissionSourceNames = map[EmissionSource]string{
+	EmissionFertilizer: "fertilizer",
+	EmissionBurning:    "burning",
+	EmissionFuel:       "fuel",
+	EmissionOther:      "other",
+}
+
+func (e EmissionSource) String() string {
+	return enumName(emissionSourceNames, e)
+}
+
+func (e EmissionSource) MarshalText() ([]byte, error) {
+	return marshalEnum(emissionSourceNames, e)
+}
+
+func (e *EmissionSource) UnmarshalText(text []byte) error {
+	return unmarshalEnum(emissionSourceNames, text, e)
+}
+
+// Emissions of the single source of the statement (t CO2-e)
+// id - id of the burning event or fuel consumption, empty for the fertilizers
+// and other emissions
+type EmissionItem struct {
+	Source    EmissionSource  `json:"source"`
+	ID        string          `json:"id,omitempty"`
+	Emissions decimal.Decimal `json:"emissions"`
+}
+
+// Itemized project emissions, total is the emissions argument of
+// NetEmissionsRemoval
+// gwp - GWP set used for the defaults of the global warming potentials
+type EmissionsStatement struct {
+	GWP   GWPSet          `json:"gwp"`
+	Items []EmissionItem  `json:"items"`
+	Total decimal.Decimal `json:"total"`
+}
+
+// Sum of the emissions of the source (t CO2-e)
+func (e EmissionsStatement) BySource(source EmissionSource) decimal.Decimal {
+	sum := decimal.Zero
+	for _, item := range e.Items {
+		if item.Source == source {
+			sum = sum.Add(item.Emissions)
+		}
+	}
+	return sum
+}
+
+// Calculate the itemized project emissions
+// fertilizers - nitrogen fertilizer, see NetGHGEmissions
+// burning - biomass burning, see BurningEvent
+// fuels - fossil fuel combustion, see FuelConsumption
+// otherEmissions - emissions from other sources (t CO2-e)
+// gwp - GWP set of the global warming potentials not given in the inputs
+func ProjectEmissions(fertilizers []Fertilizer, burning []BurningEvent, fuels []FuelConsumption, otherEmissions decimal.Decimal, gwp GWPSet) EmissionsStatement {
+	return projectEmissions(nil, fertilizers, burning, fuels, otherEmissions, gwp)
+}
+
+func projectEmissions(tr *Trace, fertilizers []Fertilizer, burning []BurningEvent, fuels []FuelConsumption, otherEmissions decimal.Decimal, gwp GWPSet) EmissionsStatement {
+	tr = tr.Step("Emissions").
+		Of(gwp.String()).
+		Input("otherEmissions", otherEmissions)
+	statement := EmissionsStatement{GWP: gwp, Items: []EmissionItem{}}
+	add := func(source EmissionSource, id string, emissions decimal.Decimal) {
+		statement.Items = append(statement.Items, EmissionItem{Source: source, ID: id, Emissions: emissions})
+		statement.Total = statement.Total.Add(emissions)
+	}
+	for _, fertilizer := range fertilizers {
+		add(EmissionFertilizer, "", fertilizer.emissions(tr, gwp))
+	}
+	for _, event := range burning {
+		add(EmissionBurning, event.ID, event.emissions(tr, gwp))
+	}
+	for _, fuel := range fuels {
+		add(EmissionFuel, fuel.ID, fuel.emissions(tr, gwp))
+	}
+	if !otherEmissions.IsZero() {
+		add(EmissionOther, "", otherEmissions)
+	}
+	tr.Result(statement.Total)
+	return statement
 }
@@ -0,0 +1,130 @@
+package carbon_calc
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+type FuelType uint8
+
+const (
+	FuelDiesel FuelType = iota
+	FuelGasoline
+	// Gasoline of the 2-stroke engines, e.g. chainsaws
+	FuelTwoStrokeGasoline
+)
+
+var fuelTypeNames = map[FuelType]string{
+	FuelDiesel:            "diesel",
+	FuelGasoline:          "gasoline",
+	FuelTwoStrokeGasoline: "two-stroke-gasoline",
+}
+
+func (f FuelType) String() string {
+	return enumName(fuelTypeNames, f)
+}
+
+func (f FuelType) MarshalText() ([]byte, error) {
+	return marshalEnum(fuelTypeNames, f)
+}
+
+func (f *FuelType) UnmarshalText(text []byte) error {
+	return unmarshalEnum(fuelTypeNames, text, f)
+}
+
+// Default properties of the fuel
+// netCalorificValue - net calorific value (MJ/kg)
+// density - density of the fuel (kg/l)
+// co2, ch4, n2o - emission factors (kg/TJ)
+type FuelProperties struct {
+	NetCalorificValue float64 `json:"netCalorificValue" yaml:"netCalorificValue"`
+	Density           float64 `json:"density" yaml:"density"`
+	CO2               float64 `json:"co2" yaml:"co2"`
+	CH4               float64 `json:"ch4" yaml:"ch4"`
+	N2O               float64 `json:"n2o" yaml:"n2o"`
+}
+
+// Default fuel properties of IPCC 2006 Volume 2 Tables 1.2, 1.4 and 3.3.1
+// (off-road mobile sources and machinery)
+var FuelLibrary map[FuelType]FuelProperties = map[FuelType]FuelProperties{
+	FuelDiesel:            {NetCalorificValue: 43, Density: 0.84, CO2: 74100, CH4: 4.15, N2O: 28.6},
+	FuelGasoline:          {NetCalorificValue: 44.3, Density: 0.74, CO2: 69300, CH4: 50, N2O: 2},
+	FuelTwoStrokeGasoline: {NetCalorificValue: 44.3, Density: 0.74, CO2: 69300, CH4: 140, N2O: 0.4},
+}
+
+// Fuel consumed by the project vehicles and machinery during the stage
+// volume - volume of the fuel (l), used if mass is 0
+// mass - mass of the fuel (kg)
+// netCalorificValue, density, emissionFactorCO2, emissionFactorCH4,
+// emissionFactorN2O - 0 if you want to get the values of the fuel type, see
+// FuelLibrary
+// gwpCH4, gwpN2O - global warming potentials, 0 if you want to get the fossil
+// CH4 and N2O values of the GWP set, see GWPDict
+type FuelConsumption struct {
+	ID                string          `json:"id"`
+	Fuel              FuelType        `json:"fuel"`
+	Volume            decimal.Decimal `json:"volume"`
+	Mass              decimal.Decimal `json:"mass"`
+	NetCalorificValue decimal.Decimal `json:"netCalorificValue"`
+	Density           decimal.Decimal `json:"density"`
+	EmissionFactorCO2 decimal.Decimal `json:"emissionFactorCO2"`
+	EmissionFactorCH4 decimal.Decimal `json:"emissionFactorCH4"`
+	EmissionFactorN2O decimal.Decimal `json:"emissionFactorN2O"`
+	GWPCH4            decimal.Decimal `json:"gwpCH4"`
+	GWPN2O            decimal.Decimal `json:"gwpN2O"`
+}
+
+// Calculate the emission of the gas from the fuel combustion (t CO2-e)
+// mass - mass of the fuel (kg)
+// netCalorificValue - net calorific value (MJ/kg)
+// emissionFactor - emission factor of the gas (kg/TJ)
+// gwp - global warming potential of the gas, 1 for CO2
+func FuelEmissions(mass, netCalorificValue, emissionFactor, gwp decimal.Decimal) decimal.Decimal {
+	return fuelEmissions(nil, "", mass, netCalorificValue, emissionFactor, gwp)
+}
+
+func fuelEmissions(tr *Trace, gas string, mass, netCalorificValue, emissionFactor, gwp decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("FuelEmissions").
+		Of(gas).
+		Input("mass", mass).
+		Input("netCalorificValue", netCalorificValue).
+		Input("emissionFactor", emissionFactor).
+		Input("gwp", gwp)
+	// MJ to TJ and kg to t
+	return tr.Result(mass.
+		Mul(netCalorificValue).
+		Mul(emissionFactor).
+		Mul(gwp).
+		Mul(decimal.New(1, -9)))
+}
+
+// Calculate the CO2, CH4 and N2O emissions of the fuel combustion (t CO2-e)
+func (f FuelConsumption) Emissions() decimal.Decimal {
+	return f.emissions(nil, GWPAR5)
+}
+
+// Calculate the emissions of the fuel combustion with the defaults of the GWP
+// set
+func (f FuelConsumption) EmissionsGWP(gwp GWPSet) decimal.Decimal {
+	return f.emissions(nil, gwp)
+}
+
+func (f FuelConsumption) emissions(tr *Trace, gwp GWPSet) decimal.Decimal {
+	tr = tr.Step("FuelConsumption").Of(f.ID)
+	properties := FuelLibrary[f.Fuel]
+	mass := f.Mass
+	if mass.IsZero() {
+		tr.Input("volume", f.Volume)
+		density := tr.inputOrDefault("density", f.Density, decimal.NewFromFloat(properties.Density))
+		mass = f.Volume.Mul(density)
+	}
+	netCalorificValue := tr.inputOrDefault("netCalorificValue", f.NetCalorificValue, decimal.NewFromFloat(properties.NetCalorificValue))
+	emissionFactorCO2 := tr.inputOrDefault("emissionFactorCO2", f.EmissionFactorCO2, decimal.NewFromFloat(properties.CO2))
+	emissionFactorCH4 := tr.inputOrDefault("emissionFactorCH4", f.EmissionFactorCH4, decimal.NewFromFloat(properties.CH4))
+	emissionFactorN2O := tr.inputOrDefault("emissionFactorN2O", f.EmissionFactorN2O, decimal.NewFromFloat(properties.N2O))
+	gwpCH4 := tr.inputOrDefault("gwpCH4", f.GWPCH4, gwp.CH4Fossil())
+	gwpN2O := tr.inputOrDefault("gwpN2O", f.GWPN2O, gwp.N2O())
+	co2 := fuelEmissions(tr, "CO2", mass, netCalorificValue, emissionFactorCO2, gwp.CO2())
+	ch4 := fuelEmissions(tr, "CH4", mass, netCalorificValue, emissionFactorCH4, gwpCH4)
+	n2o := fuelEmissions(tr, "N2O", mass, netCalorificValue, emissionFactorN2O, gwpN2O)
+	return tr.Result(co2.Add(ch4).Add(n2o))
+}
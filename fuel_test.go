@@ -0,0 +1,100 @@
+package carbon_calc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFuelEmissions(t *testing.T) {
+	// 1000 kg * 43 MJ/kg * 74100 kg/TJ / 1e9
+	result := FuelEmissions(decimal.New(1000, 0), decimal.New(43, 0), decimal.New(74100, 0), decimal.New(1, 0))
+	if !result.Equal(decimal.NewFromFloat(3.1863)) {
+		t.Fatalf("expect: 3.1863, have: %s", result)
+	}
+
+	type Test struct {
+		fuel   FuelConsumption
+		result float64
+	}
+	tests := []Test{
+		// Diesel defaults: 0.043 TJ * (74100 + 4.15 * 30 + 28.6 * 265) / 1000
+		{FuelConsumption{Mass: decimal.New(1000, 0)}, 3.5175505},
+		// 1000 l of diesel is 840 kg
+		{FuelConsumption{Volume: decimal.New(1000, 0)}, 2.95474242},
+		// Mass takes precedence over volume
+		{FuelConsumption{Mass: decimal.New(1000, 0), Volume: decimal.New(1, 0)}, 3.5175505},
+		// 0.0443 TJ * (69300 + 140 * 30 + 0.4 * 265) / 1000
+		{FuelConsumption{Fuel: FuelTwoStrokeGasoline, Mass: decimal.New(1000, 0)}, 3.2607458},
+		{FuelConsumption{
+			Mass:              decimal.New(100, 0),
+			NetCalorificValue: decimal.New(40, 0),
+			EmissionFactorCO2: decimal.New(70000, 0),
+			EmissionFactorCH4: decimal.New(10, 0),
+			EmissionFactorN2O: decimal.New(1, 0),
+			GWPCH4:            decimal.New(25, 0),
+			GWPN2O:            decimal.New(298, 0),
+		}, 0.28 + 0.001 + 0.001192},
+		{FuelConsumption{Fuel: FuelGasoline}, 0},
+	}
+	for i, tt := range tests {
+		result := tt.fuel.Emissions()
+		if !result.Equal(decimal.NewFromFloat(tt.result)) {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, tt.result, result)
+		}
+	}
+}
+
+func TestProjectEmissions(t *testing.T) {
+	burning := BurningEvent{ID: "fire", Area: decimal.New(10, 0), Biomass: decimal.New(100, 0)}
+	fuel := FuelConsumption{ID: "tractor", Mass: decimal.New(1000, 0)}
+	statement := ProjectEmissions(nil, []BurningEvent{burning}, []FuelConsumption{fuel}, decimal.New(2, 0), GWPAR5)
+	if len(statement.Items) != 3 {
+		t.Fatalf("expect 3 items, have: %v", statement.Items)
+	}
+	expected := []EmissionItem{
+		{EmissionBurning, "fire", burning.Emissions()},
+		{EmissionFuel, "tractor", fuel.Emissions()},
+		{EmissionOther, "", decimal.New(2, 0)},
+	}
+	for i, item := range expected {
+		have := statement.Items[i]
+		if have.Source != item.Source || have.ID != item.ID || !have.Emissions.Equal(item.Emissions) {
+			t.Fatalf("Item number %d, expect: %v, have: %v", i, item, have)
+		}
+	}
+	total := burning.Emissions().Add(fuel.Emissions()).Add(decimal.New(2, 0))
+	if !statement.Total.Equal(total) {
+		t.Fatalf("expect: %s, have: %s", total, statement.Total)
+	}
+	if !statement.BySource(EmissionFuel).Equal(fuel.Emissions()) || !statement.BySource(EmissionFertilizer).IsZero() {
+		t.Fatalf("Wrong emissions by source: %v", statement)
+	}
+}
+
+func TestStageFuels(t *testing.T) {
+	stage := testStage()
+	without, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fuel := FuelConsumption{ID: "chainsaw", Fuel: FuelTwoStrokeGasoline, Volume: decimal.New(200, 0)}
+	stage.Fuels = []FuelConsumption{fuel}
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.FuelEmissions.Equal(fuel.Emissions()) || !result.Emissions.Equal(without.Emissions.Add(fuel.Emissions())) {
+		t.Fatalf("expect: %s, have: %s, %s", fuel.Emissions(), result.FuelEmissions, result.Emissions)
+	}
+	if !result.EmissionsStatement.Total.Equal(result.Emissions) {
+		t.Fatalf("expect: %s, have: %s", result.Emissions, result.EmissionsStatement.Total)
+	}
+
+	stage.Fuels[0].Volume = decimal.New(-1, 0)
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "fuel volume" {
+		t.Fatalf("expect fuel volume error, have: %v", err)
+	}
+}
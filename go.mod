@@ -4,7 +4,7 @@ go 1.19
 
 require (
 	github.com/shopspring/decimal v1.3.1
+	golang.org/x/exp v0.0.0-20230212135524-a684f29349b6
 	gonum.org/v1/gonum v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
 )
-
-require golang.org/x/exp v0.0.0-20230212135524-a684f29349b6 // indirect
@@ -0,0 +1,67 @@
+package carbon_calc
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Set of the global warming potentials (100-year horizon) of the IPCC
+// assessment report, zero value is AR5
+type GWPSet uint8
+
+const (
+	GWPAR5 GWPSet = iota
+	GWPAR4
+	GWPAR6
+)
+
+var gwpSetNames = map[GWPSet]string{
+	GWPAR5: "ar5",
+	GWPAR4: "ar4",
+	GWPAR6: "ar6",
+}
+
+func (g GWPSet) String() string {
+	return enumName(gwpSetNames, g)
+}
+
+func (g GWPSet) MarshalText() ([]byte, error) {
+	return marshalEnum(gwpSetNames, g)
+}
+
+func (g *GWPSet) UnmarshalText(text []byte) error {
+	return unmarshalEnum(gwpSetNames, text, g)
+}
+
+// Global warming potentials of the gases
+// ch4Fossil - CH4 of the fossil origin, e.g. fuel combustion
+// ch4 - CH4 of the non-fossil origin, e.g. biomass burning
+type GWPValues struct {
+	CO2       float64 `json:"co2" yaml:"co2"`
+	CH4Fossil float64 `json:"ch4Fossil" yaml:"ch4Fossil"`
+	CH4       float64 `json:"ch4" yaml:"ch4"`
+	N2O       float64 `json:"n2o" yaml:"n2o"`
+}
+
+// Values of AR4 WG1 Table 2.14, AR5 WG1 Table 8.7 and AR6 WG1 Table 7.15, AR4
+// does not distinguish the fossil CH4
+var GWPDict map[GWPSet]GWPValues = map[GWPSet]GWPValues{
+	GWPAR4: {CO2: 1, CH4Fossil: 25, CH4: 25, N2O: 298},
+	GWPAR5: {CO2: 1, CH4Fossil: 30, CH4: 28, N2O: 265},
+	GWPAR6: {CO2: 1, CH4Fossil: 29.8, CH4: 27, N2O: 273},
+}
+
+func (g GWPSet) CO2() decimal.Decimal {
+	return decimal.NewFromFloat(GWPDict[g].CO2)
+}
+
+func (g GWPSet) CH4Fossil() decimal.Decimal {
+	return decimal.NewFromFloat(GWPDict[g].CH4Fossil)
+}
+
+func (g GWPSet) CH4() decimal.Decimal {
+	return decimal.NewFromFloat(GWPDict[g].CH4)
+}
+
+func (g GWPSet) N2O() decimal.Decimal {
+	return decimal.NewFromFloat(GWPDict[g].N2O)
+}
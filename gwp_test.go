@@ -0,0 +1,91 @@
+package carbon_calc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestGWPSet(t *testing.T) {
+	type Test struct {
+		gwp                 GWPSet
+		ch4Fossil, ch4, n2o float64
+	}
+	tests := []Test{
+		{GWPAR4, 25, 25, 298},
+		{GWPAR5, 30, 28, 265},
+		{GWPAR6, 29.8, 27, 273},
+	}
+	for i, tt := range tests {
+		if !tt.gwp.CO2().Equal(decimal.New(1, 0)) ||
+			!tt.gwp.CH4Fossil().Equal(decimal.NewFromFloat(tt.ch4Fossil)) ||
+			!tt.gwp.CH4().Equal(decimal.NewFromFloat(tt.ch4)) ||
+			!tt.gwp.N2O().Equal(decimal.NewFromFloat(tt.n2o)) {
+			t.Fatalf("Test number %d, wrong values of %s", i, tt.gwp)
+		}
+	}
+	var gwp GWPSet
+	if err := gwp.UnmarshalText([]byte("ar6")); err != nil || gwp != GWPAR6 {
+		t.Fatalf("expect: ar6, have: %s, %v", gwp, err)
+	}
+}
+
+func TestEmissionsDefaultGWP(t *testing.T) {
+	mass := decimal.New(12, 0)
+	type Test struct {
+		name    string
+		compute func(GWPSet) decimal.Decimal
+	}
+	tests := []Test{
+		{"CO2eNdirecttDefaultGWP", func(gwp GWPSet) decimal.Decimal { return CO2eNdirecttDefaultGWP(mass, mass, gwp) }},
+		{"NfertVolatITDefaultGWP", func(gwp GWPSet) decimal.Decimal { return NfertVolatITDefaultGWP(mass, mass, gwp) }},
+		{"NfertLeachITDefaultGWP", func(gwp GWPSet) decimal.Decimal { return NfertLeachITDefaultGWP(mass, mass, gwp) }},
+	}
+	for _, tt := range tests {
+		ar5 := tt.compute(GWPAR5)
+		// Emissions are proportional to the N2O global warming potential
+		for _, gwp := range []GWPSet{GWPAR4, GWPAR6} {
+			expect := ar5.Mul(gwp.N2O()).Div(GWPAR5.N2O())
+			if have := tt.compute(gwp); !have.Round(9).Equal(expect.Round(9)) {
+				t.Fatalf("%s %s, expect: %s, have: %s", tt.name, gwp, expect, have)
+			}
+		}
+	}
+	if !CO2eNdirecttDefault(mass, mass).Equal(CO2eNdirecttDefaultGWP(mass, mass, GWPAR5)) {
+		t.Fatalf("CO2eNdirecttDefault must use AR5")
+	}
+}
+
+func TestStageGWP(t *testing.T) {
+	stage := testStage()
+	stage.Fertilizers = []Fertilizer{{Applications: decimal.New(1, 0), MassSynthFertz: decimal.New(100, 0)}}
+	stage.Burning = []BurningEvent{{ID: "fire", Area: decimal.New(1, 0), Biomass: decimal.New(10, 0)}}
+	stage.Fuels = []FuelConsumption{{ID: "tractor", Volume: decimal.New(100, 0)}}
+	stage.GWP = GWPAR6
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.EmissionsStatement.GWP != GWPAR6 {
+		t.Fatalf("expect: ar6, have: %s", result.EmissionsStatement.GWP)
+	}
+	expect := stage.Fertilizers[0].EmissionsGWP(GWPAR6).
+		Add(stage.Burning[0].EmissionsGWP(GWPAR6)).
+		Add(stage.Fuels[0].EmissionsGWP(GWPAR6))
+	if !result.Emissions.Equal(expect) {
+		t.Fatalf("expect: %s, have: %s", expect, result.Emissions)
+	}
+	ar5 := stage.Fertilizers[0].Emissions().
+		Add(stage.Burning[0].Emissions()).
+		Add(stage.Fuels[0].Emissions())
+	if result.Emissions.Equal(ar5) {
+		t.Fatalf("AR6 emissions must differ from AR5: %s", ar5)
+	}
+
+	stage.GWP = 10
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "gwp" {
+		t.Fatalf("expect gwp error, have: %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package carbon_calc
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Source of the leakage in the leakage statement
+type LeakageSource uint8
+
+const (
+	LeakageDisplacement LeakageSource = iota
+	LeakageFuelwood
+)
+
+var leakageSourceNames = map[LeakageSource]string{
+	LeakageDisplacement: "displacement",
+	LeakageFuelwood:     "fuelwood",
+}
+
+func (l LeakageSource) String() string {
+	return enumName(leakageSourceNames, l)
+}
+
+func (l LeakageSource) MarshalText() ([]byte, error) {
+	return marshalEnum(leakageSourceNames, l)
+}
+
+func (l *LeakageSource) UnmarshalText(text []byte) error {
+	return unmarshalEnum(leakageSourceNames, text, l)
+}
+
+// Pre-project agricultural activity displaced by the project, see CDM
+// AR-TOOL15
+// area - area of the displaced activity (ha)
+// forestFraction - fraction of the displaced activity moved to the forest land
+// biomass - above-ground biomass of the forest on the land receiving the
+// activity (t d.m./ha)
+// rootShoot - root-shoot ratio of the forest, 0 if you want to get default
+// value 0.25
+// fraction - carbon fraction of the forest biomass, 0 if you want to get
+// default value 0.47
+type Displacement struct {
+	ID             string          `json:"id"`
+	Area           decimal.Decimal `json:"area"`
+	ForestFraction decimal.Decimal `json:"forestFraction"`
+	Biomass        decimal.Decimal `json:"biomass"`
+	RootShoot      decimal.Decimal `json:"rootShoot"`
+	Fraction       decimal.Decimal `json:"fraction"`
+}
+
+// Fuelwood collected in the project area during the stage that would not be
+// collected without the project
+// volume - volume of the fuelwood (m3)
+// density - basic density of the wood (t/m3)
+// nonRenewable - fraction of the fuelwood from the non-renewable biomass, 0 if
+// you want to get default value 1
+// fraction - carbon fraction of the wood, 0 if you want to get default value
+// 0.47
+type FuelwoodCollection struct {
+	ID           string          `json:"id"`
+	Volume       decimal.Decimal `json:"volume"`
+	Density      decimal.Decimal `json:"density"`
+	NonRenewable decimal.Decimal `json:"nonRenewable"`
+	Fraction     decimal.Decimal `json:"fraction"`
+}
+
+// Calculate the leakage of the displacement of the agricultural activity
+// (t CO2-e), the loss of the forest carbon on the land receiving the activity
+// fraction - carbon fraction of the forest biomass
+// rootShoot - root-shoot ratio of the forest
+// area - area of the displaced activity (ha)
+// forestFraction - fraction of the displaced activity moved to the forest land
+// biomass - above-ground biomass of the forest (t d.m./ha)
+func DisplacementLeakage(fraction, rootShoot, area, forestFraction, biomass decimal.Decimal) decimal.Decimal {
+	return displacementLeakage(nil, fraction, rootShoot, area, forestFraction, biomass)
+}
+
+func displacementLeakage(tr *Trace, fraction, rootShoot, area, forestFraction, biomass decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("DisplacementLeakage").
+		Input("fraction", fraction).
+		Input("rootShoot", rootShoot).
+		Input("area", area).
+		Input("forestFraction", forestFraction).
+		Input("biomass", biomass)
+	return tr.Result(decimal.NewFromFloat(44.0 / 12.0).
+		Mul(fraction).
+		Mul(decimal.New(1, 0).Add(rootShoot)).
+		Mul(area).
+		Mul(forestFraction).
+		Mul(biomass))
+}
+
+// Calculate the leakage of the displacement (t CO2-e)
+func (d Displacement) Leakage() decimal.Decimal {
+	return d.leakage(nil)
+}
+
+func (d Displacement) leakage(tr *Trace) decimal.Decimal {
+	tr = tr.Step("Displacement").Of(d.ID)
+	fraction := tr.inputOrDefault("fraction", d.Fraction, decimal.NewFromFloat(0.47))
+	rootShoot := tr.inputOrDefault("rootShoot", d.RootShoot, decimal.NewFromFloat(0.25))
+	return tr.Result(displacementLeakage(tr, fraction, rootShoot, d.Area, d.ForestFraction, d.Biomass))
+}
+
+// Calculate the leakage of the fuelwood collection (t CO2-e)
+// fraction - carbon fraction of the wood
+// volume - volume of the fuelwood (m3)
+// density - basic density of the wood (t/m3)
+// nonRenewable - fraction of the fuelwood from the non-renewable biomass
+func FuelwoodLeakage(fraction, volume, density, nonRenewable decimal.Decimal) decimal.Decimal {
+	return fuelwoodLeakage(nil, fraction, volume, density, nonRenewable)
+}
+
+func fuelwoodLeakage(tr *Trace, fraction, volume, density, nonRenewable decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("FuelwoodLeakage").
+		Input("fraction", fraction).
+		Input("volume", volume).
+		Input("density", density).
+		Input("nonRenewable", nonRenewable)
+	return tr.Result(decimal.NewFromFloat(44.0 / 12.0).
+		Mul(fraction).
+		Mul(volume).
+		Mul(density).
+		Mul(nonRenewable))
+}
+
+// Calculate the leakage of the fuelwood collection (t CO2-e)
+func (f FuelwoodCollection) Leakage() decimal.Decimal {
+	return f.leakage(nil)
+}
+
+func (f FuelwoodCollection) leakage(tr *Trace) decimal.Decimal {
+	tr = tr.Step("FuelwoodCollection").Of(f.ID)
+	fraction := tr.inputOrDefault("fraction", f.Fraction, decimal.NewFromFloat(0.47))
+	nonRenewable := tr.inputOrDefault("nonRenewable", f.NonRenewable, decimal.New(1, 0))
+	return tr.Result(fuelwoodLeakage(tr, fraction, f.Volume, f.Density, nonRenewable))
+}
+
+// Leakage of the single source of the statement (t CO2-e)
+type LeakageItem struct {
+	Source  LeakageSource   `json:"source"`
+	ID      string          `json:"id,omitempty"`
+	Leakage decimal.Decimal `json:"leakage"`
+}
+
+// Itemized leakage of the project
+// total - leakage (t CO2-e)
+// fraction - leakage argument of NetEmissionsRemoval, total divided by the
+// carbon, not more than 1 and 0 if there is no carbon
+type LeakageStatement struct {
+	Items    []LeakageItem   `json:"items"`
+	Total    decimal.Decimal `json:"total"`
+	Fraction decimal.Decimal `json:"fraction"`
+}
+
+// Sum of the leakage of the source (t CO2-e)
+func (l LeakageStatement) BySource(source LeakageSource) decimal.Decimal {
+	sum := decimal.Zero
+	for _, item := range l.Items {
+		if item.Source == source {
+			sum = sum.Add(item.Leakage)
+		}
+	}
+	return sum
+}
+
+// Calculate the itemized leakage of the project
+// displacements - displaced pre-project agricultural activities
+// fuelwood - fuelwood collection attributable to the project
+// cTotalCarbon - carbon the leakage fraction is applied to, see
+// NetEmissionsRemoval
+func ProjectLeakage(displacements []Displacement, fuelwood []FuelwoodCollection, cTotalCarbon decimal.Decimal) LeakageStatement {
+	return projectLeakage(nil, displacements, fuelwood, cTotalCarbon)
+}
+
+func projectLeakage(tr *Trace, displacements []Displacement, fuelwood []FuelwoodCollection, cTotalCarbon decimal.Decimal) LeakageStatement {
+	tr = tr.Step("Leakage").
+		Input("cTotalCarbon", cTotalCarbon)
+	statement := LeakageStatement{Items: []LeakageItem{}}
+	add := func(source LeakageSource, id string, leakage decimal.Decimal) {
+		statement.Items = append(statement.Items, LeakageItem{Source: source, ID: id, Leakage: leakage})
+		statement.Total = statement.Total.Add(leakage)
+	}
+	for _, displacement := range displacements {
+		add(LeakageDisplacement, displacement.ID, displacement.leakage(tr))
+	}
+	for _, collection := range fuelwood {
+		add(LeakageFuelwood, collection.ID, collection.leakage(tr))
+	}
+	if cTotalCarbon.IsPositive() {
+		statement.Fraction = decimal.Min(decimal.New(1, 0), statement.Total.Div(cTotalCarbon))
+	}
+	tr.Result(statement.Fraction)
+	return statement
+}
@@ -0,0 +1,91 @@
+package carbon_calc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLeakage(t *testing.T) {
+	type Test struct {
+		leakage decimal.Decimal
+		result  float64 // precision = 6
+	}
+	tests := []Test{
+		// 44/12 * 0.47 * 1.25 * 2 ha * 0.5 * 100 t/ha
+		{Displacement{Area: decimal.New(2, 0), ForestFraction: decimal.NewFromFloat(0.5), Biomass: decimal.New(100, 0)}.Leakage(), 215.416667},
+		{Displacement{Area: decimal.New(2, 0), Biomass: decimal.New(100, 0)}.Leakage(), 0},
+		{DisplacementLeakage(decimal.NewFromFloat(0.5), decimal.NewFromFloat(0.2), decimal.New(1, 0), decimal.New(1, 0), decimal.New(12, 0)), 26.4},
+		// 44/12 * 0.47 * 10 m3 * 0.6 t/m3
+		{FuelwoodCollection{Volume: decimal.New(10, 0), Density: decimal.NewFromFloat(0.6)}.Leakage(), 10.34},
+		{FuelwoodCollection{Volume: decimal.New(10, 0), Density: decimal.NewFromFloat(0.6), NonRenewable: decimal.NewFromFloat(0.5)}.Leakage(), 5.17},
+		{FuelwoodLeakage(decimal.NewFromFloat(0.5), decimal.New(6, 0), decimal.New(1, 0), decimal.New(1, 0)), 11},
+	}
+	for i, tt := range tests {
+		if !tt.leakage.Round(6).Equal(decimal.NewFromFloat(tt.result)) {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, tt.result, tt.leakage)
+		}
+	}
+}
+
+func TestProjectLeakage(t *testing.T) {
+	displacement := Displacement{ID: "grazing", Area: decimal.New(2, 0), ForestFraction: decimal.NewFromFloat(0.5), Biomass: decimal.New(100, 0)}
+	fuelwood := FuelwoodCollection{ID: "village", Volume: decimal.New(10, 0), Density: decimal.NewFromFloat(0.6)}
+	statement := ProjectLeakage([]Displacement{displacement}, []FuelwoodCollection{fuelwood}, decimal.New(1000, 0))
+	total := displacement.Leakage().Add(fuelwood.Leakage())
+	if len(statement.Items) != 2 || statement.Items[0].ID != "grazing" || statement.Items[1].Source != LeakageFuelwood {
+		t.Fatalf("Wrong items: %v", statement.Items)
+	}
+	if !statement.Total.Equal(total) || !statement.Fraction.Equal(total.Div(decimal.New(1000, 0))) {
+		t.Fatalf("expect: %s, have: %s, %s", total, statement.Total, statement.Fraction)
+	}
+	if !statement.BySource(LeakageDisplacement).Equal(displacement.Leakage()) {
+		t.Fatalf("expect: %s, have: %s", displacement.Leakage(), statement.BySource(LeakageDisplacement))
+	}
+
+	// The fraction is capped by 1 and is 0 without carbon
+	if statement := ProjectLeakage([]Displacement{displacement}, nil, decimal.New(10, 0)); !statement.Fraction.Equal(decimal.New(1, 0)) {
+		t.Fatalf("expect: 1, have: %s", statement.Fraction)
+	}
+	if statement := ProjectLeakage([]Displacement{displacement}, nil, decimal.Zero); !statement.Fraction.IsZero() {
+		t.Fatalf("expect: 0, have: %s", statement.Fraction)
+	}
+}
+
+func TestStageLeakage(t *testing.T) {
+	stage := testStage()
+	stage.Leakage = decimal.NewFromFloat(0.05)
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	carbon := result.ConservativeCarbon.Add(result.SOC)
+	if !result.Leakage.Equal(stage.Leakage) || !result.LeakageStatement.Total.Equal(carbon.Mul(stage.Leakage)) {
+		t.Fatalf("expect: %s, have: %v", stage.Leakage, result.LeakageStatement)
+	}
+
+	stage.Leakage = decimal.Zero
+	stage.Fuelwood = []FuelwoodCollection{{ID: "village", Volume: decimal.New(1, 0), Density: decimal.NewFromFloat(0.6)}}
+	result, err = stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	carbon = result.ConservativeCarbon.Add(result.SOC)
+	fraction := stage.Fuelwood[0].Leakage().Div(carbon)
+	if !result.Leakage.Equal(fraction) {
+		t.Fatalf("expect: %s, have: %s", fraction, result.Leakage)
+	}
+	net := NetEmissionsRemoval(carbon, result.Baseline, fraction, result.Emissions)
+	if !result.NetEmissionsRemoval.Equal(net) {
+		t.Fatalf("expect: %s, have: %s", net, result.NetEmissionsRemoval)
+	}
+
+	stage.Leakage = decimal.NewFromFloat(0.05)
+	stage.Fuelwood[0].Density = decimal.New(-1, 0)
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 2 ||
+		errs[0].Field != "leakage" || errs[0].Rule != RuleCalculated || errs[1].Field != "fuelwood density" {
+		t.Fatalf("expect leakage and fuelwood density errors, have: %v", err)
+	}
+}
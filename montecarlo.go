@@ -0,0 +1,298 @@
+package carbon_calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Rules violated by the Monte Carlo configuration
+const (
+	RuleMinIterations = "must be at least 2"
+	RuleOrdered       = "must satisfy min <= mode <= max and min < max"
+)
+
+type DistributionType uint8
+
+const (
+	DistributionNormal DistributionType = iota
+	DistributionLogNormal
+	DistributionTriangular
+	DistributionUniform
+)
+
+var distributionTypeNames = map[DistributionType]string{
+	DistributionNormal:     "normal",
+	DistributionLogNormal:  "lognormal",
+	DistributionTriangular: "triangular",
+	DistributionUniform:    "uniform",
+}
+
+func (d DistributionType) String() string {
+	return enumName(distributionTypeNames, d)
+}
+
+func (d DistributionType) MarshalText() ([]byte, error) {
+	return marshalEnum(distributionTypeNames, d)
+}
+
+func (d *DistributionType) UnmarshalText(text []byte) error {
+	return unmarshalEnum(distributionTypeNames, text, d)
+}
+
+// Distribution of the multiplicative factor of the parameter, factor 1 is the
+// nominal value of the parameter, negative factors are truncated to 0
+// mean - mean of the normal distribution, 0 if you want to get default value 1;
+// mean of the logarithm of the lognormal distribution
+// stdDev - standard deviation of the normal distribution or of the logarithm
+// of the lognormal distribution
+// min, mode, max - bounds of the triangular and uniform distributions, mode is
+// used by the triangular distribution only
+type Distribution struct {
+	Type   DistributionType `json:"type"`
+	Mean   float64          `json:"mean,omitempty"`
+	StdDev float64          `json:"stdDev,omitempty"`
+	Min    float64          `json:"min,omitempty"`
+	Mode   float64          `json:"mode,omitempty"`
+	Max    float64          `json:"max,omitempty"`
+}
+
+// Nil if the distribution is nil, the factor is 1 then
+func (d *Distribution) rander(src rand.Source) distuv.Rander {
+	if d == nil {
+		return nil
+	}
+	switch d.Type {
+	case DistributionLogNormal:
+		return distuv.LogNormal{Mu: d.Mean, Sigma: d.StdDev, Src: src}
+	case DistributionTriangular:
+		return distuv.NewTriangle(d.Min, d.Max, d.Mode, src)
+	case DistributionUniform:
+		return distuv.Uniform{Min: d.Min, Max: d.Max, Src: src}
+	default:
+		mean := d.Mean
+		if mean == 0 {
+			mean = 1
+		}
+		return distuv.Normal{Mu: mean, Sigma: d.StdDev, Src: src}
+	}
+}
+
+func (v *validator) validateDistribution(field string, d *Distribution) {
+	if d == nil {
+		return
+	}
+	switch d.Type {
+	case DistributionNormal, DistributionLogNormal:
+		v.check(d.StdDev >= 0, field+" stdDev", d.StdDev, RuleNonNegative, nil)
+	case DistributionTriangular:
+		v.check(d.Min < d.Max && d.Min <= d.Mode && d.Mode <= d.Max, field, fmt.Sprintf("%g, %g, %g", d.Min, d.Mode, d.Max), RuleOrdered, nil)
+	case DistributionUniform:
+		v.check(d.Min < d.Max, field, fmt.Sprintf("%g, %g", d.Min, d.Max), RuleOrdered, nil)
+	}
+}
+
+// Sampled factor truncated to 0, nil if the distribution is nil
+func sampleFactor(r distuv.Rander) *decimal.Decimal {
+	if r == nil {
+		return nil
+	}
+	factor := decimal.NewFromFloat(math.Max(0, r.Rand()))
+	return &factor
+}
+
+// Multiplicative factors of the tree parameters, nil factor means the
+// parameter is not changed
+type parameterFactors struct {
+	density, biomass, ratio *decimal.Decimal
+}
+
+func (f parameterFactors) apply(density, biomass, ratio decimal.Decimal) (decimal.Decimal, decimal.Decimal, decimal.Decimal) {
+	if f.density != nil {
+		density = density.Mul(*f.density)
+	}
+	if f.biomass != nil {
+		biomass = biomass.Mul(*f.biomass)
+	}
+	if f.ratio != nil {
+		ratio = ratio.Mul(*f.ratio)
+	}
+	return density, biomass, ratio
+}
+
+// Monte Carlo propagation of the parameter uncertainty through the stage
+// calculation, alternative to the sampling uncertainty of
+// UncertaintyCarbonStored
+// iterations - number of runs of the stage calculation, 0 if you want to get
+// default value 1000
+// seed - seed of the random number generator, the same seed gives the same
+// result
+// density, biomassExpansion, rootShoot - distributions of the factor of the
+// wood density, BEF and root-shoot ratio, sampled once per iteration, nil if
+// the parameter is fixed
+// height - distribution of the factor of the tree height (measurement error),
+// sampled for every tree, nil if the height is fixed
+// confidence - two-sided confidence level of the uncertainty, 0 if you want to
+// get default value 0.9
+type MonteCarlo struct {
+	Iterations       int           `json:"iterations"`
+	Seed             uint64        `json:"seed"`
+	Confidence       float64       `json:"confidence"`
+	Density          *Distribution `json:"density,omitempty"`
+	BiomassExpansion *Distribution `json:"biomassExpansion,omitempty"`
+	RootShoot        *Distribution `json:"rootShoot,omitempty"`
+	Height           *Distribution `json:"height,omitempty"`
+}
+
+// Distribution of the total carbon stored in all monitoring zones
+// nominal - total carbon of the stage with nominal parameters
+// p5, p50, p95 - percentiles of the total carbon
+// lower, upper - bounds of the confidence interval of the total carbon
+// uncertainty - half-width of the confidence interval as a fraction of the
+// mean, can be used instead of UncertaintyCarbonStored
+// uncertaintyDiscount, conservativeCarbon - see UncertaintyDiscount and
+// ConservativeTotalCarbon of the nominal total carbon with the discount
+// schedule of the stage
+type MonteCarloResult struct {
+	Iterations          int             `json:"iterations"`
+	Confidence          float64         `json:"confidence"`
+	Nominal             decimal.Decimal `json:"nominal"`
+	Mean                decimal.Decimal `json:"mean"`
+	StdDev              decimal.Decimal `json:"stdDev"`
+	P5                  decimal.Decimal `json:"p5"`
+	P50                 decimal.Decimal `json:"p50"`
+	P95                 decimal.Decimal `json:"p95"`
+	Lower               decimal.Decimal `json:"lower"`
+	Upper               decimal.Decimal `json:"upper"`
+	Uncertainty         decimal.Decimal `json:"uncertainty"`
+	UncertaintyDiscount decimal.Decimal `json:"uncertaintyDiscount"`
+	ConservativeCarbon  decimal.Decimal `json:"conservativeCarbon"`
+	// Sorted total carbon of every iteration
+	Samples []float64 `json:"-"`
+}
+
+// Percentile of the total carbon
+// p - probability between 0 and 1
+func (r MonteCarloResult) Percentile(p float64) decimal.Decimal {
+	if len(r.Samples) == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromFloat(stat.Quantile(p, stat.Empirical, r.Samples, nil))
+}
+
+// Check the Monte Carlo configuration, returns ValidationErrors with all
+// problems found
+func (m MonteCarlo) Validate() error {
+	v := &validator{}
+	v.check(m.Iterations == 0 || m.Iterations >= 2, "iterations", m.Iterations, RuleMinIterations, nil)
+	v.check(m.Confidence >= 0 && m.Confidence < 1, "confidence", m.Confidence, RuleFraction, nil)
+	v.validateDistribution("density", m.Density)
+	v.validateDistribution("biomassExpansion", m.BiomassExpansion)
+	v.validateDistribution("rootShoot", m.RootShoot)
+	v.validateDistribution("height", m.Height)
+	return v.errs.err()
+}
+
+func (m MonteCarlo) iterations() int {
+	if m.Iterations == 0 {
+		return 1000
+	}
+	return m.Iterations
+}
+
+func (m MonteCarlo) confidence() float64 {
+	if m.Confidence == 0 {
+		return defaultConfidence
+	}
+	return m.Confidence
+}
+
+// Run the stage calculation with the sampled parameters
+// Returns ValidationErrors if the configuration or the stage is not valid
+func (m MonteCarlo) Run(s Stage) (MonteCarloResult, error) {
+	if err := m.Validate(); err != nil {
+		return MonteCarloResult{}, err
+	}
+	s.Explain = false
+	nominal, err := s.Calculate()
+	if err != nil {
+		return MonteCarloResult{}, err
+	}
+	src := rand.NewSource(m.Seed)
+	density := m.Density.rander(src)
+	biomass := m.BiomassExpansion.rander(src)
+	ratio := m.RootShoot.rander(src)
+	height := m.Height.rander(src)
+
+	iterations := m.iterations()
+	samples := make([]float64, 0, iterations)
+	for i := 0; i < iterations; i++ {
+		run := s
+		run.factors = parameterFactors{
+			density: sampleFactor(density),
+			biomass: sampleFactor(biomass),
+			ratio:   sampleFactor(ratio),
+		}
+		if height != nil {
+			run.Zones = sampleHeights(s.Zones, height)
+		}
+		result, err := run.Calculate()
+		if errors.Is(err, ErrZeroCarbon) {
+			// The factors truncated to 0 may leave no carbon in the stage
+			samples = append(samples, 0)
+			continue
+		}
+		if err != nil {
+			return MonteCarloResult{}, fmt.Errorf("Monte Carlo iteration %d: %w", i, err)
+		}
+		samples = append(samples, result.TotalCarbon.InexactFloat64())
+	}
+	sort.Float64s(samples)
+
+	mean, stdDev := stat.MeanStdDev(samples, nil)
+	if mean == 0 {
+		return MonteCarloResult{}, ErrZeroCarbon
+	}
+	result := MonteCarloResult{
+		Iterations: iterations,
+		Confidence: m.confidence(),
+		Nominal:    nominal.TotalCarbon,
+		Mean:       decimal.NewFromFloat(mean),
+		StdDev:     decimal.NewFromFloat(stdDev),
+		Samples:    samples,
+	}
+	result.P5 = result.Percentile(0.05)
+	result.P50 = result.Percentile(0.5)
+	result.P95 = result.Percentile(0.95)
+	result.Lower = result.Percentile((1 - result.Confidence) / 2)
+	result.Upper = result.Percentile((1 + result.Confidence) / 2)
+	result.Uncertainty = result.Upper.Sub(result.Lower).Div(decimal.New(2, 0)).Div(result.Mean.Abs())
+	result.UncertaintyDiscount = scheduleDiscount(s.discount(), result.Uncertainty)
+	result.ConservativeCarbon = conservativeTotalCarbon(nil, result.Nominal, result.Uncertainty, s.discount())
+	return result, nil
+}
+
+// Copy of the zones with the height of every tree multiplied by the sampled
+// factor
+func sampleHeights(zones []MonitoringZone, height distuv.Rander) []MonitoringZone {
+	sampled := make([]MonitoringZone, len(zones))
+	for i, zone := range zones {
+		zone.Plots = append([]Plot(nil), zone.Plots...)
+		for j := range zone.Plots {
+			trees := make([]Tree, len(zone.Plots[j].Trees))
+			for k, tree := range zone.Plots[j].Trees {
+				tree.Height = tree.Height.Mul(*sampleFactor(height))
+				trees[k] = tree
+			}
+			zone.Plots[j].Trees = trees
+		}
+		sampled[i] = zone
+	}
+	return sampled
+}
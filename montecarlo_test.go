@@ -0,0 +1,147 @@
+package carbon_calc
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMonteCarloFixedParameters(t *testing.T) {
+	result, err := MonteCarlo{Iterations: 10}.Run(testStage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Mean.Round(9).Equal(result.Nominal.Round(9)) || !result.Uncertainty.Round(9).Equal(decimal.Zero) {
+		t.Fatalf("Fixed parameters should give nominal carbon, have: %s, %s", result.Mean, result.Uncertainty)
+	}
+	if result.Iterations != 10 || len(result.Samples) != 10 {
+		t.Fatalf("expect: 10 iterations, have: %d", result.Iterations)
+	}
+}
+
+func TestMonteCarloDensity(t *testing.T) {
+	// Tree carbon is linear in the density, so the uncertainty of the total
+	// carbon is the uncertainty of the density factor, 1.645 * 0.05
+	stage := testStage()
+	mc := MonteCarlo{
+		Iterations: 2000,
+		Seed:       1,
+		Density:    &Distribution{Type: DistributionNormal, StdDev: 0.05},
+	}
+	result, err := mc.Run(stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := math.Abs(result.Mean.Div(result.Nominal).InexactFloat64() - 1); diff > 0.01 {
+		t.Fatalf("Mean should be close to nominal, have: %s, %s", result.Mean, result.Nominal)
+	}
+	if diff := math.Abs(result.Uncertainty.InexactFloat64() - 1.645*0.05); diff > 0.005 {
+		t.Fatalf("expect: %f, have: %s", 1.645*0.05, result.Uncertainty)
+	}
+	if !result.P5.LessThan(result.P50) || !result.P50.LessThan(result.P95) {
+		t.Fatalf("Wrong percentiles: %s, %s, %s", result.P5, result.P50, result.P95)
+	}
+	if !result.UncertaintyDiscount.Equal(UncertaintyDiscount(result.Uncertainty)) {
+		t.Fatalf("Wrong uncertainty discount: %s", result.UncertaintyDiscount)
+	}
+
+	again, err := mc.Run(stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.Mean.Equal(result.Mean) || !again.P95.Equal(result.P95) {
+		t.Fatalf("Same seed should give the same result")
+	}
+	mc.Seed = 2
+	other, err := mc.Run(stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Mean.Equal(result.Mean) {
+		t.Fatalf("Different seed should give different result")
+	}
+}
+
+func TestMonteCarloTruncatedFactor(t *testing.T) {
+	// A quarter of the density factors are negative and truncated to 0, the
+	// mean factor is 1.5^2 / 2 / 2 = 0.5625
+	mc := MonteCarlo{
+		Iterations: 1000,
+		Seed:       1,
+		Density:    &Distribution{Type: DistributionUniform, Min: -0.5, Max: 1.5},
+	}
+	result, err := mc.Run(testStage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.P5.IsZero() {
+		t.Fatalf("Truncated factor should give zero carbon, have: %s", result.P5)
+	}
+	if diff := math.Abs(result.Mean.Div(result.Nominal).InexactFloat64() - 0.5625); diff > 0.03 {
+		t.Fatalf("expect: %f, have: %s", 0.5625, result.Mean.Div(result.Nominal))
+	}
+	zero := decimal.Zero
+	density, biomass, ratio := parameterFactors{density: &zero}.apply(decimal.New(1, 0), decimal.New(2, 0), decimal.New(3, 0))
+	if !density.IsZero() || !biomass.Equal(decimal.New(2, 0)) || !ratio.Equal(decimal.New(3, 0)) {
+		t.Fatalf("expect: 0 2 3, have: %s %s %s", density, biomass, ratio)
+	}
+}
+
+func TestMonteCarloDistributions(t *testing.T) {
+	type Test struct {
+		distribution Distribution
+		min, max     float64
+	}
+	tests := []Test{
+		{Distribution{Type: DistributionUniform, Min: 0.9, Max: 1.1}, 0.9, 1.1},
+		{Distribution{Type: DistributionTriangular, Min: 0.8, Mode: 1, Max: 1.1}, 0.8, 1.1},
+		{Distribution{Type: DistributionLogNormal, StdDev: 0.1}, 0, math.Inf(1)},
+	}
+	for i, tt := range tests {
+		mc := MonteCarlo{Iterations: 200, Seed: 3, Height: &tt.distribution}
+		result, err := mc.Run(testStage())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Uncertainty.IsZero() {
+			t.Fatalf("Test number %d, sampled height should give uncertainty", i)
+		}
+		r := tt.distribution.rander(nil)
+		for j := 0; j < 100; j++ {
+			if value := r.Rand(); value < tt.min || value > tt.max {
+				t.Fatalf("Test number %d, %f out of [%f, %f]", i, value, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestMonteCarloValidate(t *testing.T) {
+	mc := MonteCarlo{
+		Iterations: 1,
+		Density:    &Distribution{Type: DistributionNormal, StdDev: -0.1},
+		Height:     &Distribution{Type: DistributionTriangular, Min: 1.1, Mode: 1, Max: 0.9},
+	}
+	_, err := mc.Run(testStage())
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expect 3 ValidationErrors, have: %v", err)
+	}
+	fields := []string{"iterations", "density stdDev", "height"}
+	for i, field := range fields {
+		if errs[i].Field != field {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, field, errs[i].Field)
+		}
+	}
+}
+
+func TestDistributionTypeText(t *testing.T) {
+	var d DistributionType
+	if err := d.UnmarshalText([]byte("triangular")); err != nil || d != DistributionTriangular {
+		t.Fatalf("expect: %s, have: %s, %v", DistributionTriangular, d, err)
+	}
+	if err := d.UnmarshalText([]byte("beta")); err == nil {
+		t.Fatalf("Unknown distribution should return error")
+	}
+}
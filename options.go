@@ -0,0 +1,22 @@
+package carbon_calc
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Defaults substituted for the options that were not provided, by the name of
+// the option
+type Defaults map[string]decimal.Decimal
+
+// Pointer to the value of the option, zero is a legal value
+func Optional(value decimal.Decimal) *decimal.Decimal {
+	return &value
+}
+
+// Option of the zero-means-default API, nil if value is zero
+func zeroDefault(value decimal.Decimal) *decimal.Decimal {
+	if value.IsZero() {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,135 @@
+package carbon_calc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCarbonPerTreeWith(t *testing.T) {
+	options := CarbonPerTreeOptions{
+		Radius:  decimal.NewFromFloat(0.05),
+		Height:  decimal.New(5, 0),
+		Density: decimal.NewFromFloat(0.55),
+		Biomass: decimal.NewFromFloat(1.15),
+		Ratio:   decimal.NewFromFloat(0.3),
+	}
+	result, defaults := CarbonPerTreeWith(options)
+	expect := CarbonPerTree(decimal.Zero, options.Radius, options.Height, decimal.Zero, options.Density, options.Biomass, options.Ratio)
+	if !result.Equal(expect) {
+		t.Fatalf("expect: %s, have: %s", expect, result)
+	}
+	if len(defaults) != 2 || !defaults["fraction"].Equal(decimal.NewFromFloat(0.47)) || !defaults["form"].Equal(decimal.NewFromFloat(0.25)) {
+		t.Fatalf("Wrong defaults: %v", defaults)
+	}
+
+	options.Form = Optional(decimal.Zero)
+	options.Fraction = Optional(decimal.NewFromFloat(0.5))
+	result, defaults = CarbonPerTreeWith(options)
+	if !result.IsZero() || len(defaults) != 0 {
+		t.Fatalf("expect: 0 without defaults, have: %s, %v", result, defaults)
+	}
+}
+
+func TestAboveGroundBiomassWith(t *testing.T) {
+	options := AboveGroundBiomassOptions{
+		AreaConsCarbon: decimal.New(100, 0),
+		Ratio:          decimal.NewFromFloat(0.25),
+		Area:           decimal.New(2, 0),
+	}
+	result, defaults := AboveGroundBiomassWith(options)
+	expect := AboveGroundBiomass(options.AreaConsCarbon, options.Ratio, decimal.Zero, options.Area)
+	if !result.Equal(expect) || len(defaults) != 1 || !defaults["cfTree"].Equal(decimal.NewFromFloat(0.47)) {
+		t.Fatalf("expect: %s, have: %s, %v", expect, result, defaults)
+	}
+}
+
+func TestFertilizerWith(t *testing.T) {
+	inputs := FertilizerInputs{MassSynthFertz: decimal.New(100, 0), MassOrgFertz: decimal.New(50, 0)}
+	direct, defaults := CO2eNdirecttWith(CO2eNdirecttOptions{FertilizerInputs: inputs})
+	expect := CO2eNdirectt(inputs.MassSynthFertz, decimal.Zero, inputs.MassOrgFertz, decimal.Zero, decimal.Zero, decimal.Zero)
+	if !direct.Equal(expect) || len(defaults) != 4 || !defaults["gWarmingPotentl"].Equal(decimal.New(265, 0)) {
+		t.Fatalf("expect: %s, have: %s, %v", expect, direct, defaults)
+	}
+
+	volat, defaults := NfertVolatITWith(NfertVolatITOptions{FertilizerInputs: inputs, AllFractOrg: Optional(decimal.Zero)})
+	// Only the synthetic fertilizer volatilises
+	expect = NfertVolatIT(inputs.MassSynthFertz, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero)
+	if !volat.Equal(expect) {
+		t.Fatalf("expect: %s, have: %s", expect, volat)
+	}
+	if _, ok := defaults["allFractOrg"]; ok {
+		t.Fatalf("allFractOrg must not be defaulted: %v", defaults)
+	}
+
+	leach, defaults := NfertLeachITWith(NfertLeachITOptions{FertilizerInputs: inputs, NFractSoil: Optional(decimal.Zero)})
+	if !leach.IsZero() || !defaults["nitrOxdEmissLR"].Equal(decimal.NewFromFloat(0.0075)) {
+		t.Fatalf("expect: 0, have: %s, %v", leach, defaults)
+	}
+
+	inputs.GWP = GWPAR6
+	leach, defaults = NfertLeachITWith(NfertLeachITOptions{FertilizerInputs: inputs})
+	if leach.IsZero() || !defaults["gWarmingPotentl"].Equal(decimal.New(273, 0)) {
+		t.Fatalf("expect AR6 default, have: %s, %v", leach, defaults)
+	}
+}
+
+func TestOCCShareWith(t *testing.T) {
+	minted := decimal.New(100, 0)
+	bufferPool, defaults := OCCBufferPoolWith(OCCShareOptions{MintedOCC: minted})
+	if !bufferPool.Equal(OCCBufferPool(minted, 0)) || !defaults["percent"].Equal(decimal.NewFromFloat(0.07)) {
+		t.Fatalf("expect: %s, have: %s, %v", OCCBufferPool(minted, 0), bufferPool, defaults)
+	}
+	bufferPool, defaults = OCCBufferPoolWith(OCCShareOptions{MintedOCC: minted, Percent: Optional(decimal.Zero)})
+	if !bufferPool.IsZero() || len(defaults) != 0 {
+		t.Fatalf("expect: 0, have: %s, %v", bufferPool, defaults)
+	}
+	holders, defaults := OCCHoldersWith(OCCShareOptions{MintedOCC: minted, Percent: Optional(decimal.NewFromFloat(0.1))})
+	if !holders.Equal(decimal.New(10, 0)) || len(defaults) != 0 {
+		t.Fatalf("expect: 10, have: %s, %v", holders, defaults)
+	}
+}
+
+func TestStageDefaults(t *testing.T) {
+	stage := testStage()
+	stage.Fertilizers = []Fertilizer{{
+		Applications:   decimal.New(1, 0),
+		MassSynthFertz: decimal.New(100, 0),
+		NFractSoil:     Optional(decimal.Zero),
+	}}
+	stage.BufferPercent = Optional(decimal.Zero)
+	stage.Zones[0].Plots[0].Trees[0].Fraction = nil
+	stage.Zones[0].Plots[0].Trees[1].Form = Optional(decimal.Zero)
+	stage.Zones[0].Plots[2].Trees[2].Fraction = nil
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.BufferPool.IsZero() || !result.Holders.Equal(result.MintedOCC.Mul(decimal.NewFromFloat(0.08))) {
+		t.Fatalf("expect: 0, have: %s, %s", result.BufferPool, result.Holders)
+	}
+	if _, ok := result.Defaults["bufferPercent"]; ok || !result.Defaults["holdersPercent"].Equal(decimal.NewFromFloat(0.08)) {
+		t.Fatalf("expect holdersPercent default only, have: %v", result.Defaults)
+	}
+	if _, ok := result.Defaults["fertilizers[0].nFractSoil"]; ok || !result.Defaults["fertilizers[0].nitrOxdEmissLR"].Equal(decimal.NewFromFloat(0.0075)) {
+		t.Fatalf("expect nitrOxdEmissLR default only, have: %v", result.Defaults)
+	}
+	// Zero form is used as is, excluded tree has no defaults
+	trees := 0
+	for name := range result.Defaults {
+		if strings.HasPrefix(name, "zones[") {
+			trees++
+		}
+	}
+	if !result.Defaults["zones[0].plots[0].trees[0].fraction"].Equal(decimal.NewFromFloat(0.47)) || trees != 1 ||
+		!result.Zones[0].Plots[0].Trees[1].Carbon.IsZero() {
+		t.Fatalf("expect fraction default of tree-1 and zero carbon of tree-2, have: %v, %s", result.Defaults, result.Zones[0].Plots[0].Trees[1].Carbon)
+	}
+	// Zero leaching is used as is
+	leach := stage.Fertilizers[0]
+	leach.NFractSoil = nil
+	if result.Emissions.GreaterThanOrEqual(leach.EmissionsGWP(stage.GWP)) {
+		t.Fatalf("expect emissions without leaching below: %s, have: %s", leach.EmissionsGWP(stage.GWP), result.Emissions)
+	}
+}
@@ -0,0 +1,295 @@
+package carbon_calc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrParameterSetVersion = errors.New("Parameter set should have a version.")
+
+// Versioned set of forest type / species specific parameters used in the
+// carbon calculation, so the projects can use different parameter vintages
+// version - version of the parameter set, recorded in the stage result
+// source - citation of the source of values, e.g. IPCC 2006 Table 4.4
+type ParameterSet struct {
+	Version          string                     `json:"version" yaml:"version"`
+	Source           string                     `json:"source" yaml:"source"`
+	Density          DensityParameters          `json:"density" yaml:"density"`
+	BiomassExpansion BiomassExpansionParameters `json:"biomassExpansion" yaml:"biomassExpansion"`
+	RootShoot        RootShootParameters        `json:"rootShoot" yaml:"rootShoot"`
+}
+
+// Density (over-bark) of trees, values by rainfall are used for the forest
+// types present in byRainfall, otherwise values by species
+type DensityParameters struct {
+	Default    float64                                 `json:"default" yaml:"default"`
+	ByRainfall map[ForestType]map[RainfallType]float64 `json:"byRainfall,omitempty" yaml:"byRainfall,omitempty"`
+	BySpecies  map[ForestType]map[TreeSpecies]float64  `json:"bySpecies,omitempty" yaml:"bySpecies,omitempty"`
+}
+
+// Biomass expansion factor for conversion of tree stem biomass to
+// above-ground tree biomass
+type BiomassExpansionParameters struct {
+	Default   float64                                `json:"default" yaml:"default"`
+	BySpecies map[ForestType]map[TreeSpecies]float64 `json:"bySpecies,omitempty" yaml:"bySpecies,omitempty"`
+}
+
+// Root-shoot ratio classes, values by rainfall are used for the forest types
+// present in byRainfall, then values by forest type, otherwise values by species
+// byEcologicalZone - classes by IPCC global ecological zone, see
+// RootShootRatioForZone
+type RootShootParameters struct {
+	Default          float64                                          `json:"default" yaml:"default"`
+	ByRainfall       map[ForestType]map[RainfallType]RootShootClasses `json:"byRainfall,omitempty" yaml:"byRainfall,omitempty"`
+	ByForestType     map[ForestType]RootShootClasses                  `json:"byForestType,omitempty" yaml:"byForestType,omitempty"`
+	BySpecies        map[ForestType]map[TreeSpecies]RootShootClasses  `json:"bySpecies,omitempty" yaml:"bySpecies,omitempty"`
+	ByEcologicalZone map[EcologicalZone]ZoneRootShootClasses          `json:"byEcologicalZone,omitempty" yaml:"byEcologicalZone,omitempty"`
+}
+
+// Root-shoot ratio classes of the ecological zone
+// all - classes for the species not present in bySpecies
+type ZoneRootShootClasses struct {
+	All       RootShootClasses                 `json:"all,omitempty" yaml:"all,omitempty"`
+	BySpecies map[TreeSpecies]RootShootClasses `json:"bySpecies,omitempty" yaml:"bySpecies,omitempty"`
+}
+
+// Root-shoot ratio for above-ground biomass up to maxBiomass (t/ha, inclusive),
+// nil maxBiomass for the last unbounded class
+type RootShootClass struct {
+	MaxBiomass *float64 `json:"maxBiomass,omitempty" yaml:"maxBiomass,omitempty"`
+	Ratio      float64  `json:"ratio" yaml:"ratio"`
+}
+
+// Root-shoot ratio classes ordered by above-ground biomass, the first class is
+// the default value
+type RootShootClasses []RootShootClass
+
+// Root-shoot ratio of the class the above-ground biomass belongs to
+func (c RootShootClasses) Ratio(abovegroundBiomass float64) (float64, bool) {
+	for _, class := range c {
+		if class.MaxBiomass == nil || abovegroundBiomass <= *class.MaxBiomass {
+			return class.Ratio, true
+		}
+	}
+	return 0, false
+}
+
+// Root-shoot ratio of the class the above-ground biomass belongs to, the
+// default root-shoot ratio if there is no such class
+func (c RootShootClasses) value(abovegroundBiomass float64) float64 {
+	value, ok := c.Ratio(abovegroundBiomass)
+	if !ok {
+		return RootShootRatioDefault
+	}
+	return value
+}
+
+func (c RootShootClasses) clone() RootShootClasses {
+	return append(RootShootClasses(nil), c...)
+}
+
+func maxBiomass(v float64) *float64 {
+	return &v
+}
+
+func mapValues[K1, K2 comparable, V, W any](values map[K1]map[K2]V, f func(V) W) map[K1]map[K2]W {
+	result := make(map[K1]map[K2]W, len(values))
+	for k1, inner := range values {
+		result[k1] = make(map[K2]W, len(inner))
+		for k2, v := range inner {
+			result[k1][k2] = f(v)
+		}
+	}
+	return result
+}
+
+func sameValue[V any](v V) V {
+	return v
+}
+
+var defaultParameterSet = DefaultParameterSet()
+
+// IPCC 2006 Table 4.4 root-shoot ratios of the ecological zone
+func ipccZoneRootShoot() map[EcologicalZone]ZoneRootShootClasses {
+	rainForest := ZoneRootShootClasses{All: rootShootTropicalWet.clone()}
+	moist := ZoneRootShootClasses{All: rootShootTropicalMoist.clone()}
+	dry := ZoneRootShootClasses{All: rootShootTropicalDry.clone()}
+	conifers := rootShootConiferous.clone()
+	// Other broadleaf classes are used for all temperate species except
+	// conifers, oak and eucalyptus
+	temperate := ZoneRootShootClasses{
+		All: rootShootBroadleaf.clone(),
+		BySpecies: map[TreeSpecies]RootShootClasses{
+			TreeSpeciesConiferous: conifers,
+			TreeSpeciesPines:      conifers,
+			// Table 4.4 gives value for oak forest > 70 t/ha only, other
+			// broadleaf value is used below
+			TreeSpeciesOak:        {{MaxBiomass: maxBiomass(70), Ratio: 0.46}, {Ratio: 0.3}},
+			TreeSpeciesEucalyptus: {{MaxBiomass: maxBiomass(50), Ratio: 0.44}, {MaxBiomass: maxBiomass(150), Ratio: 0.28}, {Ratio: 0.2}},
+		},
+	}
+	boreal := ZoneRootShootClasses{All: rootShootBoreal.clone()}
+	return map[EcologicalZone]ZoneRootShootClasses{
+		EcologicalZoneTropicalRainForest:           rainForest,
+		EcologicalZoneTropicalMoistDeciduousForest: moist,
+		EcologicalZoneTropicalDryForest:            dry,
+		EcologicalZoneTropicalShrubland:            {All: RootShootClasses{{Ratio: 0.4}}},
+		EcologicalZoneTropicalMountainSystems:      {All: RootShootClasses{{Ratio: 0.27}}},
+		EcologicalZoneSubtropicalHumidForest:       moist,
+		EcologicalZoneSubtropicalDryForest:         dry,
+		EcologicalZoneSubtropicalSteppe:            {All: RootShootClasses{{Ratio: 0.32}}},
+		// No estimate available in Table 4.4, tropical mountain systems value
+		// is used
+		EcologicalZoneSubtropicalMountainSystems: {All: RootShootClasses{{Ratio: 0.27}}},
+		EcologicalZoneTemperateOceanicForest:     temperate,
+		EcologicalZoneTemperateContinentalForest: temperate,
+		EcologicalZoneTemperateMountainSystems:   temperate,
+		EcologicalZoneBorealConiferousForest:     boreal,
+		EcologicalZoneBorealTundraWoodland:       boreal,
+		EcologicalZoneBorealMountainSystems:      boreal,
+	}
+}
+
+// Parameter set with the values of DensityOverBarkOfTrees,
+// BiomassExpansionFactor and RootShootRatioForTree, built from the tables of
+// these functions
+func DefaultParameterSet() *ParameterSet {
+	return &ParameterSet{
+		Version: "ipcc-2006",
+		Source:  "IPCC 2006 Guidelines for National Greenhouse Gas Inventories, Volume 4, Chapter 4, Tables 4.4, 4.5 and 4.14",
+		Density: DensityParameters{
+			Default:    DensityOverBarkOfTreesDefault,
+			ByRainfall: mapValues(DensityOverBarkOfTreesRainfall, sameValue[float64]),
+			BySpecies:  mapValues(DensityOverBarkOfTreesDict, sameValue[float64]),
+		},
+		BiomassExpansion: BiomassExpansionParameters{
+			Default:   BiomassExpansionFactorDefault,
+			BySpecies: mapValues(BiomassExpansionFactorDict, sameValue[float64]),
+		},
+		RootShoot: RootShootParameters{
+			Default:    RootShootRatioDefault,
+			ByRainfall: mapValues(rootShootRainfallClasses, RootShootClasses.clone),
+			ByForestType: map[ForestType]RootShootClasses{
+				ForestTypeBoreal: rootShootBoreal.clone(),
+			},
+			BySpecies:        mapValues(rootShootSpeciesClasses, RootShootClasses.clone),
+			ByEcologicalZone: ipccZoneRootShoot(),
+		},
+	}
+}
+
+// Density (over-bark) of tree depending on its tree species / forest type
+func (p *ParameterSet) DensityOverBarkOfTrees(forestType ForestType, specie TreeSpecies, rainfall RainfallType) decimal.Decimal {
+	if values, ok := p.Density.ByRainfall[forestType]; ok {
+		value, ok := values[rainfall]
+		if !ok {
+			return decimal.NewFromFloat(p.Density.Default)
+		}
+		return decimal.NewFromFloat(value)
+	}
+	value, ok := p.Density.BySpecies[forestType][specie]
+	if !ok {
+		return decimal.NewFromFloat(p.Density.Default)
+	}
+	return decimal.NewFromFloat(value)
+}
+
+// Biomass expansion factor depending on tree species / forest type
+func (p *ParameterSet) BiomassExpansionFactor(forestType ForestType, specie TreeSpecies) decimal.Decimal {
+	value, ok := p.BiomassExpansion.BySpecies[forestType][specie]
+	if !ok {
+		return decimal.NewFromFloat(p.BiomassExpansion.Default)
+	}
+	return decimal.NewFromFloat(value)
+}
+
+// Root-shoot ratio depending on tree species / forest type
+// abovegroundBiomass - 0 if you want to get default value
+func (p *ParameterSet) RootShootRatioForTree(forestType ForestType, species TreeSpecies, rainfall RainfallType, abovegroundBiomass float64) decimal.Decimal {
+	var classes RootShootClasses
+	if values, ok := p.RootShoot.ByRainfall[forestType]; ok {
+		classes = values[rainfall]
+	} else if values, ok := p.RootShoot.ByForestType[forestType]; ok {
+		classes = values
+	} else {
+		classes = p.RootShoot.BySpecies[forestType][species]
+	}
+	value, ok := classes.Ratio(abovegroundBiomass)
+	if !ok {
+		return decimal.NewFromFloat(p.RootShoot.Default)
+	}
+	return decimal.NewFromFloat(value)
+}
+
+// Root-shoot ratio depending on the IPCC global ecological zone, tree species
+// and above-ground biomass class (IPCC 2006 Table 4.4)
+// abovegroundBiomass - 0 if you want to get default value
+func (p *ParameterSet) RootShootRatioForZone(zone EcologicalZone, species TreeSpecies, abovegroundBiomass float64) decimal.Decimal {
+	classes, ok := p.RootShoot.ByEcologicalZone[zone].BySpecies[species]
+	if !ok {
+		classes = p.RootShoot.ByEcologicalZone[zone].All
+	}
+	value, ok := classes.Ratio(abovegroundBiomass)
+	if !ok {
+		return decimal.NewFromFloat(p.RootShoot.Default)
+	}
+	return decimal.NewFromFloat(value)
+}
+
+// Check the parameter set, returns ValidationErrors with all problems found
+func (p *ParameterSet) Validate() error {
+	v := &validator{}
+	v.validateParameterSet("", p)
+	return v.errs.err()
+}
+
+func (v *validator) validateParameterSet(prefix string, p *ParameterSet) {
+	v.check(p.Version != "", prefix+"version", p.Version, RuleRequired, ErrParameterSetVersion)
+}
+
+// Read the parameter set in JSON format
+func ReadParameterSetJSON(r io.Reader) (*ParameterSet, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	p := &ParameterSet{}
+	if err := decoder.Decode(p); err != nil {
+		return nil, err
+	}
+	return p, p.Validate()
+}
+
+// Read the parameter set in YAML format
+func ReadParameterSetYAML(r io.Reader) (*ParameterSet, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	p := &ParameterSet{}
+	if err := decoder.Decode(p); err != nil {
+		return nil, err
+	}
+	return p, p.Validate()
+}
+
+// Load the parameter set from the JSON (.json) or YAML (.yaml, .yml) file
+func LoadParameterSet(path string) (*ParameterSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ReadParameterSetJSON(bytes.NewReader(data))
+	case ".yaml", ".yml":
+		return ReadParameterSetYAML(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("Unknown parameter set format %q.", filepath.Ext(path))
+	}
+}
@@ -0,0 +1,172 @@
+package carbon_calc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	testForestTypes = []ForestType{ForestTypeTropicalSubtropical, ForestTypeTemperate, ForestTypeBoreal}
+	testSpecies     = []TreeSpecies{TreeSpeciesConiferous, TreeSpeciesBroadleaf, TreeSpeciesForestTundra, TreeSpeciesMixedConiferousAndBroadleaf, TreeSpeciesPines}
+	testRainfall    = []RainfallType{RainfallTypeDry, RainfallTypeMoist, RainfallTypeWet}
+	testBiomass     = []float64{0, 10, 20, 21, 50, 60, 75, 80, 125, 130, 150, 200}
+)
+
+func TestDefaultParameterSet(t *testing.T) {
+	ps := DefaultParameterSet()
+	for _, forestType := range testForestTypes {
+		for _, species := range testSpecies {
+			if have, expect := ps.BiomassExpansionFactor(forestType, species), BiomassExpansionFactor(forestType, species); !have.Equal(expect) {
+				t.Fatalf("%s %s, expect: %s, have: %s", forestType, species, expect, have)
+			}
+			for _, rainfall := range testRainfall {
+				if have, expect := ps.DensityOverBarkOfTrees(forestType, species, rainfall), DensityOverBarkOfTrees(forestType, species, rainfall); !have.Equal(expect) {
+					t.Fatalf("%s %s %s, expect: %s, have: %s", forestType, species, rainfall, expect, have)
+				}
+				for _, biomass := range testBiomass {
+					have := ps.RootShootRatioForTree(forestType, species, rainfall, biomass)
+					expect := RootShootRatioForTree(forestType, species, rainfall, biomass)
+					if !have.Equal(expect) {
+						t.Fatalf("%s %s %s %f, expect: %s, have: %s", forestType, species, rainfall, biomass, expect, have)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDefaultParameterSetFromTables(t *testing.T) {
+	species := DensityOverBarkOfTreesDict[ForestTypeTemperate]
+	value := species[TreeSpeciesConiferous]
+	defer func() { species[TreeSpeciesConiferous] = value }()
+	species[TreeSpeciesConiferous] = 0.5
+	ps := DefaultParameterSet()
+	have := ps.DensityOverBarkOfTrees(ForestTypeTemperate, TreeSpeciesConiferous, RainfallTypeDry)
+	expect := DensityOverBarkOfTrees(ForestTypeTemperate, TreeSpeciesConiferous, RainfallTypeDry)
+	if !have.Equal(expect) || have.InexactFloat64() != 0.5 {
+		t.Fatalf("expect: %s, have: %s", expect, have)
+	}
+	// the parameter set does not share the tables
+	species[TreeSpeciesConiferous] = 0.6
+	if have := ps.DensityOverBarkOfTrees(ForestTypeTemperate, TreeSpeciesConiferous, RainfallTypeDry); have.InexactFloat64() != 0.5 {
+		t.Fatalf("expect: 0.5, have: %s", have)
+	}
+}
+
+func TestReadParameterSet(t *testing.T) {
+	expect := DefaultParameterSet()
+	data, err := json.Marshal(expect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := ReadParameterSetJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = yaml.Marshal(expect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err := ReadParameterSetYAML(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ps := range []*ParameterSet{fromJSON, fromYAML} {
+		if ps.Version != expect.Version || ps.Source != expect.Source {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, expect.Version, ps.Version)
+		}
+		for _, forestType := range testForestTypes {
+			for _, species := range testSpecies {
+				for _, rainfall := range testRainfall {
+					for _, biomass := range testBiomass {
+						have := ps.RootShootRatioForTree(forestType, species, rainfall, biomass)
+						if value := expect.RootShootRatioForTree(forestType, species, rainfall, biomass); !have.Equal(value) {
+							t.Fatalf("Test number %d, expect: %s, have: %s", i, value, have)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLoadParameterSet(t *testing.T) {
+	type Test struct {
+		name, content string
+		density       float64
+		err           bool
+	}
+	tests := []Test{
+		{"params.json", `{"version": "custom-1", "density": {"default": 0.6, "bySpecies": {"temperate": {"coniferous": 0.41}}}}`, 0.41, false},
+		{"params.yaml", "version: custom-2\ndensity:\n  default: 0.6\n  bySpecies:\n    temperate:\n      coniferous: 0.42\n", 0.42, false},
+		{"params.yml", "density:\n  default: 0.6\n", 0, true},
+		{"params.json", `{"version": "custom-3", "density": {"bySpecies": {"unknown": {"coniferous": 0.41}}}}`, 0, true},
+		{"params.txt", `version: custom-4`, 0, true},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		ps, err := LoadParameterSet(path)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("Test number %d, expect error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test number %d, %v", i, err)
+		}
+		density := ps.DensityOverBarkOfTrees(ForestTypeTemperate, TreeSpeciesConiferous, RainfallTypeDry)
+		if density.InexactFloat64() != tt.density {
+			t.Fatalf("Test number %d, expect: %f, have: %f", i, tt.density, density.InexactFloat64())
+		}
+	}
+}
+
+func TestStageParameterSet(t *testing.T) {
+	stage := testStage()
+	stage.Zones[0].ForestType = ForestTypeTemperate
+	stage.Zones[0].Plots[0].Trees[0].Density = nil
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ParameterSet != DefaultParameterSet().Version {
+		t.Fatalf("expect: %s, have: %s", DefaultParameterSet().Version, result.ParameterSet)
+	}
+
+	ps, err := ReadParameterSetJSON(strings.NewReader(`{"version": "custom", "density": {"default": 0.9}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage.Parameters = ps
+	custom, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if custom.ParameterSet != "custom" {
+		t.Fatalf("expect: custom, have: %s", custom.ParameterSet)
+	}
+	expect := result.Zones[0].Plots[0].Trees[0].Carbon.Mul(decimal.NewFromFloat(0.9)).Div(decimal.NewFromFloat(0.45))
+	have := custom.Zones[0].Plots[0].Trees[0].Carbon
+	if have.Sub(expect).Abs().GreaterThan(decimal.NewFromFloat(0.000001)) {
+		t.Fatalf("expect: %s, have: %s", expect, have)
+	}
+
+	ps.Version = ""
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "parameters version" || !errors.Is(err, ErrParameterSetVersion) {
+		t.Fatalf("expect: %v, have: %v", ErrParameterSetVersion, err)
+	}
+}
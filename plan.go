@@ -0,0 +1,231 @@
+package carbon_calc
+
+import (
+	"math"
+
+	"github.com/shopspring/decimal"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Rules violated by the sampling planner inputs
+const (
+	RulePilot = "must contain at least two pilot plots or prior mean and cv"
+)
+
+// Monitoring zone to plan the sampling for
+// area, plots - area of the zone (ha) and carbon of the pilot plots
+// (t CO2-e/ha), plots may be empty if mean and cv are set
+// mean - prior mean carbon stock (t CO2-e/ha), 0 if you want to take it from
+// the pilot plots
+// cv - prior coefficient of variation of the plot carbon, 0 if you want to take
+// it from the pilot plots
+type PlanZone struct {
+	ID string `json:"id"`
+	CarbonedZone
+	Mean decimal.Decimal `json:"mean"`
+	CV   decimal.Decimal `json:"cv"`
+}
+
+// mean and standard deviation of the plot carbon
+func (z PlanZone) moments() (float64, float64) {
+	mean := z.Mean.InexactFloat64()
+	if z.Mean.IsZero() && len(z.Plots) > 0 {
+		mean = SumDecimal(z.Plots).Div(decimal.NewFromInt(int64(len(z.Plots)))).InexactFloat64()
+	}
+	if !z.CV.IsZero() {
+		return mean, z.CV.InexactFloat64() * mean
+	}
+	return mean, math.Sqrt(VarianceOfTreeBiomass(z.Plots).InexactFloat64())
+}
+
+// Sample size calculation of CDM AR-TOOL03 with equal cost of the plots
+// precision - target half-width of the confidence interval as a fraction of
+// the mean, 0 if you want to get default value 0.1 that avoids any
+// UncertaintyDiscount
+// plotArea - area of the sample plot (ha) for the finite population
+// correction, 0 if the population is considered infinite
+// minPlots - minimum number of plots in each zone, at least 2 needed by
+// VarianceOfTreeBiomass, 0 if you want to get default value 2
+// confidence - two-sided confidence level, 0 if you want to get default value
+// 0.9 used by TDistribution
+type SamplingPlanner struct {
+	Precision  decimal.Decimal `json:"precision"`
+	Confidence float64         `json:"confidence"`
+	PlotArea   decimal.Decimal `json:"plotArea"`
+	MinPlots   int             `json:"minPlots"`
+}
+
+// Number of plots of the zone
+type ZoneAllocation struct {
+	ID    string `json:"id"`
+	Plots int    `json:"plots"`
+}
+
+// Allocation of the plots across the zones
+// plots - total number of plots, sum of the plots of the zones
+// tDistribution - t-value of the converged number of plots
+type PlotAllocation struct {
+	Plots         int              `json:"plots"`
+	TDistribution decimal.Decimal  `json:"tDistribution"`
+	Zones         []ZoneAllocation `json:"zones"`
+}
+
+// mean - area weighted mean carbon stock (t CO2-e/ha)
+// proportional - plots allocated proportionally to the zone area
+// neyman - plots allocated proportionally to the zone area and standard
+// deviation (optimal allocation)
+type SamplingPlan struct {
+	Precision    decimal.Decimal `json:"precision"`
+	Mean         decimal.Decimal `json:"mean"`
+	Proportional PlotAllocation  `json:"proportional"`
+	Neyman       PlotAllocation  `json:"neyman"`
+}
+
+func (p SamplingPlanner) precision() float64 {
+	if p.Precision.IsZero() {
+		return 0.1
+	}
+	return p.Precision.InexactFloat64()
+}
+
+func (p SamplingPlanner) confidence() float64 {
+	if p.Confidence == 0 {
+		return defaultConfidence
+	}
+	return p.Confidence
+}
+
+func (p SamplingPlanner) minPlots() int {
+	if p.MinPlots == 0 {
+		return 2
+	}
+	return p.MinPlots
+}
+
+// Check the planner and the zones, returns ValidationErrors with all problems
+// found
+func (p SamplingPlanner) Validate(zones []PlanZone) error {
+	v := &validator{}
+	v.check(len(zones) > 0, "zones", len(zones), RuleRequired, ErrNoMonitoringZones)
+	v.check(!p.Precision.IsNegative() && p.Precision.LessThanOrEqual(decimal.New(1, 0)), "precision", p.Precision, RuleFraction, nil)
+	v.check(p.Confidence >= 0 && p.Confidence < 1, "confidence", p.Confidence, RuleFraction, nil)
+	v.nonNegative("plotArea", p.PlotArea)
+	v.check(p.MinPlots >= 0, "minPlots", p.MinPlots, RuleNonNegative, nil)
+	v.check(p.MinPlots != 1, "minPlots", p.MinPlots, RuleMinPlots, nil)
+	for _, zone := range zones {
+		v.zone = zone.ID
+		v.positive("area", zone.Area)
+		prior := zone.Mean.GreaterThan(decimal.Zero) && zone.CV.GreaterThan(decimal.Zero)
+		v.check(prior || len(zone.Plots) >= 2, "plots", len(zone.Plots), RulePilot, nil)
+		v.nonNegative("cv", zone.CV)
+	}
+	return v.errs.err()
+}
+
+// Calculate the number of sample plots needed to reach the target precision at
+// the confidence level and allocate them across the zones
+func (p SamplingPlanner) Plan(zones []PlanZone) (SamplingPlan, error) {
+	if err := p.Validate(zones); err != nil {
+		return SamplingPlan{}, err
+	}
+	area := 0.0
+	for _, zone := range zones {
+		area += zone.Area.InexactFloat64()
+	}
+	weights := make([]float64, len(zones))
+	deviations := make([]float64, len(zones))
+	mean := 0.0
+	for i, zone := range zones {
+		weights[i] = zone.Area.InexactFloat64() / area
+		zoneMean, deviation := zone.moments()
+		deviations[i] = deviation
+		mean += weights[i] * zoneMean
+	}
+	if mean == 0 {
+		return SamplingPlan{}, ErrZeroCarbon
+	}
+	// Number of sampling units in the population, 0 if infinite
+	population := 0.0
+	if !p.PlotArea.IsZero() {
+		population = area / p.PlotArea.InexactFloat64()
+	}
+	allowed := p.precision() * mean
+
+	sumWS, sumWS2 := 0.0, 0.0
+	for i := range zones {
+		sumWS += weights[i] * deviations[i]
+		sumWS2 += weights[i] * deviations[i] * deviations[i]
+	}
+	proportional := make([]float64, len(zones))
+	neyman := make([]float64, len(zones))
+	for i := range zones {
+		proportional[i] = weights[i]
+		if sumWS > 0 {
+			neyman[i] = weights[i] * deviations[i] / sumWS
+		} else {
+			neyman[i] = weights[i]
+		}
+	}
+	plan := SamplingPlan{
+		Precision: decimal.NewFromFloat(p.precision()),
+		Mean:      decimal.NewFromFloat(mean),
+	}
+	// n = t^2 * numerator / (E^2 + t^2 * sum(w * s^2) / N)
+	plan.Proportional = p.allocate(zones, proportional, sumWS2, sumWS2, allowed, population)
+	plan.Neyman = p.allocate(zones, neyman, sumWS*sumWS, sumWS2, allowed, population)
+	return plan, nil
+}
+
+// The number of plots needed depends on the t-value of the number of plots, so
+// the smallest total number of plots is searched that is enough at its own
+// t-value
+func (p SamplingPlanner) allocate(zones []PlanZone, shares []float64, numerator, sumWS2, allowed, population float64) PlotAllocation {
+	required := func(t float64) float64 {
+		denominator := allowed * allowed
+		if population > 0 {
+			denominator += t * t * sumWS2 / population
+		}
+		return t * t * numerator / denominator
+	}
+	// Normal quantile gives the lower bound of the number of plots, the
+	// t-value needs at least one degree of freedom
+	lower := p.allocation(zones, shares, required(distuv.UnitNormal.Quantile((1+p.confidence())/2)))
+	start := lower.Plots
+	if start < len(zones)+1 {
+		start = len(zones) + 1
+	}
+	for total := start; ; total++ {
+		t := tDistribution(nil, float64(total-len(zones)), p.confidence())
+		allocation := p.allocation(zones, shares, required(t.InexactFloat64()))
+		if allocation.Plots > total {
+			continue
+		}
+		// Fewer plots would need the larger t-value of their own
+		if allocation.Plots < total {
+			allocation = p.allocation(zones, shares, float64(total))
+		}
+		// Rounding up the zones may allocate more plots than the total, the
+		// t-value of their degrees of freedom is smaller, so the precision is
+		// still met
+		if allocation.Plots != total {
+			t = tDistribution(nil, float64(allocation.Plots-len(zones)), p.confidence())
+		}
+		allocation.TDistribution = t
+		return allocation
+	}
+}
+
+// Allocate n plots across the zones by shares, rounded up and at least
+// minPlots in each zone
+func (p SamplingPlanner) allocation(zones []PlanZone, shares []float64, n float64) PlotAllocation {
+	allocation := PlotAllocation{Zones: make([]ZoneAllocation, len(zones))}
+	for i, zone := range zones {
+		plots := int(math.Ceil(n * shares[i]))
+		if plots < p.minPlots() {
+			plots = p.minPlots()
+		}
+		allocation.Zones[i] = ZoneAllocation{ID: zone.ID, Plots: plots}
+		allocation.Plots += plots
+	}
+	return allocation
+}
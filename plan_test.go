@@ -0,0 +1,128 @@
+package carbon_calc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func testPlanZone(id string, area, mean, cv float64) PlanZone {
+	return PlanZone{
+		ID:           id,
+		CarbonedZone: CarbonedZone{Area: decimal.NewFromFloat(area)},
+		Mean:         decimal.NewFromFloat(mean),
+		CV:           decimal.NewFromFloat(cv),
+	}
+}
+
+func TestSamplingPlan(t *testing.T) {
+	type Test struct {
+		planner      SamplingPlanner
+		zones        []PlanZone
+		proportional []int
+		neyman       []int
+	}
+	tests := []Test{
+		// n = (t * 0.3 / 0.1)^2, t = 1.706 for 26 degrees of freedom
+		{SamplingPlanner{}, []PlanZone{testPlanZone("zone-1", 1, 100, 0.3)}, []int{27}, []int{27}},
+		{
+			SamplingPlanner{},
+			[]PlanZone{testPlanZone("zone-1", 80, 100, 0.3), testPlanZone("zone-2", 20, 50, 0.8)},
+			[]int{30, 8},
+			[]int{28, 10},
+		},
+		{
+			SamplingPlanner{PlotArea: decimal.NewFromFloat(0.1)},
+			[]PlanZone{testPlanZone("zone-1", 80, 100, 0.3), testPlanZone("zone-2", 20, 50, 0.8)},
+			[]int{29, 8},
+			[]int{27, 9},
+		},
+		// 2 plots are enough at the normal quantile but not at t = 6.314 of
+		// their own, 4 plots are the smallest number enough at t = 2.353
+		{SamplingPlanner{Precision: decimal.NewFromFloat(0.5)}, []PlanZone{testPlanZone("zone-1", 1, 100, 0.3)}, []int{4}, []int{4}},
+		{SamplingPlanner{MinPlots: 5}, []PlanZone{testPlanZone("zone-1", 99, 100, 0.3), testPlanZone("zone-2", 1, 100, 0.3)}, []int{26, 5}, []int{26, 5}},
+	}
+	for i, tt := range tests {
+		plan, err := tt.planner.Plan(tt.zones)
+		if err != nil {
+			t.Fatalf("Test number %d, %v", i, err)
+		}
+		for _, allocation := range []struct {
+			result PlotAllocation
+			expect []int
+		}{{plan.Proportional, tt.proportional}, {plan.Neyman, tt.neyman}} {
+			total := 0
+			for j, plots := range allocation.expect {
+				total += plots
+				if allocation.result.Zones[j].Plots != plots || allocation.result.Zones[j].ID != tt.zones[j].ID {
+					t.Fatalf("Test number %d, expect: %v, have: %v", i, allocation.expect, allocation.result.Zones)
+				}
+			}
+			if allocation.result.Plots != total {
+				t.Fatalf("Test number %d, expect: %d, have: %d", i, total, allocation.result.Plots)
+			}
+			expectT := TDistribution(float64(total - len(tt.zones)))
+			if total > 2 && !allocation.result.TDistribution.Equal(expectT) {
+				t.Fatalf("Test number %d, expect: %s, have: %s", i, expectT, allocation.result.TDistribution)
+			}
+		}
+	}
+}
+
+func TestSamplingPlanPilotPlots(t *testing.T) {
+	plots := []decimal.Decimal{decimal.New(80, 0), decimal.New(120, 0), decimal.New(95, 0), decimal.New(105, 0)}
+	pilot := PlanZone{ID: "zone-1", CarbonedZone: CarbonedZone{Area: decimal.New(10, 0), Plots: plots}}
+	mean, deviation := pilot.moments()
+	if mean != 100 {
+		t.Fatalf("expect: 100, have: %f", mean)
+	}
+	prior := testPlanZone("zone-1", 10, mean, deviation/mean)
+	pilotPlan, err := SamplingPlanner{}.Plan([]PlanZone{pilot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	priorPlan, err := SamplingPlanner{}.Plan([]PlanZone{prior})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pilotPlan.Neyman.Plots != priorPlan.Neyman.Plots || !pilotPlan.Mean.Equal(decimal.New(100, 0)) {
+		t.Fatalf("Pilot plots should give the same plan as the prior cv: %v, %v", pilotPlan, priorPlan)
+	}
+}
+
+func TestSamplingPlanValidate(t *testing.T) {
+	zones := []PlanZone{
+		testPlanZone("zone-1", 0, 100, 0.3),
+		{ID: "zone-2", CarbonedZone: CarbonedZone{Area: decimal.New(1, 0), Plots: []decimal.Decimal{decimal.New(1, 0)}}},
+	}
+	_, err := SamplingPlanner{Precision: decimal.New(2, 0)}.Plan(zones)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expect 3 ValidationErrors, have: %v", err)
+	}
+	if errs[2].Zone != "zone-2" || errs[2].Rule != RulePilot {
+		t.Fatalf("expect: %s, have: %v", RulePilot, errs[2])
+	}
+	single := []PlanZone{testPlanZone("zone-1", 1, 100, 0.3)}
+	planner := SamplingPlanner{Precision: decimal.NewFromFloat(0.5), MinPlots: 1}
+	if _, err := planner.Plan(single); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Rule != RuleMinPlots {
+		t.Fatalf("expect: %s, have: %v", RuleMinPlots, err)
+	}
+	// One plot is enough at the normal quantile, the search starts from two
+	// plots with one degree of freedom
+	allocation := planner.allocate(single, []float64{1}, 900, 900, 50, 0)
+	if allocation.Plots != 4 || !allocation.TDistribution.Equal(TDistribution(3)) {
+		t.Fatalf("expect: 4 plots, have: %v", allocation)
+	}
+	// 5 plots are enough, the two halves are rounded up to 6 plots with 4
+	// degrees of freedom
+	double := []PlanZone{testPlanZone("zone-1", 1, 100, 0.3), testPlanZone("zone-2", 1, 100, 0.3)}
+	allocation = SamplingPlanner{}.allocate(double, []float64{0.5, 0.5}, 1200, 1200, 50, 0)
+	if allocation.Plots != 6 || !allocation.TDistribution.Equal(TDistribution(4)) {
+		t.Fatalf("expect: 6 plots, have: %v", allocation)
+	}
+	if _, err := (SamplingPlanner{}).Plan(nil); !errors.Is(err, ErrNoMonitoringZones) {
+		t.Fatalf("expect: %v, have: %v", ErrNoMonitoringZones, err)
+	}
+}
@@ -0,0 +1,513 @@
+// Package server exposes the carbon_calc formulas as HTTP JSON API
+//
+// Every endpoint accepts POST with JSON body and responds with JSON, the
+// schemas mirror the types of the library, ForestType, TreeSpecies and
+// RainfallType are serialized as strings:
+//
+//	POST /tree/carbon   - carbon stored in the tree
+//	POST /zone/carbon   - carbon stored in the plots and the monitoring zone
+//	POST /uncertainty   - uncertainty of the carbon stock and its discount
+//	POST /emissions     - fertilizer, burning and fuel emissions and net
+//	                      emissions removal
+//	POST /leakage       - itemized leakage and its fraction
+//	POST /tokens        - minted OCC, buffer pool, holders and zone split
+//	POST /stage         - full stage calculation, see carbon_calc.Stage, the
+//	                      allometric equations are selected by name, the
+//	                      linear discount schedule by "linearDiscount"
+//	GET  /parameters    - parameter set used by default
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	carbon "github.com/nexeranet/carbon_calc"
+	"github.com/shopspring/decimal"
+)
+
+// Max size of the request body (bytes)
+const maxBodySize = 10 << 20
+
+// HTTP handler of the API
+// parameters - parameter set of the stage if the request does not contain
+// one, nil if you want to get DefaultParameterSet
+// species - registry of the wood density used by the stage, may be nil
+type Server struct {
+	Parameters *carbon.ParameterSet
+	Species    *carbon.SpeciesRegistry
+	mux        *http.ServeMux
+}
+
+func New() *Server {
+	s := &Server{mux: http.NewServeMux()}
+	s.mux.HandleFunc("/tree/carbon", post(s.treeCarbon))
+	s.mux.HandleFunc("/zone/carbon", post(s.zoneCarbon))
+	s.mux.HandleFunc("/uncertainty", post(s.uncertainty))
+	s.mux.HandleFunc("/emissions", post(s.emissions))
+	s.mux.HandleFunc("/leakage", post(s.leakage))
+	s.mux.HandleFunc("/tokens", post(s.tokens))
+	s.mux.HandleFunc("/stage", post(s.stage))
+	s.mux.HandleFunc("/parameters", s.parameters)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Error response of the API
+// validation - all problems found in the input, see carbon_calc.Stage.Validate
+type ErrorResponse struct {
+	Error      string                  `json:"error"`
+	Validation carbon.ValidationErrors `json:"validation,omitempty"`
+}
+
+// Input is not a valid JSON of the request
+type decodeError struct {
+	err error
+}
+
+func (e decodeError) Error() string {
+	return e.err.Error()
+}
+
+func post(handle func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "Method not allowed."})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		response, err := handle(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func decode(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return decodeError{err}
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	response := ErrorResponse{Error: err.Error()}
+	status := http.StatusUnprocessableEntity
+	var decodeErr decodeError
+	if errors.As(err, &decodeErr) {
+		status = http.StatusBadRequest
+	}
+	errors.As(err, &response.Validation)
+	writeJSON(w, status, response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) parameters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "Method not allowed."})
+		return
+	}
+	parameters := s.Parameters
+	if parameters == nil {
+		parameters = carbon.DefaultParameterSet()
+	}
+	writeJSON(w, http.StatusOK, parameters)
+}
+
+// Input of the tree carbon, see carbon_calc.CarbonPerTreeWith
+// fraction, form - null if you want to get the default values, zero is a legal
+// value
+// equation - name of the built-in allometric equation, empty if you want to
+// use the form factor model
+type TreeCarbonRequest struct {
+	Fraction *decimal.Decimal `json:"fraction"`
+	Radius   decimal.Decimal  `json:"radius"`
+	Height   decimal.Decimal  `json:"height"`
+	Form     *decimal.Decimal `json:"form"`
+	Density  decimal.Decimal  `json:"density"`
+	Biomass  decimal.Decimal  `json:"biomass"`
+	Ratio    decimal.Decimal  `json:"ratio"`
+	Equation string           `json:"equation,omitempty"`
+}
+
+// defaults - defaults substituted for the fraction and form not provided
+type TreeCarbonResponse struct {
+	Carbon   decimal.Decimal `json:"carbon"`
+	Defaults carbon.Defaults `json:"defaults"`
+}
+
+func (s *Server) treeCarbon(r *http.Request) (interface{}, error) {
+	var request TreeCarbonRequest
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	tree := carbon.Tree{
+		Radius:   request.Radius,
+		Height:   request.Height,
+		Fraction: request.Fraction,
+		Form:     request.Form,
+		Density:  &request.Density,
+		Biomass:  &request.Biomass,
+		Ratio:    &request.Ratio,
+	}
+	if err := tree.Validate(); err != nil {
+		return nil, err
+	}
+	var equation carbon.AllometricEquation
+	if request.Equation != "" {
+		var err error
+		if equation, err = carbon.AllometricEquationByName(request.Equation); err != nil {
+			return nil, err
+		}
+	}
+	if request.Height.LessThan(decimal.NewFromFloat(1.3)) {
+		return nil, carbon.NotEnoughHeight
+	}
+	var response TreeCarbonResponse
+	if equation == nil {
+		response.Carbon, response.Defaults = carbon.CarbonPerTreeWith(carbon.CarbonPerTreeOptions{
+			Fraction: request.Fraction,
+			Radius:   request.Radius,
+			Height:   request.Height,
+			Form:     request.Form,
+			Density:  request.Density,
+			Biomass:  request.Biomass,
+			Ratio:    request.Ratio,
+		})
+		return response, nil
+	}
+	m := carbon.TreeMeasurement{
+		Radius:  request.Radius,
+		Height:  request.Height,
+		Density: request.Density,
+		Biomass: request.Biomass,
+	}
+	if request.Form != nil {
+		m.Form = *request.Form
+	}
+	response.Carbon, response.Defaults = carbon.CarbonPerTreeAllometricWith(equation, m, request.Fraction, request.Ratio)
+	return response, nil
+}
+
+// Carbon of the trees in the sample plot (t CO2-e)
+type PlotCarbon struct {
+	ID    string            `json:"id"`
+	Area  decimal.Decimal   `json:"area"`
+	Trees []decimal.Decimal `json:"trees"`
+}
+
+// Input of the zone aggregation, see carbon_calc.CarbonStoredInPlot and
+// carbon_calc.CarbonStoredInMonitoringZone
+type ZoneCarbonRequest struct {
+	Area  decimal.Decimal `json:"area"`
+	Plots []PlotCarbon    `json:"plots"`
+}
+
+type ZoneCarbonResponse struct {
+	Plots  []carbon.PlotResult `json:"plots"`
+	Carbon decimal.Decimal     `json:"carbon"`
+}
+
+func (s *Server) zoneCarbon(r *http.Request) (interface{}, error) {
+	var request ZoneCarbonRequest
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	if len(request.Plots) == 0 {
+		return nil, carbon.ErrNoPlots
+	}
+	response := ZoneCarbonResponse{}
+	sum := decimal.Zero
+	for _, plot := range request.Plots {
+		plotCarbon := carbon.SumDecimal(plot.Trees)
+		perHa, err := carbon.ValidateCarbonStoredInPlot(plotCarbon, plot.Area)
+		if err != nil {
+			return nil, fmt.Errorf("plot %s: %w", plot.ID, err)
+		}
+		response.Plots = append(response.Plots, carbon.PlotResult{ID: plot.ID, Carbon: plotCarbon, CarbonPerHa: perHa})
+		sum = sum.Add(perHa)
+	}
+	var err error
+	response.Carbon, err = carbon.ValidateCarbonStoredInMonitoringZone(sum, decimal.New(int64(len(request.Plots)), 0), request.Area)
+	return response, err
+}
+
+// Input of the uncertainty, see carbon_calc.UncertaintyCarbonStoredInterval
+// zones - area of the zone and carbon per ha of its plots
+// totalCarbon - carbon stock in trees in all monitoring zones, 0 if you do not
+// need the conservative carbon
+// confidence - two-sided confidence level, 0 if you want to get default value
+// 0.9
+// standard - carbon standard of the project, selects the discount schedule
+type UncertaintyRequest struct {
+	Zones       []carbon.CarbonedZone `json:"zones"`
+	TotalCarbon decimal.Decimal       `json:"totalCarbon"`
+	Confidence  float64               `json:"confidence"`
+	Standard    carbon.Standard       `json:"standard"`
+}
+
+// estimate - stratified estimate of the total carbon stock (t CO2-e), see
+// carbon_calc.StratifiedEstimator
+type UncertaintyResponse struct {
+	carbon.UncertaintyInterval
+	Estimate             carbon.StratifiedEstimate `json:"estimate"`
+	DiscountSchedule     string                    `json:"discountSchedule"`
+	UncertaintyDeduction decimal.Decimal           `json:"uncertaintyDeduction"`
+	ConservativeCarbon   decimal.Decimal           `json:"conservativeCarbon"`
+}
+
+func (s *Server) uncertainty(r *http.Request) (interface{}, error) {
+	var request UncertaintyRequest
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Confidence < 0 || request.Confidence >= 1 {
+		return nil, carbon.ValidationErrors{{Field: "confidence", Value: fmt.Sprint(request.Confidence), Rule: carbon.RuleFraction}}
+	}
+	area := decimal.Zero
+	for _, zone := range request.Zones {
+		area = area.Add(zone.Area)
+	}
+	interval, err := carbon.UncertaintyCarbonStoredInterval(request.Confidence, area, request.Zones)
+	if err != nil {
+		return nil, err
+	}
+	estimate, err := carbon.StratifiedEstimator(request.Confidence, request.Zones)
+	if err != nil {
+		return nil, err
+	}
+	schedule := request.Standard.DiscountSchedule()
+	return UncertaintyResponse{
+		UncertaintyInterval:  interval,
+		Estimate:             estimate,
+		DiscountSchedule:     schedule.String(),
+		UncertaintyDeduction: schedule.Deduction(interval.Uncertainty),
+		ConservativeCarbon:   carbon.ConservativeTotalCarbonSchedule(schedule, request.TotalCarbon, interval.Uncertainty),
+	}, nil
+}
+
+// Input of the emissions, see carbon_calc.ProjectEmissions and
+// carbon_calc.NetEmissionsRemoval
+// gwp - GWP set of the global warming potentials not given in the inputs
+// conservativeCarbon, baseline, leakage - inputs of the net emissions removal
+type EmissionsRequest struct {
+	Fertilizers        []carbon.Fertilizer      `json:"fertilizers"`
+	Burning            []carbon.BurningEvent    `json:"burning"`
+	Fuels              []carbon.FuelConsumption `json:"fuels"`
+	OtherEmissions     decimal.Decimal          `json:"otherEmissions"`
+	GWP                carbon.GWPSet            `json:"gwp"`
+	ConservativeCarbon decimal.Decimal          `json:"conservativeCarbon"`
+	Baseline           decimal.Decimal          `json:"baseline"`
+	Leakage            decimal.Decimal          `json:"leakage"`
+}
+
+// fertilizers, burning, fuels - emissions of each fertilizer, burning and fuel
+// in order of the request
+// statement - itemized emissions
+type EmissionsResponse struct {
+	Fertilizers         []decimal.Decimal         `json:"fertilizers"`
+	Burning             []decimal.Decimal         `json:"burning"`
+	Fuels               []decimal.Decimal         `json:"fuels"`
+	Statement           carbon.EmissionsStatement `json:"statement"`
+	Emissions           decimal.Decimal           `json:"emissions"`
+	NetEmissionsRemoval decimal.Decimal           `json:"netEmissionsRemoval"`
+}
+
+func (s *Server) emissions(r *http.Request) (interface{}, error) {
+	var request EmissionsRequest
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	var errs carbon.ValidationErrors
+	for i, fertilizer := range request.Fertilizers {
+		var fertilizerErrs carbon.ValidationErrors
+		if errors.As(fertilizer.Validate(), &fertilizerErrs) {
+			for _, err := range fertilizerErrs {
+				err.Field = fmt.Sprintf("fertilizers[%d] %s", i, err.Field)
+			}
+			errs = append(errs, fertilizerErrs...)
+		}
+	}
+	for i, burning := range request.Burning {
+		var burningErrs carbon.ValidationErrors
+		if errors.As(burning.Validate(), &burningErrs) {
+			for _, err := range burningErrs {
+				err.Field = fmt.Sprintf("burning[%d] %s", i, err.Field)
+			}
+			errs = append(errs, burningErrs...)
+		}
+	}
+	for i, fuel := range request.Fuels {
+		var fuelErrs carbon.ValidationErrors
+		if errors.As(fuel.Validate(), &fuelErrs) {
+			for _, err := range fuelErrs {
+				err.Field = fmt.Sprintf("fuels[%d] %s", i, err.Field)
+			}
+			errs = append(errs, fuelErrs...)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	statement := carbon.ProjectEmissions(request.Fertilizers, request.Burning, request.Fuels, request.OtherEmissions, request.GWP)
+	response := EmissionsResponse{
+		Fertilizers: []decimal.Decimal{},
+		Burning:     []decimal.Decimal{},
+		Fuels:       []decimal.Decimal{},
+		Statement:   statement,
+		Emissions:   statement.Total,
+	}
+	for _, item := range statement.Items {
+		switch item.Source {
+		case carbon.EmissionFertilizer:
+			response.Fertilizers = append(response.Fertilizers, item.Emissions)
+		case carbon.EmissionBurning:
+			response.Burning = append(response.Burning, item.Emissions)
+		case carbon.EmissionFuel:
+			response.Fuels = append(response.Fuels, item.Emissions)
+		}
+	}
+	response.NetEmissionsRemoval = carbon.NetEmissionsRemoval(request.ConservativeCarbon, request.Baseline, request.Leakage, response.Emissions)
+	return response, nil
+}
+
+// Input of the leakage, see carbon_calc.ProjectLeakage
+// conservativeCarbon - carbon the leakage fraction is applied to
+type LeakageRequest struct {
+	Displacements      []carbon.Displacement       `json:"displacements"`
+	Fuelwood           []carbon.FuelwoodCollection `json:"fuelwood"`
+	ConservativeCarbon decimal.Decimal             `json:"conservativeCarbon"`
+}
+
+func (s *Server) leakage(r *http.Request) (interface{}, error) {
+	var request LeakageRequest
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	var errs carbon.ValidationErrors
+	for i, displacement := range request.Displacements {
+		var displacementErrs carbon.ValidationErrors
+		if errors.As(displacement.Validate(), &displacementErrs) {
+			for _, err := range displacementErrs {
+				err.Field = fmt.Sprintf("displacements[%d] %s", i, err.Field)
+			}
+			errs = append(errs, displacementErrs...)
+		}
+	}
+	for i, collection := range request.Fuelwood {
+		var fuelwoodErrs carbon.ValidationErrors
+		if errors.As(collection.Validate(), &fuelwoodErrs) {
+			for _, err := range fuelwoodErrs {
+				err.Field = fmt.Sprintf("fuelwood[%d] %s", i, err.Field)
+			}
+			errs = append(errs, fuelwoodErrs...)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return carbon.ProjectLeakage(request.Displacements, request.Fuelwood, request.ConservativeCarbon), nil
+}
+
+// Conservative carbon of the monitoring zone in the current and previous stage
+type ZoneTokens struct {
+	ID                         string          `json:"id"`
+	ConservativeCarbon         decimal.Decimal `json:"conservativeCarbon"`
+	PreviousConservativeCarbon decimal.Decimal `json:"previousConservativeCarbon"`
+}
+
+// Input of the token split, see carbon_calc.MintedOCC, carbon_calc.OCCBufferPool,
+// carbon_calc.OCCHolders and carbon_calc.OCCMintedPerMonitoringZone
+// bufferPercent, holdersPercent - omit if you want to get default value, 0 is
+// used as is
+// conservativeCarbon, previousConservativeCarbon, zones - needed for the split
+// of the minted OCC by monitoring zones only, the zones get 0 if the carbon
+// of all zones did not change
+type TokensRequest struct {
+	NetEmissionsRemoval         decimal.Decimal  `json:"netEmissionsRemoval"`
+	PreviousNetEmissionsRemoval decimal.Decimal  `json:"previousNetEmissionsRemoval"`
+	BufferPercent               *decimal.Decimal `json:"bufferPercent"`
+	HoldersPercent              *decimal.Decimal `json:"holdersPercent"`
+	ConservativeCarbon          decimal.Decimal  `json:"conservativeCarbon"`
+	PreviousConservativeCarbon  decimal.Decimal  `json:"previousConservativeCarbon"`
+	Zones                       []ZoneTokens     `json:"zones"`
+}
+
+type ZoneMinted struct {
+	ID        string          `json:"id"`
+	MintedOCC decimal.Decimal `json:"mintedOCC"`
+}
+
+// defaults - defaults substituted for bufferPercent and holdersPercent
+type TokensResponse struct {
+	MintedOCC  decimal.Decimal `json:"mintedOCC"`
+	BufferPool decimal.Decimal `json:"bufferPool"`
+	Holders    decimal.Decimal `json:"holders"`
+	Zones      []ZoneMinted    `json:"zones"`
+	Defaults   carbon.Defaults `json:"defaults"`
+}
+
+func (s *Server) tokens(r *http.Request) (interface{}, error) {
+	var request TokensRequest
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	minted := carbon.MintedOCC(request.NetEmissionsRemoval, request.PreviousNetEmissionsRemoval)
+	bufferPool, bufferDefaults := carbon.OCCBufferPoolWith(carbon.OCCShareOptions{MintedOCC: minted, Percent: request.BufferPercent})
+	holders, holdersDefaults := carbon.OCCHoldersWith(carbon.OCCShareOptions{MintedOCC: minted, Percent: request.HoldersPercent})
+	response := TokensResponse{
+		MintedOCC:  minted,
+		BufferPool: bufferPool,
+		Holders:    holders,
+		Zones:      []ZoneMinted{},
+		Defaults:   carbon.Defaults{},
+	}
+	if percent, ok := bufferDefaults["percent"]; ok {
+		response.Defaults["bufferPercent"] = percent
+	}
+	if percent, ok := holdersDefaults["percent"]; ok {
+		response.Defaults["holdersPercent"] = percent
+	}
+	for _, zone := range request.Zones {
+		zoneMinted, err := carbon.ValidateOCCMintedPerMonitoringZone(minted,
+			request.ConservativeCarbon, zone.ConservativeCarbon,
+			request.PreviousConservativeCarbon, zone.PreviousConservativeCarbon)
+		if err == carbon.ErrNoCarbonChange {
+			// Nothing is minted by zone as in carbon_calc.Stage
+			zoneMinted = decimal.Zero
+		} else if err != nil {
+			return nil, err
+		}
+		response.Zones = append(response.Zones, ZoneMinted{ID: zone.ID, MintedOCC: zoneMinted})
+	}
+	return response, nil
+}
+
+func (s *Server) stage(r *http.Request) (interface{}, error) {
+	var stage carbon.Stage
+	if err := decode(r, &stage); err != nil {
+		return nil, err
+	}
+	if stage.Parameters == nil {
+		stage.Parameters = s.Parameters
+	} else if err := stage.Parameters.Validate(); err != nil {
+		return nil, err
+	}
+	stage.Species = s.Species
+	return stage.Calculate()
+}
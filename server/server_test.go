@@ -0,0 +1,386 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	carbon "github.com/nexeranet/carbon_calc"
+	"github.com/shopspring/decimal"
+)
+
+func request(t *testing.T, handler http.Handler, method, path string, body string, response interface{}) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("%s: expect application/json, have: %s", path, ct)
+	}
+	if response != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), response); err != nil {
+			t.Fatalf("%s: %v\n%s", path, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestTreeCarbon(t *testing.T) {
+	s := New()
+	type Test struct {
+		body   string
+		status int
+		result decimal.Decimal
+	}
+	tests := []Test{
+		{
+			`{"fraction": "0.47", "radius": "0.05", "height": "5", "form": "0.25", "density": "0.55", "biomass": "1.15", "ratio": "0.3"}`,
+			http.StatusOK,
+			carbon.CarbonPerTree(decimal.NewFromFloat(0.47), decimal.NewFromFloat(0.05), decimal.New(5, 0), decimal.NewFromFloat(0.25), decimal.NewFromFloat(0.55), decimal.NewFromFloat(1.15), decimal.NewFromFloat(0.3)),
+		},
+		{
+			`{"radius": "0.05", "height": "10", "density": "0.6", "ratio": "0.24", "equation": "chave-2014"}`,
+			http.StatusOK,
+			carbon.CarbonPerTreeAllometric(carbon.Chave2014Equation{}, carbon.TreeMeasurement{Radius: decimal.NewFromFloat(0.05), Height: decimal.New(10, 0), Density: decimal.NewFromFloat(0.6)}, decimal.Zero, decimal.NewFromFloat(0.24)),
+		},
+		{`{"radius": "0.05", "height": "5", "form": "0", "density": "0.55", "biomass": "1.15"}`, http.StatusOK, decimal.Zero},
+		{`{"radius": "0.05", "height": "1.2"}`, http.StatusUnprocessableEntity, decimal.Zero},
+		{`{"radius": "0.05", "height": "5", "equation": "unknown"}`, http.StatusUnprocessableEntity, decimal.Zero},
+		{`{"radius": "-0.05", "height": "5"}`, http.StatusUnprocessableEntity, decimal.Zero},
+		{`{"radius": "0.05", "heigth": "5"}`, http.StatusBadRequest, decimal.Zero},
+	}
+	for i, tt := range tests {
+		var response TreeCarbonResponse
+		status := request(t, s, http.MethodPost, "/tree/carbon", tt.body, &response)
+		if status != tt.status {
+			t.Fatalf("Test number %d, expect: %d, have: %d", i, tt.status, status)
+		}
+		if !response.Carbon.Equal(tt.result) {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, tt.result, response.Carbon)
+		}
+	}
+
+	var response TreeCarbonResponse
+	body := `{"radius": "0.05", "height": "5", "density": "0.55", "biomass": "1.15", "ratio": "0.3"}`
+	if status := request(t, s, http.MethodPost, "/tree/carbon", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	if !response.Carbon.Equal(tests[0].result) || len(response.Defaults) != 2 ||
+		!response.Defaults["fraction"].Equal(decimal.NewFromFloat(0.47)) || !response.Defaults["form"].Equal(decimal.NewFromFloat(0.25)) {
+		t.Fatalf("expect: %s with fraction and form defaults, have: %v", tests[0].result, response)
+	}
+	body = `{"radius": "0.05", "height": "10", "density": "0.6", "ratio": "0.24", "equation": "chave-2014"}`
+	response = TreeCarbonResponse{}
+	if status := request(t, s, http.MethodPost, "/tree/carbon", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	if len(response.Defaults) != 1 || !response.Defaults["fraction"].Equal(decimal.NewFromFloat(0.47)) {
+		t.Fatalf("expect fraction default, have: %v", response.Defaults)
+	}
+}
+
+func TestZoneCarbon(t *testing.T) {
+	var response ZoneCarbonResponse
+	body := `{"area": "8", "plots": [
+		{"id": "plot-1", "area": "0.1", "trees": ["0.1", "0.2"]},
+		{"id": "plot-2", "area": "0.1", "trees": ["0.3"]}
+	]}`
+	if status := request(t, New(), http.MethodPost, "/zone/carbon", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	expect := carbon.CarbonStoredInMonitoringZone(decimal.New(6, 0), decimal.New(2, 0), decimal.New(8, 0))
+	if !response.Carbon.Equal(expect) || !response.Plots[1].CarbonPerHa.Equal(decimal.New(3, 0)) {
+		t.Fatalf("expect: %s, have: %v", expect, response)
+	}
+
+	var errResponse ErrorResponse
+	body = `{"area": "8", "plots": [{"id": "plot-1", "area": "0", "trees": ["0.1"]}]}`
+	if status := request(t, New(), http.MethodPost, "/zone/carbon", body, &errResponse); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expect: %d, have: %d", http.StatusUnprocessableEntity, status)
+	}
+	if !strings.Contains(errResponse.Error, carbon.ErrZeroPlotArea.Error()) {
+		t.Fatalf("expect: %s, have: %s", carbon.ErrZeroPlotArea, errResponse.Error)
+	}
+}
+
+func TestUncertainty(t *testing.T) {
+	var response UncertaintyResponse
+	body := `{"totalCarbon": "13", "zones": [
+		{"area": "8", "plots": ["1.2", "1.5", "0.9"]},
+		{"area": "1", "plots": ["2.1", "2.5"]}
+	]}`
+	if status := request(t, New(), http.MethodPost, "/uncertainty", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	zones := []carbon.CarbonedZone{
+		{Area: decimal.New(8, 0), Plots: []decimal.Decimal{decimal.NewFromFloat(1.2), decimal.NewFromFloat(1.5), decimal.NewFromFloat(0.9)}},
+		{Area: decimal.New(1, 0), Plots: []decimal.Decimal{decimal.NewFromFloat(2.1), decimal.NewFromFloat(2.5)}},
+	}
+	tDelta := carbon.TDistribution(3)
+	uncertainty := carbon.UncertaintyCarbonStored(tDelta, decimal.New(9, 0), zones)
+	if !response.TDistribution.Equal(tDelta) || !response.Uncertainty.Equal(uncertainty) {
+		t.Fatalf("expect: %s, %s, have: %v", tDelta, uncertainty, response)
+	}
+	estimate, err := carbon.StratifiedEstimator(0, zones)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Estimate.Total.Equal(estimate.Total) || !response.Estimate.StandardError.Equal(estimate.StandardError) {
+		t.Fatalf("expect: %v, have: %v", estimate, response.Estimate)
+	}
+	if !response.ConservativeCarbon.Equal(carbon.ConservativeTotalCarbon(decimal.New(13, 0), uncertainty)) {
+		t.Fatalf("Wrong conservative carbon: %s", response.ConservativeCarbon)
+	}
+
+	body = strings.Replace(body, `{"totalCarbon": "13",`, `{"totalCarbon": "13", "standard": "vm0047",`, 1)
+	if status := request(t, New(), http.MethodPost, "/uncertainty", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	schedule := carbon.StandardVM0047.DiscountSchedule()
+	if response.DiscountSchedule != "vm0047" || !response.ConservativeCarbon.Equal(carbon.ConservativeTotalCarbonSchedule(schedule, decimal.New(13, 0), uncertainty)) {
+		t.Fatalf("Wrong vm0047 result: %s, %s", response.DiscountSchedule, response.ConservativeCarbon)
+	}
+}
+
+func TestEmissions(t *testing.T) {
+	fertilizer := carbon.Fertilizer{
+		Applications:    decimal.New(2, 0),
+		MassSynthFertz:  decimal.New(100, 0),
+		NContSynthFertz: carbon.Optional(decimal.NewFromFloat(0.46)),
+		NitrOxdEmissSOC: carbon.Optional(decimal.NewFromFloat(0.01)),
+		AllFractSynth:   carbon.Optional(decimal.NewFromFloat(0.1)),
+		NitrOxdEmissWS:  carbon.Optional(decimal.NewFromFloat(0.01)),
+		NFractSoil:      carbon.Optional(decimal.NewFromFloat(0.3)),
+		NitrOxdEmissLR:  carbon.Optional(decimal.NewFromFloat(0.0075)),
+		GWarmingPotentl: carbon.Optional(decimal.New(298, 0)),
+	}
+	fertilizerJSON, err := json.Marshal(fertilizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"otherEmissions": "0.5", "conservativeCarbon": "10", "baseline": "1", "leakage": "0.05", "fertilizers": [` + string(fertilizerJSON) + `]}`
+	var response EmissionsResponse
+	if status := request(t, New(), http.MethodPost, "/emissions", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	emissions := fertilizer.Emissions().Add(decimal.NewFromFloat(0.5))
+	if !response.Emissions.Equal(emissions) || !response.Fertilizers[0].Equal(fertilizer.Emissions()) {
+		t.Fatalf("expect: %s, have: %v", emissions, response)
+	}
+	net := carbon.NetEmissionsRemoval(decimal.New(10, 0), decimal.New(1, 0), decimal.NewFromFloat(0.05), emissions)
+	if !response.NetEmissionsRemoval.Equal(net) {
+		t.Fatalf("expect: %s, have: %s", net, response.NetEmissionsRemoval)
+	}
+
+	var errResponse ErrorResponse
+	body = `{"fertilizers": [{"nFractSoil": "3"}]}`
+	if status := request(t, New(), http.MethodPost, "/emissions", body, &errResponse); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expect: %d, have: %d", http.StatusUnprocessableEntity, status)
+	}
+	if len(errResponse.Validation) != 1 || errResponse.Validation[0].Field != "fertilizers[0] nFractSoil" {
+		t.Fatalf("Wrong validation errors: %v", errResponse)
+	}
+
+	body = `{"burning": [{"area": "10", "biomass": "100"}]}`
+	if status := request(t, New(), http.MethodPost, "/emissions", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	burning := carbon.BurningEvent{Area: decimal.New(10, 0), Biomass: decimal.New(100, 0)}
+	if len(response.Burning) != 1 || !response.Emissions.Equal(burning.Emissions()) {
+		t.Fatalf("expect: %s, have: %v", burning.Emissions(), response)
+	}
+	body = `{"burning": [{"area": "-1"}]}`
+	if status := request(t, New(), http.MethodPost, "/emissions", body, &errResponse); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expect: %d, have: %d", http.StatusUnprocessableEntity, status)
+	}
+	if len(errResponse.Validation) != 1 || errResponse.Validation[0].Field != "burning[0] area" {
+		t.Fatalf("Wrong validation errors: %v", errResponse)
+	}
+
+	body = `{"otherEmissions": "1", "fuels": [{"id": "tractor", "fuel": "diesel", "volume": "1000"}]}`
+	if status := request(t, New(), http.MethodPost, "/emissions", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	fuel := carbon.FuelConsumption{ID: "tractor", Volume: decimal.New(1000, 0)}
+	if len(response.Fuels) != 1 || !response.Fuels[0].Equal(fuel.Emissions()) ||
+		len(response.Statement.Items) != 2 || response.Statement.Items[0].ID != "tractor" ||
+		!response.Emissions.Equal(fuel.Emissions().Add(decimal.New(1, 0))) {
+		t.Fatalf("expect: %s, have: %v", fuel.Emissions(), response)
+	}
+	body = `{"gwp": "ar4", "fuels": [{"id": "tractor", "volume": "1000"}]}`
+	if status := request(t, New(), http.MethodPost, "/emissions", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	if response.Statement.GWP != carbon.GWPAR4 || !response.Emissions.Equal(fuel.EmissionsGWP(carbon.GWPAR4)) {
+		t.Fatalf("expect: %s, have: %v", fuel.EmissionsGWP(carbon.GWPAR4), response)
+	}
+	body = `{"fuels": [{"mass": "-1"}]}`
+	if status := request(t, New(), http.MethodPost, "/emissions", body, &errResponse); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expect: %d, have: %d", http.StatusUnprocessableEntity, status)
+	}
+	if len(errResponse.Validation) != 1 || errResponse.Validation[0].Field != "fuels[0] mass" {
+		t.Fatalf("Wrong validation errors: %v", errResponse)
+	}
+}
+
+func TestLeakage(t *testing.T) {
+	body := `{"conservativeCarbon": "1000",
+		"displacements": [{"id": "grazing", "area": "2", "forestFraction": "0.5", "biomass": "100"}],
+		"fuelwood": [{"id": "village", "volume": "10", "density": "0.6"}]}`
+	var response carbon.LeakageStatement
+	if status := request(t, New(), http.MethodPost, "/leakage", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	displacement := carbon.Displacement{Area: decimal.New(2, 0), ForestFraction: decimal.NewFromFloat(0.5), Biomass: decimal.New(100, 0)}
+	fuelwood := carbon.FuelwoodCollection{Volume: decimal.New(10, 0), Density: decimal.NewFromFloat(0.6)}
+	total := displacement.Leakage().Add(fuelwood.Leakage())
+	if len(response.Items) != 2 || response.Items[1].ID != "village" ||
+		!response.Total.Equal(total) || !response.Fraction.Equal(total.Div(decimal.New(1000, 0))) {
+		t.Fatalf("expect: %s, have: %v", total, response)
+	}
+
+	var errResponse ErrorResponse
+	body = `{"fuelwood": [{"nonRenewable": "2"}]}`
+	if status := request(t, New(), http.MethodPost, "/leakage", body, &errResponse); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expect: %d, have: %d", http.StatusUnprocessableEntity, status)
+	}
+	if len(errResponse.Validation) != 1 || errResponse.Validation[0].Field != "fuelwood[0] nonRenewable" {
+		t.Fatalf("Wrong validation errors: %v", errResponse)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	body := `{"netEmissionsRemoval": "10", "previousNetEmissionsRemoval": "4",
+		"conservativeCarbon": "12", "previousConservativeCarbon": "6",
+		"zones": [{"id": "zone-1", "conservativeCarbon": "8", "previousConservativeCarbon": "5"}]}`
+	var response TokensResponse
+	if status := request(t, New(), http.MethodPost, "/tokens", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	minted := decimal.New(6, 0)
+	zoneMinted := carbon.OCCMintedPerMonitoringZone(minted, decimal.New(12, 0), decimal.New(8, 0), decimal.New(6, 0), decimal.New(5, 0))
+	if !response.MintedOCC.Equal(minted) ||
+		!response.BufferPool.Equal(carbon.OCCBufferPool(minted, 0)) ||
+		!response.Holders.Equal(carbon.OCCHolders(minted, 0)) ||
+		!response.Zones[0].MintedOCC.Equal(zoneMinted) ||
+		len(response.Defaults) != 2 || !response.Defaults["bufferPercent"].Equal(decimal.NewFromFloat(0.07)) {
+		t.Fatalf("Wrong tokens: %v", response)
+	}
+
+	body = `{"netEmissionsRemoval": "10", "bufferPercent": 0, "holdersPercent": "0.1"}`
+	response = TokensResponse{}
+	if status := request(t, New(), http.MethodPost, "/tokens", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	if !response.BufferPool.IsZero() || !response.Holders.Equal(decimal.New(1, 0)) || len(response.Defaults) != 0 {
+		t.Fatalf("Wrong tokens: %v", response)
+	}
+
+	// No carbon change mints nothing by zone as the stage does
+	body = `{"netEmissionsRemoval": "10", "conservativeCarbon": "6", "previousConservativeCarbon": "6",
+		"zones": [{"id": "zone-1", "conservativeCarbon": "3", "previousConservativeCarbon": "3"}]}`
+	response = TokensResponse{}
+	if status := request(t, New(), http.MethodPost, "/tokens", body, &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	if len(response.Zones) != 1 || !response.Zones[0].MintedOCC.IsZero() {
+		t.Fatalf("Wrong tokens: %v", response)
+	}
+}
+
+func TestStage(t *testing.T) {
+	tree := func(id string, radius, height float64) carbon.Tree {
+		return carbon.Tree{ID: id, Radius: decimal.NewFromFloat(radius), Height: decimal.NewFromFloat(height)}
+	}
+	stage := carbon.Stage{
+		Zones: []carbon.MonitoringZone{{
+			ID:         "zone-1",
+			Area:       decimal.New(8, 0),
+			ForestType: carbon.ForestTypeTemperate,
+			Species:    carbon.TreeSpeciesBroadleaf,
+			Rainfall:   carbon.RainfallTypeWet,
+			Plots: []carbon.Plot{
+				{ID: "plot-1", Area: decimal.NewFromFloat(0.1), Trees: []carbon.Tree{tree("tree-1", 0.05, 5), tree("tree-2", 0.06, 4)}},
+				{ID: "plot-2", Area: decimal.NewFromFloat(0.1), Trees: []carbon.Tree{tree("tree-3", 0.04, 3)}},
+			},
+		}},
+		DeltaTime: decimal.New(1, 0),
+		Leakage:   decimal.NewFromFloat(0.05),
+	}
+	body, err := json.Marshal(stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, enum := range []string{`"temperate"`, `"broadleaf"`, `"wet"`} {
+		if !bytes.Contains(body, []byte(enum)) {
+			t.Fatalf("Stage JSON should contain %s: %s", enum, body)
+		}
+	}
+	var response carbon.StageResult
+	if status := request(t, New(), http.MethodPost, "/stage", string(body), &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	expect, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.MintedOCC.Equal(expect.MintedOCC) || !response.TotalCarbon.Equal(expect.TotalCarbon) {
+		t.Fatalf("expect: %v, have: %v", expect, response)
+	}
+
+	stage.Zones[0].Plots[0].Trees[0].ScientificName = "Tectona grandis"
+	body = []byte(strings.Replace(string(body), `"plots"`, `"equation": "chave-2014", "plots"`, 1))
+	body = []byte(strings.Replace(string(body), `"id":"tree-1"`, `"id":"tree-1","scientificName":"Tectona grandis"`, 1))
+	body = []byte(strings.Replace(string(body), `"zones"`, `"equations": {"tectona grandis": "brown-1997-wet"}, "zones"`, 1))
+	if status := request(t, New(), http.MethodPost, "/stage", string(body), &response); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	stage.Zones[0].Equation = carbon.Chave2014Equation{}
+	stage.Equations = map[string]carbon.AllometricEquation{"Tectona grandis": carbon.Brown1997Equation{Rainfall: carbon.RainfallTypeWet}}
+	if expect, err = stage.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	if !response.TotalCarbon.Equal(expect.TotalCarbon) || !response.Zones[0].Plots[0].Trees[0].Carbon.Equal(expect.Zones[0].Plots[0].Trees[0].Carbon) {
+		t.Fatalf("expect: %v, have: %v", expect, response)
+	}
+	stage.Zones[0].Equation = nil
+	stage.Equations = nil
+
+	stage.Zones[0].Plots = stage.Zones[0].Plots[:1]
+	body, err = json.Marshal(stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errResponse ErrorResponse
+	if status := request(t, New(), http.MethodPost, "/stage", string(body), &errResponse); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expect: %d, have: %d", http.StatusUnprocessableEntity, status)
+	}
+	if len(errResponse.Validation) != 1 || errResponse.Validation[0].Rule != carbon.RuleMinPlots {
+		t.Fatalf("Wrong validation errors: %v", errResponse)
+	}
+
+	body = []byte(`{"parameters": {"density": {"default": 0.9}}}`)
+	errResponse = ErrorResponse{}
+	if status := request(t, New(), http.MethodPost, "/stage", string(body), &errResponse); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expect: %d, have: %d", http.StatusUnprocessableEntity, status)
+	}
+	if len(errResponse.Validation) != 1 || errResponse.Validation[0].Field != "version" {
+		t.Fatalf("Wrong validation errors: %v", errResponse)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	s := New()
+	if status := request(t, s, http.MethodGet, "/stage", "", nil); status != http.StatusMethodNotAllowed {
+		t.Fatalf("expect: %d, have: %d", http.StatusMethodNotAllowed, status)
+	}
+	var parameters carbon.ParameterSet
+	if status := request(t, s, http.MethodGet, "/parameters", "", &parameters); status != http.StatusOK {
+		t.Fatalf("expect: %d, have: %d", http.StatusOK, status)
+	}
+	if parameters.Version != carbon.DefaultParameterSet().Version {
+		t.Fatalf("expect: %s, have: %s", carbon.DefaultParameterSet().Version, parameters.Version)
+	}
+}
@@ -0,0 +1,64 @@
+package carbon_calc
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Shrubs of the monitoring zone, see CDM AR-TOOL14
+// crownCover - shrub crown cover in the project (fraction)
+// baselineCrownCover - shrub crown cover in the baseline, before the project
+// (fraction)
+// forestBiomass - above-ground biomass of the forest in the region (t d.m./ha)
+// ratio - ratio of the shrub biomass per ha at full crown cover to the forest
+// biomass, 0 if you want to get default value 0.1
+// rootShoot - root-shoot ratio of shrubs, 0 if you want to get default value
+// 0.4
+// fraction - carbon fraction of shrub biomass, 0 if you want to get default
+// value 0.47
+type Shrubs struct {
+	CrownCover         decimal.Decimal `json:"crownCover"`
+	BaselineCrownCover decimal.Decimal `json:"baselineCrownCover"`
+	ForestBiomass      decimal.Decimal `json:"forestBiomass"`
+	Ratio              decimal.Decimal `json:"ratio"`
+	RootShoot          decimal.Decimal `json:"rootShoot"`
+	Fraction           decimal.Decimal `json:"fraction"`
+}
+
+// Calculate the carbon stock in shrubs (t CO2-e)
+// fraction - carbon fraction of shrub biomass, 0 if you want to get default
+// value 0.47
+// rootShoot - root-shoot ratio of shrubs, 0 if you want to get default value
+// 0.4
+// area - area of monitoring zone (ha)
+// forestBiomass - above-ground biomass of the forest in the region (t d.m./ha)
+// ratio - ratio of the shrub biomass per ha at full crown cover to the forest
+// biomass, 0 if you want to get default value 0.1
+// crownCover - shrub crown cover (fraction)
+func CarbonStoredInShrubs(fraction, rootShoot, area, forestBiomass, ratio, crownCover decimal.Decimal) decimal.Decimal {
+	return carbonStoredInShrubs(nil, fraction, rootShoot, area, forestBiomass, ratio, crownCover)
+}
+
+func carbonStoredInShrubs(tr *Trace, fraction, rootShoot, area, forestBiomass, ratio, crownCover decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("CarbonStoredInShrubs")
+	fraction = tr.inputOrDefault("fraction", fraction, decimal.NewFromFloat(0.47))
+	rootShoot = tr.inputOrDefault("rootShoot", rootShoot, decimal.NewFromFloat(0.4))
+	ratio = tr.inputOrDefault("ratio", ratio, decimal.NewFromFloat(0.1))
+	tr.Input("area", area).
+		Input("forestBiomass", forestBiomass).
+		Input("crownCover", crownCover)
+	return tr.Result(decimal.NewFromFloat(44.0 / 12.0).
+		Mul(fraction).
+		Mul(decimal.New(1, 0).Add(rootShoot)).
+		Mul(area).
+		Mul(forestBiomass).
+		Mul(ratio).
+		Mul(crownCover))
+}
+
+// Carbon stock in shrubs per ha in the project and in the baseline
+// (t CO2-e/ha)
+func (s Shrubs) carbonPerHa(tr *Trace) (decimal.Decimal, decimal.Decimal) {
+	project := carbonStoredInShrubs(tr, s.Fraction, s.RootShoot, decimal.New(1, 0), s.ForestBiomass, s.Ratio, s.CrownCover)
+	baseline := carbonStoredInShrubs(tr, s.Fraction, s.RootShoot, decimal.New(1, 0), s.ForestBiomass, s.Ratio, s.BaselineCrownCover)
+	return project, baseline
+}
@@ -0,0 +1,87 @@
+package carbon_calc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCarbonStoredInShrubs(t *testing.T) {
+	type Test struct {
+		fraction, rootShoot, area, forestBiomass, ratio, crownCover float64
+		result                                                      float64 // multiplied by 44/12
+	}
+	tests := []Test{
+		// Defaults: 0.47 * 1.4 * 10 * 100 * 0.1 * 0.5
+		{0, 0, 10, 100, 0, 0.5, 0.47 * 1.4 * 10 * 100 * 0.1 * 0.5},
+		{0.5, 0.3, 2, 150, 0.2, 0.25, 0.5 * 1.3 * 2 * 150 * 0.2 * 0.25},
+		{0, 0, 10, 100, 0, 0, 0},
+	}
+	for i, tt := range tests {
+		result := CarbonStoredInShrubs(
+			decimal.NewFromFloat(tt.fraction),
+			decimal.NewFromFloat(tt.rootShoot),
+			decimal.NewFromFloat(tt.area),
+			decimal.NewFromFloat(tt.forestBiomass),
+			decimal.NewFromFloat(tt.ratio),
+			decimal.NewFromFloat(tt.crownCover))
+		expect := decimal.NewFromFloat(44.0 / 12).Mul(decimal.NewFromFloat(tt.result))
+		if !result.Round(6).Equal(expect.Round(6)) {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, expect, result)
+		}
+	}
+}
+
+func TestStageShrubs(t *testing.T) {
+	trees, err := testStage().Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := testStage()
+	stage.Zones[1].Shrubs = &Shrubs{
+		CrownCover:         decimal.NewFromFloat(0.3),
+		BaselineCrownCover: decimal.NewFromFloat(0.2),
+		ForestBiomass:      decimal.New(100, 0),
+	}
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shrubs := CarbonStoredInShrubs(decimal.Zero, decimal.Zero, decimal.New(1, 0), decimal.New(100, 0), decimal.Zero, decimal.NewFromFloat(0.3))
+	baselineShrubs := CarbonStoredInShrubs(decimal.Zero, decimal.Zero, decimal.New(1, 0), decimal.New(100, 0), decimal.Zero, decimal.NewFromFloat(0.2))
+	zone := result.Zones[1]
+	if !zone.Shrubs.Equal(shrubs) || !zone.BaselineShrubs.Equal(baselineShrubs) {
+		t.Fatalf("expect: %s, %s, have: %s, %s", shrubs, baselineShrubs, zone.Shrubs, zone.BaselineShrubs)
+	}
+	if !zone.Carbon.Round(9).Equal(trees.Zones[1].Carbon.Add(shrubs).Round(9)) {
+		t.Fatalf("expect: %s, have: %s", trees.Zones[1].Carbon.Add(shrubs), zone.Carbon)
+	}
+	if !result.Baseline.Equal(trees.Baseline) || !result.Shrubs.Equal(shrubs) || !result.BaselineShrubs.Equal(baselineShrubs) {
+		t.Fatalf("Wrong baseline: %s, %s", result.Baseline, result.BaselineShrubs)
+	}
+	// Shrubs are not sampled by plot, the uncertainty is not changed and the
+	// shrubs are added after the discount
+	if !result.Uncertainty.Equal(trees.Uncertainty) || !result.CarbonVariance.Equal(trees.CarbonVariance) {
+		t.Fatalf("expect: %s %s, have: %s %s", trees.Uncertainty, trees.CarbonVariance, result.Uncertainty, result.CarbonVariance)
+	}
+	if !result.Zones[1].Plots[0].CarbonPerHa.Equal(trees.Zones[1].Plots[0].CarbonPerHa) {
+		t.Fatalf("expect: %s, have: %s", trees.Zones[1].Plots[0].CarbonPerHa, result.Zones[1].Plots[0].CarbonPerHa)
+	}
+	if !result.ConservativeCarbon.Equal(trees.ConservativeCarbon.Add(shrubs)) || !zone.ConservativeCarbon.Equal(trees.Zones[1].ConservativeCarbon.Add(shrubs)) {
+		t.Fatalf("expect: %s, have: %s", trees.ConservativeCarbon.Add(shrubs), result.ConservativeCarbon)
+	}
+	// The baseline stock is deducted from the conservative carbon
+	expect := result.ConservativeCarbon.Add(result.SOC).Sub(baselineShrubs).
+		Mul(decimal.New(1, 0).Sub(result.Leakage)).Sub(result.Baseline).Sub(result.Emissions)
+	if !result.NetEmissionsRemoval.Round(9).Equal(expect.Round(9)) {
+		t.Fatalf("expect: %s, have: %s", expect, result.NetEmissionsRemoval)
+	}
+
+	stage.Zones[1].Shrubs.CrownCover = decimal.NewFromFloat(1.2)
+	stage.Zones[1].Shrubs.ForestBiomass = decimal.Zero
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expect 2 ValidationErrors, have: %v", err)
+	}
+}
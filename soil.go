@@ -0,0 +1,170 @@
+package carbon_calc
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// IPCC climate region of the reference soil organic carbon
+type ClimateRegion uint8
+
+const (
+	ClimateBoreal ClimateRegion = iota
+	ClimateColdTemperateDry
+	ClimateColdTemperateMoist
+	ClimateWarmTemperateDry
+	ClimateWarmTemperateMoist
+	ClimateTropicalDry
+	ClimateTropicalMoist
+	ClimateTropicalWet
+	ClimateTropicalMontane
+)
+
+// IPCC soil type of the reference soil organic carbon
+type SoilType uint8
+
+const (
+	SoilHighActivityClay SoilType = iota
+	SoilLowActivityClay
+	SoilSandy
+	SoilSpodic
+	SoilVolcanic
+	SoilWetland
+)
+
+var climateRegionNames = map[ClimateRegion]string{
+	ClimateBoreal:             "boreal",
+	ClimateColdTemperateDry:   "cold-temperate-dry",
+	ClimateColdTemperateMoist: "cold-temperate-moist",
+	ClimateWarmTemperateDry:   "warm-temperate-dry",
+	ClimateWarmTemperateMoist: "warm-temperate-moist",
+	ClimateTropicalDry:        "tropical-dry",
+	ClimateTropicalMoist:      "tropical-moist",
+	ClimateTropicalWet:        "tropical-wet",
+	ClimateTropicalMontane:    "tropical-montane",
+}
+
+var soilTypeNames = map[SoilType]string{
+	SoilHighActivityClay: "high-activity-clay",
+	SoilLowActivityClay:  "low-activity-clay",
+	SoilSandy:            "sandy",
+	SoilSpodic:           "spodic",
+	SoilVolcanic:         "volcanic",
+	SoilWetland:          "wetland",
+}
+
+func (c ClimateRegion) String() string {
+	return enumName(climateRegionNames, c)
+}
+
+func (c ClimateRegion) MarshalText() ([]byte, error) {
+	return marshalEnum(climateRegionNames, c)
+}
+
+func (c *ClimateRegion) UnmarshalText(text []byte) error {
+	return unmarshalEnum(climateRegionNames, text, c)
+}
+
+func (s SoilType) String() string {
+	return enumName(soilTypeNames, s)
+}
+
+func (s SoilType) MarshalText() ([]byte, error) {
+	return marshalEnum(soilTypeNames, s)
+}
+
+func (s *SoilType) UnmarshalText(text []byte) error {
+	return unmarshalEnum(soilTypeNames, text, s)
+}
+
+// Reference SOC stocks (t C/ha in 0-30 cm depth) of IPCC 2006 Volume 4 Table
+// 2.3, used by CDM AR-TOOL16, the combinations without estimate are absent
+var ReferenceSOCDict map[ClimateRegion]map[SoilType]float64 = map[ClimateRegion]map[SoilType]float64{
+	ClimateBoreal:             {SoilHighActivityClay: 68, SoilSandy: 10, SoilSpodic: 117, SoilVolcanic: 20, SoilWetland: 146},
+	ClimateColdTemperateDry:   {SoilHighActivityClay: 50, SoilLowActivityClay: 33, SoilSandy: 34, SoilVolcanic: 20, SoilWetland: 87},
+	ClimateColdTemperateMoist: {SoilHighActivityClay: 95, SoilLowActivityClay: 85, SoilSandy: 71, SoilSpodic: 115, SoilVolcanic: 130, SoilWetland: 87},
+	ClimateWarmTemperateDry:   {SoilHighActivityClay: 38, SoilLowActivityClay: 24, SoilSandy: 19, SoilVolcanic: 70, SoilWetland: 88},
+	ClimateWarmTemperateMoist: {SoilHighActivityClay: 88, SoilLowActivityClay: 63, SoilSandy: 34, SoilVolcanic: 80, SoilWetland: 88},
+	ClimateTropicalDry:        {SoilHighActivityClay: 38, SoilLowActivityClay: 35, SoilSandy: 31, SoilVolcanic: 50, SoilWetland: 86},
+	ClimateTropicalMoist:      {SoilHighActivityClay: 65, SoilLowActivityClay: 47, SoilSandy: 39, SoilVolcanic: 70, SoilWetland: 86},
+	ClimateTropicalWet:        {SoilHighActivityClay: 44, SoilLowActivityClay: 60, SoilSandy: 66, SoilVolcanic: 130, SoilWetland: 86},
+	ClimateTropicalMontane:    {SoilHighActivityClay: 88, SoilLowActivityClay: 63, SoilSandy: 34, SoilVolcanic: 80, SoilWetland: 86},
+}
+
+// Reference SOC stock depending on climate region / soil type (t C/ha), 0 if
+// there is no estimate
+func ReferenceSOC(climate ClimateRegion, soil SoilType) decimal.Decimal {
+	return decimal.NewFromFloat(ReferenceSOCDict[climate][soil])
+}
+
+// Soil organic carbon of the monitoring zone, see CDM AR-TOOL16
+// reference - reference SOC stock (t C/ha), 0 if you want to get ReferenceSOC
+// of the climate region and soil type
+// landUse, management, input - factors of the land use, management and input
+// before the project (AR-TOOL16 Tables 3-5), 0 if you want to get default
+// value 1
+type SoilCarbon struct {
+	Climate    ClimateRegion   `json:"climate"`
+	SoilType   SoilType        `json:"soilType"`
+	Reference  decimal.Decimal `json:"reference"`
+	LandUse    decimal.Decimal `json:"landUse"`
+	Management decimal.Decimal `json:"management"`
+	Input      decimal.Decimal `json:"input"`
+}
+
+// Calculate the SOC stock at the start of the project (t C/ha)
+// reference - reference SOC stock (t C/ha), see ReferenceSOC
+// landUse, management, input - factors of the land use, management and input
+// before the project, 0 if you want to get default value 1
+func InitialSOC(reference, landUse, management, input decimal.Decimal) decimal.Decimal {
+	return initialSOC(nil, reference, landUse, management, input)
+}
+
+func initialSOC(tr *Trace, reference, landUse, management, input decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("InitialSOC").
+		Input("reference", reference)
+	landUse = tr.inputOrDefault("landUse", landUse, decimal.New(1, 0))
+	management = tr.inputOrDefault("management", management, decimal.New(1, 0))
+	input = tr.inputOrDefault("input", input, decimal.New(1, 0))
+	return tr.Result(reference.Mul(landUse).Mul(management).Mul(input))
+}
+
+// Calculate the increase of SOC in monitoring zone during the stage (t CO2-e)
+// SOC increases linearly from the initial to the reference stock over 20 years,
+// not faster than 0.8 t C/ha per year, the increase is not negative and stops
+// when the whole difference of the stocks is accumulated
+// reference, initial - reference and initial SOC stocks (t C/ha)
+// area - area of monitoring zone (ha)
+// deltaTime - time elapsed between current stage and previous validated stage
+// (years), see BaselineInMonitoringZone
+// previous - SOC increase accumulated in the previous stages (t CO2-e)
+func SOCChangeInMonitoringZone(reference, initial, area, deltaTime, previous decimal.Decimal) decimal.Decimal {
+	return socChangeInMonitoringZone(nil, reference, initial, area, deltaTime, previous)
+}
+
+func socChangeInMonitoringZone(tr *Trace, reference, initial, area, deltaTime, previous decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("SOCChangeInMonitoringZone").
+		Input("reference", reference).
+		Input("initial", initial).
+		Input("area", area).
+		Input("deltaTime", deltaTime).
+		Input("previous", previous)
+	difference := decimal.Max(decimal.Zero, reference.Sub(initial))
+	rate := decimal.Min(difference.Div(decimal.New(20, 0)), decimal.NewFromFloat(0.8))
+	toCO2 := decimal.NewFromFloat(44.0 / 12.0)
+	change := toCO2.Mul(rate).Mul(area).Mul(deltaTime)
+	remaining := decimal.Max(decimal.Zero, toCO2.Mul(difference).Mul(area).Sub(previous))
+	return tr.Result(decimal.Min(change, remaining))
+}
+
+// SOC increase of the zone during the stage (t CO2-e)
+func (z MonitoringZone) socChange(tr *Trace, deltaTime, previous decimal.Decimal) decimal.Decimal {
+	soil := z.Soil
+	reference := soil.Reference
+	if reference.IsZero() {
+		reference = tr.Step("ReferenceSOC").
+			Of(soil.Climate.String() + " " + soil.SoilType.String()).
+			Result(ReferenceSOC(soil.Climate, soil.SoilType))
+	}
+	initial := initialSOC(tr, reference, soil.LandUse, soil.Management, soil.Input)
+	return socChangeInMonitoringZone(tr, reference, initial, z.Area, deltaTime, previous)
+}
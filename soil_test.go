@@ -0,0 +1,100 @@
+package carbon_calc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestReferenceSOC(t *testing.T) {
+	type Test struct {
+		climate ClimateRegion
+		soil    SoilType
+		result  float64
+	}
+	tests := []Test{
+		{ClimateTropicalMoist, SoilLowActivityClay, 47},
+		{ClimateWarmTemperateMoist, SoilHighActivityClay, 88},
+		{ClimateBoreal, SoilSpodic, 117},
+		{ClimateTropicalDry, SoilSpodic, 0},
+	}
+	for i, tt := range tests {
+		result := ReferenceSOC(tt.climate, tt.soil)
+		if result.InexactFloat64() != tt.result {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, tt.result, result)
+		}
+	}
+}
+
+func TestSOCChangeInMonitoringZone(t *testing.T) {
+	type Test struct {
+		reference, initial, area, deltaTime, previous float64
+		result                                        float64 // t C, multiplied by 44/12
+	}
+	tests := []Test{
+		// 20 t C/ha difference, 1 t C/ha per year capped at 0.8
+		{60, 40, 10, 2, 0, 0.8 * 10 * 2},
+		// 10 t C/ha difference, 0.5 t C/ha per year
+		{60, 50, 10, 2, 0, 0.5 * 10 * 2},
+		// Only the rest of the difference is left
+		{60, 50, 10, 5, 44.0 / 12 * 95, 5},
+		{60, 50, 10, 5, 44.0 / 12 * 100, 0},
+		// No loss of SOC
+		{40, 50, 10, 5, 0, 0},
+	}
+	for i, tt := range tests {
+		result := SOCChangeInMonitoringZone(
+			decimal.NewFromFloat(tt.reference),
+			decimal.NewFromFloat(tt.initial),
+			decimal.NewFromFloat(tt.area),
+			decimal.NewFromFloat(tt.deltaTime),
+			decimal.NewFromFloat(tt.previous))
+		expect := decimal.NewFromFloat(44.0 / 12).Mul(decimal.NewFromFloat(tt.result))
+		if !result.Round(6).Equal(expect.Round(6)) {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, expect, result)
+		}
+	}
+	initial := InitialSOC(decimal.New(50, 0), decimal.NewFromFloat(0.48), decimal.Zero, decimal.NewFromFloat(0.92))
+	if !initial.Equal(decimal.NewFromFloat(50 * 0.48 * 0.92)) {
+		t.Fatalf("expect: %f, have: %s", 50*0.48*0.92, initial)
+	}
+}
+
+func TestStageSOC(t *testing.T) {
+	stage := testStage()
+	stage.DeltaTime = decimal.New(5, 0)
+	stage.Zones[0].Soil = &SoilCarbon{Climate: ClimateTropicalMoist, SoilType: SoilLowActivityClay, LandUse: decimal.NewFromFloat(0.48)}
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 47 * 0.52 / 20 t C/ha per year
+	change := SOCChangeInMonitoringZone(decimal.New(47, 0), decimal.NewFromFloat(47*0.48), decimal.New(8, 0), decimal.New(5, 0), decimal.Zero)
+	if !result.SOCChange.Equal(change) || !result.SOC.Equal(change) || !result.Zones[1].SOC.IsZero() {
+		t.Fatalf("expect: %s, have: %s, %s", change, result.SOCChange, result.SOC)
+	}
+	net := NetEmissionsRemoval(result.ConservativeCarbon.Add(change), result.Baseline, stage.Leakage, result.Emissions)
+	if !result.NetEmissionsRemoval.Equal(net) {
+		t.Fatalf("expect: %s, have: %s", net, result.NetEmissionsRemoval)
+	}
+
+	stage.Previous = &result
+	next, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.SOC.Equal(change.Add(next.SOCChange)) {
+		t.Fatalf("SOC should accumulate: %s, %s", next.SOC, next.SOCChange)
+	}
+	if !next.MintedOCC.Equal(next.NetEmissionsRemoval.Sub(result.NetEmissionsRemoval)) || !next.MintedOCC.IsPositive() {
+		t.Fatalf("SOC change should be minted: %s", next.MintedOCC)
+	}
+
+	stage.Zones[0].Soil.SoilType = SoilSpodic
+	stage.Zones[0].Soil.Input = decimal.New(-1, 0)
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expect 2 ValidationErrors, have: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package carbon_calc
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrSpeciesRegistryColumns = errors.New("Species registry should have Family, Binomial and Wood density columns.")
+
+// Level of the species registry the wood density was taken from
+type DensityMatch uint8
+
+const (
+	DensityMatchNone DensityMatch = iota
+	DensityMatchSpecies
+	DensityMatchGenus
+	DensityMatchFamily
+)
+
+var densityMatchNames = map[DensityMatch]string{
+	DensityMatchNone:    "none",
+	DensityMatchSpecies: "species",
+	DensityMatchGenus:   "genus",
+	DensityMatchFamily:  "family",
+}
+
+func (m DensityMatch) String() string {
+	return enumName(densityMatchNames, m)
+}
+
+func (m DensityMatch) MarshalText() ([]byte, error) {
+	return marshalEnum(densityMatchNames, m)
+}
+
+func (m *DensityMatch) UnmarshalText(text []byte) error {
+	return unmarshalEnum(densityMatchNames, text, m)
+}
+
+// Registry of basic wood density (oven dry mass / fresh volume, g/cm^3) by
+// botanical name, with fallback to genus and family means. As in the BIOMASS
+// package, the genus and family means are the means of the species means, so
+// the well sampled species do not dominate them.
+type SpeciesRegistry struct {
+	// records by species, records identified to the genus or family only are
+	// stored by genus or family name
+	species map[string][]float64
+	// species of genus and family
+	genus  map[string]map[string]bool
+	family map[string]map[string]bool
+}
+
+func NewSpeciesRegistry() *SpeciesRegistry {
+	return &SpeciesRegistry{
+		species: map[string][]float64{},
+		genus:   map[string]map[string]bool{},
+		family:  map[string]map[string]bool{},
+	}
+}
+
+// Split the scientific name into lower case genus and binomial
+func splitScientificName(name string) (genus, binomial string) {
+	fields := strings.Fields(strings.ToLower(name))
+	if len(fields) == 0 {
+		return "", ""
+	}
+	if len(fields) == 1 {
+		return fields[0], ""
+	}
+	return fields[0], fields[0] + " " + fields[1]
+}
+
+// Lower case binomial of the scientific name, the genus if the name has no
+// species epithet, used as the key of the lookups by scientific name
+func scientificNameKey(name string) string {
+	genus, species := splitScientificName(name)
+	if species == "" {
+		return genus
+	}
+	return species
+}
+
+// Add the wood density record, multiple records of the species are averaged
+// family - botanical family, e.g. Lamiaceae
+// binomial - scientific name, e.g. Tectona grandis
+// density - basic wood density (g/cm^3)
+func (r *SpeciesRegistry) Add(family, binomial string, density float64) {
+	genus, _ := splitScientificName(binomial)
+	family = strings.ToLower(strings.TrimSpace(family))
+	taxon := scientificNameKey(binomial)
+	if taxon == "" {
+		taxon = family
+	}
+	if taxon == "" {
+		return
+	}
+	r.species[taxon] = append(r.species[taxon], density)
+	if genus != "" {
+		addTaxon(r.genus, genus, taxon)
+	}
+	if family != "" {
+		addTaxon(r.family, family, taxon)
+	}
+}
+
+func addTaxon(groups map[string]map[string]bool, group, taxon string) {
+	if groups[group] == nil {
+		groups[group] = map[string]bool{}
+	}
+	groups[group][taxon] = true
+}
+
+func mean(values []float64) float64 {
+	return Sum(values) / float64(len(values))
+}
+
+// Mean of the species means of the group
+func (r *SpeciesRegistry) groupMean(taxa map[string]bool) float64 {
+	means := make([]float64, 0, len(taxa))
+	for taxon := range taxa {
+		means = append(means, mean(r.species[taxon]))
+	}
+	sort.Float64s(means)
+	return mean(means)
+}
+
+// Basic wood density of the tree by scientific name, falls back to the genus
+// mean and then to the family mean of the species means
+// family - botanical family of the tree, used if the genus is not present in
+// the registry, empty if unknown
+func (r *SpeciesRegistry) WoodDensity(scientificName, family string) (decimal.Decimal, DensityMatch) {
+	genus, species := splitScientificName(scientificName)
+	if values, ok := r.species[species]; ok {
+		return decimal.NewFromFloat(mean(values)), DensityMatchSpecies
+	}
+	if taxa, ok := r.genus[genus]; ok {
+		return decimal.NewFromFloat(r.groupMean(taxa)), DensityMatchGenus
+	}
+	if taxa, ok := r.family[strings.ToLower(strings.TrimSpace(family))]; ok {
+		return decimal.NewFromFloat(r.groupMean(taxa)), DensityMatchFamily
+	}
+	return decimal.Decimal{}, DensityMatchNone
+}
+
+// Wood density of the tree from the registry, falls back to
+// DensityOverBarkOfTrees of the parameter set if the tree is not present
+func (r *SpeciesRegistry) Density(ps *ParameterSet, scientificName, family string, forestType ForestType, specie TreeSpecies, rainfall RainfallType) (decimal.Decimal, DensityMatch) {
+	if r != nil {
+		if value, match := r.WoodDensity(scientificName, family); match != DensityMatchNone {
+			return value, match
+		}
+	}
+	return ps.DensityOverBarkOfTrees(forestType, specie, rainfall), DensityMatchNone
+}
+
+// Read the registry from CSV in the format of the Global Wood Density Database
+// (Family, Binomial, Wood density (g/cm^3) columns, other columns are ignored)
+func ReadSpeciesRegistryCSV(r io.Reader) (*SpeciesRegistry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	familyCol, binomialCol, densityCol := -1, -1, -1
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "family":
+			familyCol = i
+		case name == "binomial":
+			binomialCol = i
+		case strings.HasPrefix(name, "wood density"):
+			densityCol = i
+		}
+	}
+	if familyCol < 0 || binomialCol < 0 || densityCol < 0 {
+		return nil, ErrSpeciesRegistryColumns
+	}
+	registry := NewSpeciesRegistry()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) <= densityCol || len(record) <= familyCol || len(record) <= binomialCol {
+			return nil, fmt.Errorf("Species registry line %d: not enough columns.", line)
+		}
+		density, err := strconv.ParseFloat(strings.TrimSpace(record[densityCol]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Species registry line %d: %w", line, err)
+		}
+		registry.Add(record[familyCol], record[binomialCol], density)
+	}
+	return registry, nil
+}
+
+// Load the registry from CSV file, see ReadSpeciesRegistryCSV
+func LoadSpeciesRegistry(path string) (*SpeciesRegistry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadSpeciesRegistryCSV(file)
+}
@@ -0,0 +1,96 @@
+package carbon_calc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+const testWoodDensityCSV = `Number,Family,Binomial,Wood density (g/cm^3), oven dry mass/fresh volume,Region,Reference Number
+1,Lamiaceae,Tectona grandis,0.55,South-East Asia (tropical),1
+2,Lamiaceae,Tectona grandis,0.59,South-East Asia (tropical),2
+3,Lamiaceae,Tectona philippinensis,0.7,South-East Asia (tropical),3
+4,Lamiaceae,Vitex parviflora,0.8,South-East Asia (tropical),4
+5,Malvaceae,Ochroma pyramidale,0.1,South America (tropical),5
+6,Malvaceae,Ceiba pentandra,0.26,Africa (tropical),6
+`
+
+func TestSpeciesRegistryWoodDensity(t *testing.T) {
+	type Test struct {
+		name, family string
+		match        DensityMatch
+		result       float64 // precision = 3
+	}
+	tests := []Test{
+		{"Tectona grandis", "", DensityMatchSpecies, 0.57},
+		{"tectona  Grandis L.f.", "", DensityMatchSpecies, 0.57},
+		// mean of the species means 0.57 and 0.7
+		{"Tectona hamiltoniana", "", DensityMatchGenus, 0.635},
+		{"Ochroma pyramidale", "", DensityMatchSpecies, 0.1},
+		{"Ochroma lagopus", "Malvaceae", DensityMatchGenus, 0.1},
+		{"Durio zibethinus", "Malvaceae", DensityMatchFamily, 0.18},
+		{"Gmelina arborea", "Lamiaceae", DensityMatchFamily, 0.69},
+		{"Pinus caribaea", "", DensityMatchNone, 0},
+	}
+	registry, err := ReadSpeciesRegistryCSV(strings.NewReader(testWoodDensityCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		result, match := registry.WoodDensity(tt.name, tt.family)
+		if match != tt.match {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, tt.match, match)
+		}
+		if result.Round(3).InexactFloat64() != tt.result {
+			t.Fatalf("Test number %d, expect: %f, have: %f", i, tt.result, result.InexactFloat64())
+		}
+	}
+}
+
+func TestSpeciesRegistryDensityFallback(t *testing.T) {
+	var registry *SpeciesRegistry
+	density, match := registry.Density(DefaultParameterSet(), "Tectona grandis", "", ForestTypeTemperate, TreeSpeciesBroadleaf, RainfallTypeDry)
+	if match != DensityMatchNone || !density.Equal(DensityOverBarkOfTrees(ForestTypeTemperate, TreeSpeciesBroadleaf, RainfallTypeDry)) {
+		t.Fatalf("expect: %s, have: %s", DensityOverBarkOfTrees(ForestTypeTemperate, TreeSpeciesBroadleaf, RainfallTypeDry), density)
+	}
+}
+
+func TestReadSpeciesRegistryCSVErrors(t *testing.T) {
+	tests := []string{
+		"Number,Family,Region\n1,Lamiaceae,Asia\n",
+		"Family,Binomial,Wood density\nLamiaceae,Tectona grandis,heavy\n",
+		"Family,Binomial,Wood density\nLamiaceae,Tectona grandis\n",
+	}
+	for i, tt := range tests {
+		if _, err := ReadSpeciesRegistryCSV(strings.NewReader(tt)); err == nil {
+			t.Fatalf("Test number %d, expect error", i)
+		}
+	}
+}
+
+func TestStageSpeciesRegistry(t *testing.T) {
+	registry, err := ReadSpeciesRegistryCSV(strings.NewReader(testWoodDensityCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := testStage()
+	stage.Species = registry
+	stage.Explain = true
+	tree := &stage.Zones[0].Plots[0].Trees[0]
+	tree.ScientificName = "Tectona grandis"
+	tree.Density = nil
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeResult := result.Zones[0].Plots[0].Trees[0]
+	expect := CarbonPerTree(*tree.Fraction, tree.Radius, tree.Height, *tree.Form, decimal.NewFromFloat(0.57), *tree.Biomass, *tree.Ratio)
+	if treeResult.DensityMatch != DensityMatchSpecies || !treeResult.Carbon.Round(12).Equal(expect.Round(12)) {
+		t.Fatalf("expect: %s, have: %s %s", expect, treeResult.DensityMatch, treeResult.Carbon)
+	}
+	steps := result.Trace.Find("DensityOverBarkOfTrees")
+	if len(steps) == 0 || steps[0].Source != DensityMatchSpecies.String() {
+		t.Fatalf("expect density source: %s, have: %v", DensityMatchSpecies, steps)
+	}
+}
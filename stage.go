@@ -0,0 +1,708 @@
+package carbon_calc
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Tree measured on the ground in a sample plot
+// radius - radius of tree (m)
+// height - height of tree (m)
+// fraction, form, density, biomass, ratio - optional tree parameters, nil
+// values are replaced with the defaults of CarbonPerTreeWith or derived from
+// the monitoring zone forest type, species and rainfall, zero is a legal value
+// scientificName, family - botanical name of the tree, used to take the density
+// from the species registry of the stage
+type Tree struct {
+	ID             string           `json:"id"`
+	ScientificName string           `json:"scientificName,omitempty"`
+	Family         string           `json:"family,omitempty"`
+	Radius         decimal.Decimal  `json:"radius"`
+	Height         decimal.Decimal  `json:"height"`
+	Fraction       *decimal.Decimal `json:"fraction,omitempty"`
+	Form           *decimal.Decimal `json:"form,omitempty"`
+	Density        *decimal.Decimal `json:"density,omitempty"`
+	Biomass        *decimal.Decimal `json:"biomass,omitempty"`
+	Ratio          *decimal.Decimal `json:"ratio,omitempty"`
+}
+
+// Sample plot of monitoring zone
+// area - area of sample plot (ha)
+// standingDead, lyingDeadWood, litter - measurements of the dead wood and
+// litter pools, used if the pool of the monitoring zone is measured
+type Plot struct {
+	ID            string             `json:"id"`
+	Area          decimal.Decimal    `json:"area"`
+	Trees         []Tree             `json:"trees"`
+	StandingDead  []StandingDeadTree `json:"standingDead,omitempty"`
+	LyingDeadWood *LineIntersect     `json:"lyingDeadWood,omitempty"`
+	Litter        []LitterSample     `json:"litter,omitempty"`
+}
+
+// Tree biomass monitoring zone
+// area - area of monitoring zone (ha)
+// baseline - mean change in carbon stock in trees per ha and per year, see
+// BaselineInMonitoringZone
+// baselineModel - baseline modeled from the pre-project tree inventory, the
+// baseline must be 0 if present
+// abovegroundBiomass - used to select the root-shoot ratio, 0 if you want to get
+// default value
+// ecologicalZone - IPCC global ecological zone, if present the root-shoot ratio
+// is selected by ecological zone instead of forest type and rainfall
+// equation - allometric equation of the zone, nil if you want to use
+// CarbonPerTree form factor model
+// equationName - name of the built-in allometric equation of the zone, see
+// AllometricEquationByName, used if equation is nil
+// deadWood, litter - way the pools are estimated, the pools are excluded by
+// default
+// soil - soil organic carbon of the zone, nil if SOC is not accounted
+// shrubs - shrubs of the zone, nil if shrubs are not accounted
+type MonitoringZone struct {
+	ID                 string             `json:"id"`
+	Area               decimal.Decimal    `json:"area"`
+	ForestType         ForestType         `json:"forestType"`
+	Species            TreeSpecies        `json:"species"`
+	Rainfall           RainfallType       `json:"rainfall"`
+	EcologicalZone     *EcologicalZone    `json:"ecologicalZone,omitempty"`
+	AbovegroundBiomass float64            `json:"abovegroundBiomass"`
+	Baseline           decimal.Decimal    `json:"baseline"`
+	BaselineModel      *BaselineModel     `json:"baselineModel,omitempty"`
+	DeadWood           PoolMode           `json:"deadWood"`
+	Litter             PoolMode           `json:"litter"`
+	Soil               *SoilCarbon        `json:"soil,omitempty"`
+	Shrubs             *Shrubs            `json:"shrubs,omitempty"`
+	Plots              []Plot             `json:"plots"`
+	Equation           AllometricEquation `json:"-"`
+	EquationName       string             `json:"equation,omitempty"`
+}
+
+// Nitrogen fertilizer applied in the project during the stage
+// applications - fertilizes argument of NetGHGEmissions
+// For more comments on the other fields see CO2eNdirectt, NfertVolatIT and
+// NfertLeachIT functions, nil values are replaced with their defaults, zero is
+// a legal value, see Optional
+type Fertilizer struct {
+	Applications    decimal.Decimal  `json:"applications"`
+	MassSynthFertz  decimal.Decimal  `json:"massSynthFertz"`
+	NContSynthFertz *decimal.Decimal `json:"nContSynthFertz,omitempty"`
+	MassOrgFertz    decimal.Decimal  `json:"massOrgFertz"`
+	NContOrgFertz   *decimal.Decimal `json:"nContOrgFertz,omitempty"`
+	NitrOxdEmissSOC *decimal.Decimal `json:"nitrOxdEmissSOC,omitempty"`
+	AllFractSynth   *decimal.Decimal `json:"allFractSynth,omitempty"`
+	AllFractOrg     *decimal.Decimal `json:"allFractOrg,omitempty"`
+	NitrOxdEmissWS  *decimal.Decimal `json:"nitrOxdEmissWS,omitempty"`
+	NFractSoil      *decimal.Decimal `json:"nFractSoil,omitempty"`
+	NitrOxdEmissLR  *decimal.Decimal `json:"nitrOxdEmissLR,omitempty"`
+	GWarmingPotentl *decimal.Decimal `json:"gWarmingPotentl,omitempty"`
+}
+
+// Validated stage of the project
+// deltaTime - time elapsed between current stage and previous validated stage
+// (years)
+// leakage - leakage fraction, see NetEmissionsRemoval, must be 0 if the
+// leakage is calculated from the displacements and fuelwood
+// displacements, fuelwood - sources of the leakage, see ProjectLeakage
+// otherEmissions - emissions from other sources, added to fertilizer emissions
+// burning - biomass burning in the project area during the stage, added to the
+// emissions
+// fuels - fossil fuel consumed by the project vehicles and machinery during the
+// stage, added to the emissions
+// gwp - GWP set of the global warming potentials not given in the emissions
+// inputs, AR5 by default
+// bufferPercent, holdersPercent - nil if you want to get default value, 0 is
+// a legal value, see OCCBufferPool and OCCHolders
+// previous - result of the previous validated stage, nil for the first stage
+// explain - record the calculation trace of every formula in the result
+// confidence - two-sided confidence level of the uncertainty, 0 if you want to
+// get default value 0.9
+// standard - carbon standard of the project, selects the discount schedule
+// discount - custom table of the uncertainty discount, nil if you want to get
+// the schedule of the standard
+// linearDiscount - custom linear deduction of the uncertainty, nil if you want
+// to get the schedule of the standard, must be nil if the discount is given
+// schedule - custom discount schedule, takes precedence over the discount, the
+// linear discount and the standard
+// parameters - parameter set of the project, nil if you want to get
+// DefaultParameterSet
+// species - registry of the wood density by scientific name of the tree, nil if
+// you want to take density from the parameter set only
+// equations - allometric equations by scientific name of the tree, take
+// precedence over the equation of the zone, the names are matched case
+// insensitive by binomial as in the species registry
+// equationNames - names of the built-in allometric equations by scientific name
+// of the tree, see AllometricEquationByName, used for the trees not present in
+// equations
+type Stage struct {
+	Zones          []MonitoringZone              `json:"zones"`
+	DeltaTime      decimal.Decimal               `json:"deltaTime"`
+	Leakage        decimal.Decimal               `json:"leakage"`
+	Displacements  []Displacement                `json:"displacements,omitempty"`
+	Fuelwood       []FuelwoodCollection          `json:"fuelwood,omitempty"`
+	Fertilizers    []Fertilizer                  `json:"fertilizers"`
+	OtherEmissions decimal.Decimal               `json:"otherEmissions"`
+	Burning        []BurningEvent                `json:"burning,omitempty"`
+	Fuels          []FuelConsumption             `json:"fuels,omitempty"`
+	GWP            GWPSet                        `json:"gwp"`
+	BufferPercent  *decimal.Decimal              `json:"bufferPercent,omitempty"`
+	HoldersPercent *decimal.Decimal              `json:"holdersPercent,omitempty"`
+	Previous       *StageResult                  `json:"previous,omitempty"`
+	Explain        bool                          `json:"explain"`
+	Confidence     float64                       `json:"confidence"`
+	Standard       Standard                      `json:"standard"`
+	Discount       *DiscountTable                `json:"discount,omitempty"`
+	LinearDiscount *LinearDiscount               `json:"linearDiscount,omitempty"`
+	Schedule       DiscountSchedule              `json:"-"`
+	Parameters     *ParameterSet                 `json:"parameters,omitempty"`
+	Species        *SpeciesRegistry              `json:"-"`
+	Equations      map[string]AllometricEquation `json:"-"`
+	EquationNames  map[string]string             `json:"equations,omitempty"`
+	// Sampled parameter factors of the Monte Carlo iteration
+	factors parameterFactors
+	// Equations and equation names by scientificNameKey
+	equationsByKey map[string]AllometricEquation
+}
+
+type TreeResult struct {
+	ID string `json:"id"`
+	// Trees under 1.3 m are not considered in the carbon calculation
+	Excluded bool            `json:"excluded"`
+	Carbon   decimal.Decimal `json:"carbon"`
+	// Level of the species registry the density was taken from
+	DensityMatch DensityMatch `json:"densityMatch,omitempty"`
+}
+
+// carbon - carbon stock in trees of the plot
+// deadWood, litter - carbon stock in dead wood and litter per ha
+// carbonPerHa - carbon stock in the sampled pools per ha, the shrubs of the
+// zone are not sampled by plot and are not included
+type PlotResult struct {
+	ID          string          `json:"id"`
+	Trees       []TreeResult    `json:"trees"`
+	Carbon      decimal.Decimal `json:"carbon"`
+	DeadWood    decimal.Decimal `json:"deadWood"`
+	Litter      decimal.Decimal `json:"litter"`
+	CarbonPerHa decimal.Decimal `json:"carbonPerHa"`
+}
+
+// carbon - carbon stock in all pools of the zone
+// deadWood, litter - carbon stock in dead wood and litter of the zone
+// shrubs - carbon stock in shrubs of the zone, included in the carbon and in
+// the conservative carbon without the uncertainty discount
+// baselineShrubs - carbon stock in shrubs of the zone in the baseline, see
+// StageResult.BaselineShrubs
+// socChange - SOC increase during the stage, see SOCChangeInMonitoringZone
+// soc - SOC increase accumulated since the start of the project
+// baselineCurve - baseline curve of the zone with the baseline model
+type ZoneResult struct {
+	ID                 string          `json:"id"`
+	Plots              []PlotResult    `json:"plots"`
+	Carbon             decimal.Decimal `json:"carbon"`
+	DeadWood           decimal.Decimal `json:"deadWood"`
+	Litter             decimal.Decimal `json:"litter"`
+	Shrubs             decimal.Decimal `json:"shrubs"`
+	BaselineShrubs     decimal.Decimal `json:"baselineShrubs"`
+	ConservativeCarbon decimal.Decimal `json:"conservativeCarbon"`
+	AbovegroundBiomass decimal.Decimal `json:"abovegroundBiomass"`
+	Baseline           decimal.Decimal `json:"baseline"`
+	BaselineCurve      BaselineCurve   `json:"baselineCurve,omitempty"`
+	SOCChange          decimal.Decimal `json:"socChange"`
+	SOC                decimal.Decimal `json:"soc"`
+	MintedOCC          decimal.Decimal `json:"mintedOCC"`
+}
+
+// Every intermediate value of the stage calculation
+type StageResult struct {
+	// Years since the start of the project at the end of the stage, the sum of
+	// the delta time of the stages
+	Year        decimal.Decimal `json:"year"`
+	Zones       []ZoneResult    `json:"zones"`
+	TotalArea   decimal.Decimal `json:"totalArea"`
+	TotalCarbon decimal.Decimal `json:"totalCarbon"`
+	// Carbon stock in dead wood, litter and shrubs, included in the total
+	// carbon, the shrubs are estimated for the whole zone and are added after
+	// the uncertainty discount, they do not change the uncertainty
+	DeadWood decimal.Decimal `json:"deadWood"`
+	Litter   decimal.Decimal `json:"litter"`
+	Shrubs   decimal.Decimal `json:"shrubs"`
+	// Carbon stock in shrubs in the baseline, a standing stock like the total
+	// carbon and not a change like the baseline, it is deducted from the
+	// conservative carbon in the net emissions removal, so it is deducted
+	// once from the accumulated net emissions removal of the project
+	BaselineShrubs decimal.Decimal `json:"baselineShrubs"`
+	Confidence     float64         `json:"confidence"`
+	TDistribution  decimal.Decimal `json:"tDistribution"`
+	Uncertainty    decimal.Decimal `json:"uncertainty"`
+	// Stratified estimate of the total carbon, see StratifiedEstimator
+	CarbonVariance      decimal.Decimal `json:"carbonVariance"`
+	CarbonStandardError decimal.Decimal `json:"carbonStandardError"`
+	DegreesOfFreedom    int             `json:"degreesOfFreedom"`
+	CarbonLower         decimal.Decimal `json:"carbonLower"`
+	CarbonUpper         decimal.Decimal `json:"carbonUpper"`
+	DiscountSchedule    string          `json:"discountSchedule"`
+	UncertaintyDiscount decimal.Decimal `json:"uncertaintyDiscount"`
+	// Fraction of the total carbon deducted for the uncertainty
+	UncertaintyDeduction decimal.Decimal `json:"uncertaintyDeduction"`
+	ConservativeCarbon   decimal.Decimal `json:"conservativeCarbon"`
+	// SOC increase during the stage and accumulated since the start of the
+	// project, the accumulated increase is added to the conservative carbon
+	// in the net emissions removal
+	SOCChange decimal.Decimal `json:"socChange"`
+	SOC       decimal.Decimal `json:"soc"`
+	Baseline  decimal.Decimal `json:"baseline"`
+	Emissions decimal.Decimal `json:"emissions"`
+	// Itemized emissions, see ProjectEmissions
+	EmissionsStatement EmissionsStatement `json:"emissionsStatement"`
+	// CH4 and N2O emissions of the biomass burning, included in the emissions
+	BurningEmissions decimal.Decimal `json:"burningEmissions"`
+	// Emissions of the fossil fuel combustion, included in the emissions
+	FuelEmissions decimal.Decimal `json:"fuelEmissions"`
+	// Itemized leakage, see ProjectLeakage, and its fraction used in the net
+	// emissions removal
+	LeakageStatement    LeakageStatement `json:"leakageStatement"`
+	Leakage             decimal.Decimal  `json:"leakage"`
+	NetEmissionsRemoval decimal.Decimal  `json:"netEmissionsRemoval"`
+	MintedOCC           decimal.Decimal  `json:"mintedOCC"`
+	BufferPool          decimal.Decimal  `json:"bufferPool"`
+	Holders             decimal.Decimal  `json:"holders"`
+	ParameterSet        string           `json:"parameterSet"`
+	// Defaults substituted for the options of the stage not provided, by the
+	// name of the option, e.g. bufferPercent or fertilizers[0].nFractSoil
+	Defaults Defaults `json:"defaults"`
+	Trace    *Trace   `json:"trace,omitempty"`
+}
+
+// Zone result of the stage by zone id, nil if zone is not present
+func (r *StageResult) Zone(id string) *ZoneResult {
+	if r == nil {
+		return nil
+	}
+	for i := range r.Zones {
+		if r.Zones[i].ID == id {
+			return &r.Zones[i]
+		}
+	}
+	return nil
+}
+
+// Calculate the carbon stored in the tree with missing parameters taken from
+// the monitoring zone
+func (s Stage) treeCarbon(tr *Trace, ps *ParameterSet, z MonitoringZone, tree Tree) (decimal.Decimal, DensityMatch, error) {
+	if tree.Height.Cmp(decimal.NewFromFloat(1.3)) == -1 {
+		return decimal.Decimal{}, DensityMatchNone, NotEnoughHeight
+	}
+	var density decimal.Decimal
+	match := DensityMatchNone
+	if tree.Density != nil {
+		density = *tree.Density
+	} else {
+		step := tr.Step("DensityOverBarkOfTrees")
+		if tree.ScientificName != "" || tree.Family != "" {
+			step.Of(tree.ScientificName)
+			density, match = s.Species.Density(ps, tree.ScientificName, tree.Family, z.ForestType, z.Species, z.Rainfall)
+			step.From(match.String())
+		} else {
+			density = ps.DensityOverBarkOfTrees(z.ForestType, z.Species, z.Rainfall)
+		}
+		step.Result(density)
+	}
+	var biomass decimal.Decimal
+	if tree.Biomass != nil {
+		biomass = *tree.Biomass
+	} else {
+		biomass = tr.Step("BiomassExpansionFactor").
+			Result(ps.BiomassExpansionFactor(z.ForestType, z.Species))
+	}
+	var ratio decimal.Decimal
+	if tree.Ratio != nil {
+		ratio = *tree.Ratio
+	} else {
+		ratio = z.rootShootRatio(tr, ps)
+	}
+	density, biomass, ratio = s.factors.apply(density, biomass, ratio)
+	equation := z.equation()
+	if byName, ok := s.equationsByKey[scientificNameKey(tree.ScientificName)]; ok {
+		equation = byName
+	}
+	if equation == nil {
+		return carbonPerTree(tr, CarbonPerTreeOptions{
+			Fraction: tree.Fraction,
+			Radius:   tree.Radius,
+			Height:   tree.Height,
+			Form:     tree.Form,
+			Density:  density,
+			Biomass:  biomass,
+			Ratio:    ratio,
+		}), match, nil
+	}
+	m := TreeMeasurement{
+		Radius:  tree.Radius,
+		Height:  tree.Height,
+		Density: density,
+		Biomass: biomass,
+	}
+	if tree.Form != nil {
+		m.Form = *tree.Form
+	}
+	return carbonPerTreeAllometric(tr, equation, m, tree.Fraction, ratio), match, nil
+}
+
+// Allometric equation of the zone, nil for CarbonPerTree form factor model
+func (z MonitoringZone) equation() AllometricEquation {
+	if z.Equation != nil || z.EquationName == "" {
+		return z.Equation
+	}
+	equation, _ := AllometricEquationByName(z.EquationName)
+	return equation
+}
+
+// Equations of the stage by scientificNameKey, the equations take precedence
+// over the equation names
+func (s Stage) equations() map[string]AllometricEquation {
+	equations := make(map[string]AllometricEquation, len(s.Equations)+len(s.EquationNames))
+	for name, equationName := range s.EquationNames {
+		if equation, err := AllometricEquationByName(equationName); err == nil {
+			equations[scientificNameKey(name)] = equation
+		}
+	}
+	for name, equation := range s.Equations {
+		equations[scientificNameKey(name)] = equation
+	}
+	return equations
+}
+
+func (z MonitoringZone) rootShootRatio(tr *Trace, ps *ParameterSet) decimal.Decimal {
+	if z.EcologicalZone != nil {
+		return tr.Step("RootShootRatioForZone").
+			Of(z.EcologicalZone.String()).
+			Input("abovegroundBiomass", decimal.NewFromFloat(z.AbovegroundBiomass)).
+			Result(ps.RootShootRatioForZone(*z.EcologicalZone, z.Species, z.AbovegroundBiomass))
+	}
+	return tr.Step("RootShootRatioForTree").
+		Input("abovegroundBiomass", decimal.NewFromFloat(z.AbovegroundBiomass)).
+		Result(ps.RootShootRatioForTree(z.ForestType, z.Species, z.Rainfall, z.AbovegroundBiomass))
+}
+
+func (s Stage) discount() DiscountSchedule {
+	if s.Schedule != nil {
+		return s.Schedule
+	}
+	if s.LinearDiscount != nil {
+		return *s.LinearDiscount
+	}
+	if s.Discount != nil {
+		return *s.Discount
+	}
+	return s.Standard.DiscountSchedule()
+}
+
+func (s Stage) parameters() *ParameterSet {
+	if s.Parameters == nil {
+		return DefaultParameterSet()
+	}
+	return s.Parameters
+}
+
+// Calculate the carbon stored in each tree, plot and monitoring zone, the
+// uncertainty, emissions, net emissions removal and the OCCs to be minted for
+// the stage, returns ValidationErrors if the stage is not valid
+func (s Stage) Calculate() (StageResult, error) {
+	if err := s.Validate(); err != nil {
+		return StageResult{}, err
+	}
+	ps := s.parameters()
+	s.equationsByKey = s.equations()
+	startYear := decimal.Zero
+	if s.Previous != nil {
+		startYear = s.Previous.Year
+	}
+	result := StageResult{
+		Year:         startYear.Add(s.DeltaTime),
+		TotalArea:    decimal.Zero,
+		TotalCarbon:  decimal.Zero,
+		ParameterSet: ps.Version,
+	}
+	var tr *Trace
+	if s.Explain {
+		tr = NewTrace("Stage")
+		result.Trace = tr
+	}
+	carbonedZones := make([]CarbonedZone, 0, len(s.Zones))
+	zoneTraces := make([]*Trace, 0, len(s.Zones))
+	numPlots := 0
+	for _, zone := range s.Zones {
+		zoneTrace := tr.Step("MonitoringZone").Of(zone.ID)
+		zoneTraces = append(zoneTraces, zoneTrace)
+		zoneResult := ZoneResult{ID: zone.ID}
+		plots := make([]decimal.Decimal, 0, len(zone.Plots))
+		var deadWoods, litters []decimal.Decimal
+		shrubs, baselineShrubs := decimal.Zero, decimal.Zero
+		if zone.Shrubs != nil {
+			shrubs, baselineShrubs = zone.Shrubs.carbonPerHa(zoneTrace)
+		}
+		for _, plot := range zone.Plots {
+			plotTrace := zoneTrace.Step("Plot").Of(plot.ID)
+			plotResult := PlotResult{ID: plot.ID, Carbon: decimal.Zero}
+			for _, tree := range plot.Trees {
+				treeTrace := plotTrace.Step("Tree").Of(tree.ID)
+				carbon, match, err := s.treeCarbon(treeTrace, ps, zone, tree)
+				if err == NotEnoughHeight {
+					plotResult.Trees = append(plotResult.Trees, TreeResult{ID: tree.ID, Excluded: true, Carbon: decimal.Zero})
+					treeTrace.Result(decimal.Zero)
+					continue
+				}
+				if err != nil {
+					return StageResult{}, err
+				}
+				plotResult.Trees = append(plotResult.Trees, TreeResult{ID: tree.ID, Carbon: carbon, DensityMatch: match})
+				plotResult.Carbon = plotResult.Carbon.Add(treeTrace.Result(carbon))
+			}
+			carbonPerHa, err := validateCarbonStoredInPlot(plotTrace, plotResult.Carbon, plot.Area)
+			if err != nil {
+				return StageResult{}, err
+			}
+			deadWood, litter, err := s.plotPools(plotTrace, ps, zone, plot, carbonPerHa)
+			if err != nil {
+				return StageResult{}, err
+			}
+			plotResult.DeadWood = deadWood
+			plotResult.Litter = litter
+			plotResult.CarbonPerHa = plotTrace.Result(carbonPerHa.Add(deadWood).Add(litter))
+			plots = append(plots, plotResult.CarbonPerHa)
+			deadWoods = append(deadWoods, deadWood)
+			litters = append(litters, litter)
+			zoneResult.Plots = append(zoneResult.Plots, plotResult)
+		}
+		zoneCarbon, err := validateCarbonStoredInMonitoringZone(zoneTrace, SumDecimal(plots), decimal.NewFromInt(int64(len(plots))), zone.Area)
+		if err != nil {
+			return StageResult{}, err
+		}
+		zoneResult.Carbon = zoneTrace.Result(zoneCarbon)
+		numZonePlots := decimal.NewFromInt(int64(len(plots)))
+		zoneResult.DeadWood = SumDecimal(deadWoods).Div(numZonePlots).Mul(zone.Area)
+		zoneResult.Litter = SumDecimal(litters).Div(numZonePlots).Mul(zone.Area)
+		zoneResult.Shrubs = shrubs.Mul(zone.Area)
+		zoneResult.BaselineShrubs = baselineShrubs.Mul(zone.Area)
+		result.DeadWood = result.DeadWood.Add(zoneResult.DeadWood)
+		result.Litter = result.Litter.Add(zoneResult.Litter)
+		result.Shrubs = result.Shrubs.Add(zoneResult.Shrubs)
+		result.BaselineShrubs = result.BaselineShrubs.Add(zoneResult.BaselineShrubs)
+		if zone.BaselineModel != nil {
+			curve, err := s.baselineCurve(zoneTrace, ps, zone, result.Year)
+			if err != nil {
+				return StageResult{}, err
+			}
+			zoneResult.BaselineCurve = curve
+			zoneResult.Baseline = zoneTrace.Step("BaselineInterval").
+				Input("from", startYear).
+				Input("to", result.Year).
+				Result(curve.Interval(startYear, result.Year))
+		} else {
+			zoneResult.Baseline = baselineInMonitoringZone(zoneTrace, zone.Baseline, zone.Area, s.DeltaTime)
+		}
+		if zone.Soil != nil {
+			previousSOC := decimal.Zero
+			if previous := s.Previous.Zone(zone.ID); previous != nil {
+				previousSOC = previous.SOC
+			}
+			zoneResult.SOCChange = zone.socChange(zoneTrace, s.DeltaTime, previousSOC)
+			zoneResult.SOC = previousSOC.Add(zoneResult.SOCChange)
+			result.SOCChange = result.SOCChange.Add(zoneResult.SOCChange)
+			result.SOC = result.SOC.Add(zoneResult.SOC)
+		}
+		carbonedZones = append(carbonedZones, CarbonedZone{Plots: plots, Area: zone.Area})
+		numPlots += len(plots)
+		result.TotalArea = result.TotalArea.Add(zone.Area)
+		result.TotalCarbon = result.TotalCarbon.Add(zoneResult.Carbon)
+		result.Zones = append(result.Zones, zoneResult)
+	}
+
+	interval, err := uncertaintyCarbonStoredInterval(tr, s.Confidence, result.TotalArea, carbonedZones)
+	if err != nil {
+		return StageResult{}, err
+	}
+	result.Confidence = interval.Confidence
+	result.TDistribution = interval.TDistribution
+	result.Uncertainty = interval.Uncertainty
+	estimate := stratifiedEstimate(tr, interval.TDistribution, carbonedZones)
+	result.CarbonVariance = estimate.Variance
+	result.CarbonStandardError = estimate.StandardError
+	result.DegreesOfFreedom = estimate.DegreesOfFreedom
+	result.CarbonLower = estimate.Lower
+	result.CarbonUpper = estimate.Upper
+	discount := s.discount()
+	result.DiscountSchedule = discount.String()
+	result.UncertaintyDiscount = scheduleDiscount(discount, result.Uncertainty)
+	result.UncertaintyDeduction = discount.Deduction(result.Uncertainty)
+	result.ConservativeCarbon = conservativeTotalCarbon(tr, result.TotalCarbon, result.Uncertainty, discount)
+
+	baselines := make([]decimal.Decimal, 0, len(result.Zones))
+	for i, zone := range s.Zones {
+		zoneTrace := zoneTraces[i]
+		zoneResult := &result.Zones[i]
+		conservativeCarbon, err := validateAreaConservativeCarbon(zoneTrace, result.ConservativeCarbon, zoneResult.Carbon, result.TotalCarbon)
+		if err != nil {
+			return StageResult{}, err
+		}
+		zoneResult.ConservativeCarbon = conservativeCarbon
+		// Biomass of the trees only, without the share of dead wood and
+		// litter
+		treeCarbon := zoneResult.ConservativeCarbon
+		if pools := zoneResult.DeadWood.Add(zoneResult.Litter); !pools.IsZero() {
+			treeCarbon = treeCarbon.Mul(zoneResult.Carbon.Sub(pools)).Div(zoneResult.Carbon)
+		}
+		zoneResult.AbovegroundBiomass = aboveGroundBiomass(zoneTrace, AboveGroundBiomassOptions{
+			AreaConsCarbon: treeCarbon,
+			Ratio:          zone.rootShootRatio(zoneTrace, ps),
+			Area:           zone.Area,
+		})
+		baselines = append(baselines, zoneResult.Baseline)
+		zoneResult.Carbon = zoneResult.Carbon.Add(zoneResult.Shrubs)
+		zoneResult.ConservativeCarbon = zoneResult.ConservativeCarbon.Add(zoneResult.Shrubs)
+	}
+	result.Baseline = baseline(tr, baselines)
+	result.TotalCarbon = result.TotalCarbon.Add(result.Shrubs)
+	result.ConservativeCarbon = result.ConservativeCarbon.Add(result.Shrubs)
+
+	result.EmissionsStatement = projectEmissions(tr, s.Fertilizers, s.Burning, s.Fuels, s.OtherEmissions, s.GWP)
+	result.Emissions = result.EmissionsStatement.Total
+	result.BurningEmissions = result.EmissionsStatement.BySource(EmissionBurning)
+	result.FuelEmissions = result.EmissionsStatement.BySource(EmissionFuel)
+	cTotalCarbon := result.ConservativeCarbon.Add(result.SOC).Sub(result.BaselineShrubs)
+	if len(s.Displacements) > 0 || len(s.Fuelwood) > 0 {
+		result.LeakageStatement = projectLeakage(tr, s.Displacements, s.Fuelwood, cTotalCarbon)
+	} else {
+		result.LeakageStatement = LeakageStatement{Items: []LeakageItem{}, Total: cTotalCarbon.Mul(s.Leakage), Fraction: s.Leakage}
+	}
+	result.Leakage = result.LeakageStatement.Fraction
+	result.NetEmissionsRemoval = netEmissionsRemoval(tr, cTotalCarbon, result.Baseline, result.Leakage, result.Emissions)
+
+	previousNet := decimal.Zero
+	previousCarbon := decimal.Zero
+	if s.Previous != nil {
+		previousNet = s.Previous.NetEmissionsRemoval
+		previousCarbon = s.Previous.ConservativeCarbon
+	}
+	result.MintedOCC = mintedOCC(tr, result.NetEmissionsRemoval, previousNet)
+	buffer := OCCShareOptions{MintedOCC: result.MintedOCC, Percent: s.BufferPercent}
+	holders := OCCShareOptions{MintedOCC: result.MintedOCC, Percent: s.HoldersPercent}
+	result.BufferPool = occBufferPool(tr, buffer)
+	result.Holders = occHolders(tr, holders)
+	result.Defaults = s.defaults(ps, buffer, holders)
+	for i := range result.Zones {
+		zoneResult := &result.Zones[i]
+		previousZoneCarbon := decimal.Zero
+		if previous := s.Previous.Zone(zoneResult.ID); previous != nil {
+			previousZoneCarbon = previous.ConservativeCarbon
+		}
+		minted, err := validateOCCMintedPerMonitoringZone(zoneTraces[i], result.MintedOCC,
+			result.ConservativeCarbon, zoneResult.ConservativeCarbon,
+			previousCarbon, previousZoneCarbon)
+		if err == ErrNoCarbonChange {
+			minted = decimal.Zero
+		} else if err != nil {
+			return StageResult{}, err
+		}
+		zoneResult.MintedOCC = minted
+	}
+	tr.Result(result.MintedOCC)
+	return result, nil
+}
+
+// Defaults substituted for the tree parameters, the fertilizer options and the
+// shares of the minted OCCs, by the name of the option in the stage
+func (s Stage) defaults(ps *ParameterSet, buffer, holders OCCShareOptions) Defaults {
+	defaults := Defaults{}
+	for i, zone := range s.Zones {
+		for j, plot := range zone.Plots {
+			for k, tree := range plot.Trees {
+				s.treeDefaults(defaults, fmt.Sprintf("zones[%d].plots[%d].trees[%d]", i, j, k), ps, zone, tree)
+			}
+			if zone.DeadWood != PoolMeasured {
+				continue
+			}
+			for k, tree := range plot.StandingDead {
+				s.treeDefaults(defaults, fmt.Sprintf("zones[%d].plots[%d].standingDead[%d]", i, j, k), ps, zone, tree.Tree)
+			}
+		}
+		if zone.BaselineModel == nil {
+			continue
+		}
+		for j, plot := range zone.BaselineModel.Plots {
+			for k, tree := range plot.Trees {
+				s.treeDefaults(defaults, fmt.Sprintf("zones[%d].baselineModel.plots[%d].trees[%d]", i, j, k), ps, zone, tree)
+			}
+		}
+	}
+	for i, fertilizer := range s.Fertilizers {
+		_, fertilizerDefaults := fertilizer.EmissionsWith(s.GWP)
+		for name, value := range fertilizerDefaults {
+			defaults[fmt.Sprintf("fertilizers[%d].%s", i, name)] = value
+		}
+	}
+	if _, bufferDefaults := OCCBufferPoolWith(buffer); len(bufferDefaults) > 0 {
+		defaults["bufferPercent"] = bufferDefaults["percent"]
+	}
+	if _, holdersDefaults := OCCHoldersWith(holders); len(holdersDefaults) > 0 {
+		defaults["holdersPercent"] = holdersDefaults["percent"]
+	}
+	return defaults
+}
+
+// Calculate the net GHG emissions from the nitrogen fertilizer
+func (f Fertilizer) Emissions() decimal.Decimal {
+	return f.emissions(nil, GWPAR5)
+}
+
+// Calculate the net GHG emissions from the nitrogen fertilizer with the N2O
+// global warming potential of the GWP set
+func (f Fertilizer) EmissionsGWP(gwp GWPSet) decimal.Decimal {
+	return f.emissions(nil, gwp)
+}
+
+// EmissionsGWP returning the defaults substituted for the options not
+// provided
+// Add the defaults substituted for the parameters of the tree, the trees
+// excluded from the calculation have no defaults
+func (s Stage) treeDefaults(defaults Defaults, prefix string, ps *ParameterSet, z MonitoringZone, tree Tree) {
+	tr := NewTrace("Tree")
+	if _, _, err := s.treeCarbon(tr, ps, z, tree); err != nil {
+		return
+	}
+	for name, value := range tr.defaults() {
+		defaults[prefix+"."+name] = value
+	}
+}
+
+func (f Fertilizer) EmissionsWith(gwp GWPSet) (decimal.Decimal, Defaults) {
+	tr := NewTrace("FertilizerEmissionsWith")
+	result := f.emissions(tr, gwp)
+	return result, tr.defaults()
+}
+
+func (f Fertilizer) emissions(tr *Trace, gwp GWPSet) decimal.Decimal {
+	inputs := FertilizerInputs{
+		MassSynthFertz:  f.MassSynthFertz,
+		NContSynthFertz: f.NContSynthFertz,
+		MassOrgFertz:    f.MassOrgFertz,
+		NContOrgFertz:   f.NContOrgFertz,
+		GWarmingPotentl: f.GWarmingPotentl,
+		GWP:             gwp,
+	}
+	direct := cO2eNdirectt(tr, CO2eNdirecttOptions{
+		FertilizerInputs: inputs,
+		NitrOxdEmissSOC:  f.NitrOxdEmissSOC,
+	})
+	volat := nfertVolatIT(tr, NfertVolatITOptions{
+		FertilizerInputs: inputs,
+		AllFractSynth:    f.AllFractSynth,
+		AllFractOrg:      f.AllFractOrg,
+		NitrOxdEmissWS:   f.NitrOxdEmissWS,
+	})
+	leach := nfertLeachIT(tr, NfertLeachITOptions{
+		FertilizerInputs: inputs,
+		NFractSoil:       f.NFractSoil,
+		NitrOxdEmissLR:   f.NitrOxdEmissLR,
+	})
+	return netGHGEmissions(tr, f.Applications, direct, cO2eNindirectt(tr, volat, leach))
+}
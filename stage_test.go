@@ -0,0 +1,164 @@
+package carbon_calc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func testTree(id string, radius, height float64) Tree {
+	return Tree{
+		ID:       id,
+		Radius:   decimal.NewFromFloat(radius),
+		Height:   decimal.NewFromFloat(height),
+		Fraction: Optional(decimal.NewFromFloat(0.47)),
+		Form:     Optional(decimal.NewFromFloat(0.25)),
+		Density:  Optional(decimal.NewFromFloat(0.55)),
+		Biomass:  Optional(decimal.NewFromFloat(1.15)),
+		Ratio:    Optional(decimal.NewFromFloat(0.3)),
+	}
+}
+
+func testStage() Stage {
+	return Stage{
+		Zones: []MonitoringZone{
+			{
+				ID:   "zone-1",
+				Area: decimal.New(8, 0),
+				Plots: []Plot{
+					{ID: "plot-1", Area: decimal.NewFromFloat(0.0201), Trees: []Tree{
+						testTree("tree-1", 0.05, 5), testTree("tree-2", 0.05, 5), testTree("tree-3", 0.05, 5),
+					}},
+					{ID: "plot-2", Area: decimal.NewFromFloat(0.0201), Trees: []Tree{
+						testTree("tree-4", 0.05, 5), testTree("tree-5", 0.05, 5),
+					}},
+					{ID: "plot-3", Area: decimal.NewFromFloat(0.03), Trees: []Tree{
+						testTree("tree-6", 0.05, 5), testTree("tree-7", 0.03, 1.53),
+						testTree("tree-8", 0.02, 1.2),
+					}},
+				},
+			},
+			{
+				ID:   "zone-2",
+				Area: decimal.New(1, 0),
+				Plots: []Plot{
+					{ID: "plot-4", Area: decimal.NewFromFloat(0.03), Trees: []Tree{
+						testTree("tree-9", 0.025, 1.68), testTree("tree-10", 0.015, 1.54),
+						testTree("tree-11", 0.02, 1.45), testTree("tree-12", 0.025, 1.44),
+					}},
+					{ID: "plot-5", Area: decimal.NewFromFloat(0.02), Trees: []Tree{
+						testTree("tree-13", 0.036, 1.6), testTree("tree-14", 0.036, 1.9),
+						testTree("tree-15", 0.031, 2.4),
+					}},
+				},
+			},
+		},
+		DeltaTime: decimal.New(1, 0),
+		Leakage:   decimal.NewFromFloat(0.05),
+	}
+}
+
+func TestStageCalculate(t *testing.T) {
+	type Test struct {
+		name   string
+		value  func(r StageResult) decimal.Decimal
+		result float64 // precision = 3
+	}
+	result, err := testStage().Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []Test{
+		{"total carbon", func(r StageResult) decimal.Decimal { return r.TotalCarbon }, 13.014},
+		{"uncertainty", func(r StageResult) decimal.Decimal { return r.Uncertainty }, 0.785},
+		{"conservative carbon", func(r StageResult) decimal.Decimal { return r.ConservativeCarbon }, 2.8},
+		{"net emissions removal", func(r StageResult) decimal.Decimal { return r.NetEmissionsRemoval }, 2.66},
+		{"minted", func(r StageResult) decimal.Decimal { return r.MintedOCC }, 2.66},
+		{"buffer pool", func(r StageResult) decimal.Decimal { return r.BufferPool }, 0.186},
+		{"holders", func(r StageResult) decimal.Decimal { return r.Holders }, 0.213},
+		{"zone carbon", func(r StageResult) decimal.Decimal { return r.Zones[0].Carbon }, 12.721},
+		{"zone minted", func(r StageResult) decimal.Decimal { return r.Zones[0].MintedOCC }, 2.601},
+	}
+	for i, tt := range tests {
+		value := tt.value(result)
+		rounded, err := strconv.ParseFloat(fmt.Sprintf("%.3f", value.InexactFloat64()), 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rounded != tt.result {
+			t.Fatalf("Test number %d (%s), expect: %f, have: %f", i, tt.name, tt.result, value.InexactFloat64())
+		}
+	}
+	if !result.Zones[0].Plots[2].Trees[2].Excluded {
+		t.Fatalf("Tree under 1.3 m should be excluded")
+	}
+}
+
+func TestStageCalculatePrevious(t *testing.T) {
+	stage := testStage()
+	previous, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage.Previous = &previous
+	stage.Zones[0].Plots[0].Trees = append(stage.Zones[0].Plots[0].Trees, testTree("tree-16", 0.05, 5))
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	minted := MintedOCC(result.NetEmissionsRemoval, previous.NetEmissionsRemoval)
+	if !result.MintedOCC.Equal(minted) {
+		t.Fatalf("expect: %s, have: %s", minted, result.MintedOCC)
+	}
+	perZone := result.Zones[0].MintedOCC.Add(result.Zones[1].MintedOCC)
+	if perZone.Sub(result.MintedOCC).Abs().GreaterThan(decimal.NewFromFloat(0.0001)) {
+		t.Fatalf("expect: %s, have: %s", result.MintedOCC, perZone)
+	}
+}
+
+func TestStageCalculateErrors(t *testing.T) {
+	if _, err := (Stage{}).Calculate(); !errors.Is(err, ErrNoMonitoringZones) {
+		t.Fatalf("expect: %v, have: %v", ErrNoMonitoringZones, err)
+	}
+	stage := Stage{Zones: []MonitoringZone{{ID: "zone-1", Area: decimal.New(1, 0)}}}
+	if _, err := stage.Calculate(); !errors.Is(err, ErrNoPlots) {
+		t.Fatalf("expect: %v, have: %v", ErrNoPlots, err)
+	}
+}
+
+func TestStageEcologicalZone(t *testing.T) {
+	stage := testStage()
+	zone := EcologicalZoneTropicalMountainSystems
+	stage.Zones[0].EcologicalZone = &zone
+	stage.Zones[0].Plots[0].Trees[0].Ratio = nil
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := CarbonPerTree(
+		decimal.NewFromFloat(0.47),
+		decimal.NewFromFloat(0.05),
+		decimal.NewFromFloat(5),
+		decimal.NewFromFloat(0.25),
+		decimal.NewFromFloat(0.55),
+		decimal.NewFromFloat(1.15),
+		decimal.NewFromFloat(0.27))
+	if have := result.Zones[0].Plots[0].Trees[0].Carbon; !have.Equal(expect) {
+		t.Fatalf("expect: %s, have: %s", expect, have)
+	}
+}
+
+func TestStageCalculateZeroCarbon(t *testing.T) {
+	stage := testStage()
+	for i := range stage.Zones {
+		for j := range stage.Zones[i].Plots {
+			stage.Zones[i].Plots[j].Trees = nil
+		}
+	}
+	if _, err := stage.Calculate(); err != ErrZeroCarbon {
+		t.Fatalf("expect: %v, have: %v", ErrZeroCarbon, err)
+	}
+}
@@ -0,0 +1,78 @@
+package carbon_calc
+
+import (
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// Stratified random sampling estimate of the carbon stock in all monitoring
+// zones in absolute units (t CO2-e)
+// total - sum of the area multiplied by the mean carbon of the plots of every
+// zone, see CarbonStoredInMonitoringZone
+// variance - sum of the squared area multiplied by the variance of the mean of
+// every zone, see VarianceOfTreeBiomass
+// standardError - square root of the variance
+// degreesOfFreedom - n - M, see TDistribution
+// lower, upper - bounds of the confidence interval of the total
+// uncertainty - half-width of the interval as a fraction of the total, equal
+// to UncertaintyCarbonStored
+type StratifiedEstimate struct {
+	Confidence       float64         `json:"confidence"`
+	Total            decimal.Decimal `json:"total"`
+	Variance         decimal.Decimal `json:"variance"`
+	StandardError    decimal.Decimal `json:"standardError"`
+	DegreesOfFreedom int             `json:"degreesOfFreedom"`
+	TDistribution    decimal.Decimal `json:"tDistribution"`
+	Lower            decimal.Decimal `json:"lower"`
+	Upper            decimal.Decimal `json:"upper"`
+	Uncertainty      decimal.Decimal `json:"uncertainty"`
+}
+
+// Calculate the stratified estimate of the total carbon stock with its
+// standard error and confidence interval
+// confidence - two-sided confidence level, 0 if you want to get default value
+// 0.9
+// zones - array of zones with area and array of carbon in each plot
+// (t CO2-e/ha)
+func StratifiedEstimator(confidence float64, zones []CarbonedZone) (StratifiedEstimate, error) {
+	if confidence == 0 {
+		confidence = defaultConfidence
+	}
+	tArea := decimal.Zero
+	numPlots := 0
+	for _, zone := range zones {
+		tArea = tArea.Add(zone.Area)
+		numPlots += len(zone.Plots)
+	}
+	if err := validateCarbonedZones(tArea, zones); err != nil {
+		return StratifiedEstimate{}, err
+	}
+	tDelta := tDistribution(nil, float64(numPlots-len(zones)), confidence)
+	estimate := stratifiedEstimate(nil, tDelta, zones)
+	estimate.Confidence = confidence
+	return estimate, nil
+}
+
+func stratifiedEstimate(tr *Trace, tDelta decimal.Decimal, zones []CarbonedZone) StratifiedEstimate {
+	tr = tr.Step("StratifiedEstimate").
+		Input("tDelta", tDelta)
+	estimate := StratifiedEstimate{TDistribution: tDelta}
+	for _, zone := range zones {
+		nI := decimal.NewFromInt(int64(len(zone.Plots)))
+		estimate.Total = estimate.Total.Add(SumDecimal(zone.Plots).Div(nI).Mul(zone.Area))
+		estimate.Variance = estimate.Variance.Add(zone.Area.Pow(decimal.New(2, 0)).Mul(varianceOfTreeBiomass(tr, zone.Plots)).Div(nI))
+		estimate.DegreesOfFreedom += len(zone.Plots) - 1
+	}
+	estimate.StandardError = tr.Step("StandardError").
+		Input("variance", estimate.Variance).
+		Result(decimal.NewFromFloat(math.Sqrt(estimate.Variance.InexactFloat64())))
+	halfWidth := tDelta.Mul(estimate.StandardError)
+	estimate.Lower = estimate.Total.Sub(halfWidth)
+	estimate.Upper = estimate.Total.Add(halfWidth)
+	if !estimate.Total.IsZero() {
+		estimate.Uncertainty = halfWidth.Div(estimate.Total.Abs())
+	}
+	tr.Result(estimate.Total)
+	return estimate
+}
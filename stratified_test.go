@@ -0,0 +1,63 @@
+package carbon_calc
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestStratifiedEstimator(t *testing.T) {
+	zones := []CarbonedZone{
+		{Area: decimal.New(3, 0), Plots: []decimal.Decimal{decimal.New(10, 0), decimal.New(14, 0), decimal.New(12, 0)}},
+		{Area: decimal.New(1, 0), Plots: []decimal.Decimal{decimal.New(20, 0), decimal.New(24, 0)}},
+	}
+	estimate, err := StratifiedEstimator(0, zones)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3 * 12 + 1 * 22, 3^2 * 4 / 3 + 1^2 * 8 / 2
+	if !estimate.Total.Equal(decimal.New(58, 0)) || !estimate.Variance.Equal(decimal.New(16, 0)) || !estimate.StandardError.Equal(decimal.New(4, 0)) {
+		t.Fatalf("expect: 58, 16, 4, have: %s, %s, %s", estimate.Total, estimate.Variance, estimate.StandardError)
+	}
+	tDelta := TDistribution(3)
+	if estimate.Confidence != 0.9 || estimate.DegreesOfFreedom != 3 || !estimate.TDistribution.Equal(tDelta) {
+		t.Fatalf("expect: 0.9, 3, %s, have: %f, %d, %s", tDelta, estimate.Confidence, estimate.DegreesOfFreedom, estimate.TDistribution)
+	}
+	lower := decimal.New(58, 0).Sub(tDelta.Mul(decimal.New(4, 0)))
+	if !estimate.Lower.Equal(lower) || !estimate.Upper.Sub(estimate.Total).Equal(estimate.Total.Sub(lower)) {
+		t.Fatalf("Wrong bounds: %s, %s", estimate.Lower, estimate.Upper)
+	}
+	uncertainty := UncertaintyCarbonStored(tDelta, decimal.New(4, 0), zones)
+	if !estimate.Uncertainty.Round(9).Equal(uncertainty.Round(9)) {
+		t.Fatalf("expect: %s, have: %s", uncertainty, estimate.Uncertainty)
+	}
+
+	if _, err := StratifiedEstimator(0.9, zones[:0]); err != ErrNoMonitoringZones {
+		t.Fatalf("expect: %v, have: %v", ErrNoMonitoringZones, err)
+	}
+	zones[1].Plots = zones[1].Plots[:1]
+	if _, err := StratifiedEstimator(0.9, zones); err == nil {
+		t.Fatal("expect error for the zone with one plot")
+	}
+}
+
+func TestStageStratifiedEstimate(t *testing.T) {
+	result, err := testStage().Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	halfWidth := result.TDistribution.Mul(result.CarbonStandardError)
+	if !result.CarbonUpper.Sub(result.TotalCarbon).Equal(halfWidth) {
+		t.Fatalf("expect: %s, have: %s", halfWidth, result.CarbonUpper.Sub(result.TotalCarbon))
+	}
+	if !halfWidth.Div(result.TotalCarbon).Round(9).Equal(result.Uncertainty.Round(9)) {
+		t.Fatalf("Standard error is not consistent with the uncertainty: %s, %s", halfWidth, result.Uncertainty)
+	}
+	numPlots := 0
+	for _, zone := range result.Zones {
+		numPlots += len(zone.Plots)
+	}
+	if result.DegreesOfFreedom != numPlots-len(result.Zones) {
+		t.Fatalf("expect: %d, have: %d", numPlots-len(result.Zones), result.DegreesOfFreedom)
+	}
+}
@@ -7,23 +7,63 @@ import "github.com/shopspring/decimal"
 // between the total carbon calculated for stage T and the total carbon calculated
 // for the previously validated stage (T-1, T-2 or T-3).
 func MintedOCC(netEmmisionCurrent, netEmmissionPrevious decimal.Decimal) decimal.Decimal {
-	return netEmmisionCurrent.Sub(netEmmissionPrevious)
+	return mintedOCC(nil, netEmmisionCurrent, netEmmissionPrevious)
+}
+
+func mintedOCC(tr *Trace, netEmmisionCurrent, netEmmissionPrevious decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("MintedOCC").
+		Input("netEmmisionCurrent", netEmmisionCurrent).
+		Input("netEmmissionPrevious", netEmmissionPrevious)
+	return tr.Result(netEmmisionCurrent.Sub(netEmmissionPrevious))
 }
 
 // Calculate the OCCs to be sent to the buffer pool
 func OCCBufferPool(mintedOcc decimal.Decimal, percent float64) decimal.Decimal {
-	if percent == 0 {
-		percent = 0.07
-	}
-	return mintedOcc.Mul(decimal.NewFromFloat(percent))
+	return occBufferPool(nil, OCCShareOptions{MintedOCC: mintedOcc, Percent: zeroDefault(decimal.NewFromFloat(percent))})
+}
+
+// Options of OCCBufferPoolWith and OCCHoldersWith
+// mintedOCC - OCCs to be minted
+// percent - share of the minted OCCs (fraction), nil if you want to get
+// default value 0.07 for the buffer pool and 0.08 for the holders
+type OCCShareOptions struct {
+	MintedOCC decimal.Decimal  `json:"mintedOCC"`
+	Percent   *decimal.Decimal `json:"percent"`
+}
+
+// Calculate the OCCs to be sent to the buffer pool, zero percent is used as is,
+// returns the defaults substituted for the options not provided
+func OCCBufferPoolWith(o OCCShareOptions) (decimal.Decimal, Defaults) {
+	tr := NewTrace("OCCBufferPoolWith")
+	result := occBufferPool(tr, o)
+	return result, tr.defaults()
+}
+
+func occBufferPool(tr *Trace, o OCCShareOptions) decimal.Decimal {
+	tr = tr.Step("OCCBufferPool").
+		Input("mintedOcc", o.MintedOCC)
+	value := tr.optional("percent", o.Percent, decimal.NewFromFloat(0.07))
+	return tr.Result(o.MintedOCC.Mul(value))
 }
 
 // Calculate the OCCs to be sent to the token holders
 func OCCHolders(mintedOcc decimal.Decimal, percent float64) decimal.Decimal {
-	if percent == 0 {
-		percent = 0.08
-	}
-	return mintedOcc.Mul(decimal.NewFromFloat(percent))
+	return occHolders(nil, OCCShareOptions{MintedOCC: mintedOcc, Percent: zeroDefault(decimal.NewFromFloat(percent))})
+}
+
+// Calculate the OCCs to be sent to the token holders, zero percent is used as
+// is, returns the defaults substituted for the options not provided
+func OCCHoldersWith(o OCCShareOptions) (decimal.Decimal, Defaults) {
+	tr := NewTrace("OCCHoldersWith")
+	result := occHolders(tr, o)
+	return result, tr.defaults()
+}
+
+func occHolders(tr *Trace, o OCCShareOptions) decimal.Decimal {
+	tr = tr.Step("OCCHolders").
+		Input("mintedOcc", o.MintedOCC)
+	value := tr.optional("percent", o.Percent, decimal.NewFromFloat(0.08))
+	return tr.Result(o.MintedOCC.Mul(value))
 }
 
 // Calculate the OCCs minted per monitoring zone
@@ -36,5 +76,28 @@ func OCCHolders(mintedOcc decimal.Decimal, percent float64) decimal.Decimal {
 // carbonP - Carbon stored in all monitoring zones, previous stage
 // zoneP - Carbon stored in monitoring zone i, previous stage
 func OCCMintedPerMonitoringZone(minted, carbonC, zoneC, carbonP, zoneP decimal.Decimal) decimal.Decimal {
-	return minted.Mul(zoneC.Sub(zoneP).Div(carbonC.Sub(carbonP)))
+	return occMintedPerMonitoringZone(nil, minted, carbonC, zoneC, carbonP, zoneP)
+}
+
+// Calculate the OCCs minted per monitoring zone with params validation
+// For more comments see OCCMintedPerMonitoringZone function
+func ValidateOCCMintedPerMonitoringZone(minted, carbonC, zoneC, carbonP, zoneP decimal.Decimal) (decimal.Decimal, error) {
+	return validateOCCMintedPerMonitoringZone(nil, minted, carbonC, zoneC, carbonP, zoneP)
+}
+
+func validateOCCMintedPerMonitoringZone(tr *Trace, minted, carbonC, zoneC, carbonP, zoneP decimal.Decimal) (decimal.Decimal, error) {
+	if carbonC.Equal(carbonP) {
+		return decimal.Decimal{}, ErrNoCarbonChange
+	}
+	return occMintedPerMonitoringZone(tr, minted, carbonC, zoneC, carbonP, zoneP), nil
+}
+
+func occMintedPerMonitoringZone(tr *Trace, minted, carbonC, zoneC, carbonP, zoneP decimal.Decimal) decimal.Decimal {
+	tr = tr.Step("OCCMintedPerMonitoringZone").
+		Input("minted", minted).
+		Input("carbonC", carbonC).
+		Input("zoneC", zoneC).
+		Input("carbonP", carbonP).
+		Input("zoneP", zoneP)
+	return tr.Result(minted.Mul(zoneC.Sub(zoneP).Div(carbonC.Sub(carbonP))))
 }
@@ -102,3 +102,33 @@ func TestOCCMintedPerMonitoringZone(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateOCCMintedPerMonitoringZone(t *testing.T) {
+	type Test struct {
+		minted, carbonC, zoneC, carbonP, zoneP float64
+		err                                    error
+		result                                 float64 // precision = 4
+	}
+	tests := []Test{
+		{3.6, 6.6, 6.041, 2.77, 2.54, nil, 3.2908},
+		{3.6, 6.6, 6.041, 6.6, 2.54, ErrNoCarbonChange, 0},
+	}
+	for i, tt := range tests {
+		result, err := ValidateOCCMintedPerMonitoringZone(
+			decimal.NewFromFloat(tt.minted),
+			decimal.NewFromFloat(tt.carbonC),
+			decimal.NewFromFloat(tt.zoneC),
+			decimal.NewFromFloat(tt.carbonP),
+			decimal.NewFromFloat(tt.zoneP))
+		if err != tt.err {
+			t.Fatalf("Test number %d, expect: %v, have: %v", i, tt.err, err)
+		}
+		rounded, err := strconv.ParseFloat(fmt.Sprintf("%.4f", result.InexactFloat64()), 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rounded != tt.result {
+			t.Fatalf("Test number %d, expect: %f, have: %f", i, tt.result, result.InexactFloat64())
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package carbon_calc
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Trace of the calculation, records the formula invoked, its named inputs
+// (including the defaults that were substituted) and its output. Nested
+// formulas are recorded as steps, so the whole calculation is a tree that can
+// be serialized to JSON.
+// All methods are safe to call on nil trace, in this case nothing is recorded.
+type Trace struct {
+	Formula string          `json:"formula"`
+	Subject string          `json:"subject,omitempty"`
+	Source  string          `json:"source,omitempty"`
+	Inputs  []TraceInput    `json:"inputs,omitempty"`
+	Output  decimal.Decimal `json:"output"`
+	Steps   []*Trace        `json:"steps,omitempty"`
+}
+
+// Named input of the formula
+// default - true if value was not provided and the default was substituted
+type TraceInput struct {
+	Name    string          `json:"name"`
+	Value   decimal.Decimal `json:"value"`
+	Default bool            `json:"default,omitempty"`
+}
+
+func NewTrace(formula string) *Trace {
+	return &Trace{Formula: formula}
+}
+
+// Start the nested formula
+func (t *Trace) Step(formula string) *Trace {
+	if t == nil {
+		return nil
+	}
+	step := NewTrace(formula)
+	t.Steps = append(t.Steps, step)
+	return step
+}
+
+// Set the identifier of the tree, plot or zone the formula is calculated for
+func (t *Trace) Of(subject string) *Trace {
+	if t == nil {
+		return nil
+	}
+	t.Subject = subject
+	return t
+}
+
+// Set the source the output was taken from, e.g. the level of the species
+// registry match
+func (t *Trace) From(source string) *Trace {
+	if t == nil {
+		return nil
+	}
+	t.Source = source
+	return t
+}
+
+func (t *Trace) Input(name string, value decimal.Decimal) *Trace {
+	if t == nil {
+		return nil
+	}
+	t.Inputs = append(t.Inputs, TraceInput{Name: name, Value: value})
+	return t
+}
+
+// Record the default value substituted for the input
+func (t *Trace) Default(name string, value decimal.Decimal) *Trace {
+	if t == nil {
+		return nil
+	}
+	t.Inputs = append(t.Inputs, TraceInput{Name: name, Value: value, Default: true})
+	return t
+}
+
+// Record the output of the formula and return it
+func (t *Trace) Result(value decimal.Decimal) decimal.Decimal {
+	if t != nil {
+		t.Output = value
+	}
+	return value
+}
+
+// All formulas with the name in the trace tree, including trace itself
+func (t *Trace) Find(formula string) []*Trace {
+	if t == nil {
+		return nil
+	}
+	var found []*Trace
+	if t.Formula == formula {
+		found = append(found, t)
+	}
+	for _, step := range t.Steps {
+		found = append(found, step.Find(formula)...)
+	}
+	return found
+}
+
+// Input value by name
+func (t *Trace) Value(name string) (decimal.Decimal, bool) {
+	if t == nil {
+		return decimal.Decimal{}, false
+	}
+	for _, input := range t.Inputs {
+		if input.Name == name {
+			return input.Value, true
+		}
+	}
+	return decimal.Decimal{}, false
+}
+
+// Record the input, substituting the default if value is zero
+func (t *Trace) inputOrDefault(name string, value, def decimal.Decimal) decimal.Decimal {
+	return t.optional(name, zeroDefault(value), def)
+}
+
+// Record the input, substituting the default if value is nil
+func (t *Trace) optional(name string, value *decimal.Decimal, def decimal.Decimal) decimal.Decimal {
+	if value == nil {
+		t.Default(name, def)
+		return def
+	}
+	t.Input(name, *value)
+	return *value
+}
+
+// Defaults substituted in the whole trace tree
+func (t *Trace) defaults() Defaults {
+	defaults := Defaults{}
+	if t == nil {
+		return defaults
+	}
+	for _, input := range t.Inputs {
+		if input.Default {
+			defaults[input.Name] = input.Value
+		}
+	}
+	for _, step := range t.Steps {
+		for name, value := range step.defaults() {
+			defaults[name] = value
+		}
+	}
+	return defaults
+}
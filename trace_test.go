@@ -0,0 +1,108 @@
+package carbon_calc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTraceNil(t *testing.T) {
+	var tr *Trace
+	value := tr.Step("CarbonPerTree").
+		Input("radius", decimal.NewFromFloat(0.05)).
+		Default("fraction", decimal.NewFromFloat(0.47)).
+		Result(decimal.New(1, 0))
+	if !value.Equal(decimal.New(1, 0)) {
+		t.Fatalf("expect: 1, have: %s", value)
+	}
+	if found := tr.Find("CarbonPerTree"); len(found) != 0 {
+		t.Fatalf("expect: 0, have: %d", len(found))
+	}
+}
+
+func TestTraceDefaults(t *testing.T) {
+	type Test struct {
+		fraction, form float64
+		defaults       []string
+	}
+	tests := []Test{
+		{0.47, 0.25, nil},
+		{0, 0.25, []string{"fraction"}},
+		{0, 0, []string{"fraction", "form"}},
+	}
+	for i, tt := range tests {
+		tr := NewTrace("Test")
+		result := carbonPerTree(tr, newCarbonPerTreeOptions(
+			decimal.NewFromFloat(tt.fraction),
+			decimal.NewFromFloat(0.05),
+			decimal.NewFromFloat(5),
+			decimal.NewFromFloat(tt.form),
+			decimal.NewFromFloat(0.55),
+			decimal.NewFromFloat(1.15),
+			decimal.NewFromFloat(0.3)))
+		step := tr.Steps[0]
+		if step.Formula != "CarbonPerTree" || !step.Output.Equal(result) {
+			t.Fatalf("Test number %d, expect: CarbonPerTree %s, have: %s %s", i, result, step.Formula, step.Output)
+		}
+		var defaults []string
+		for _, input := range step.Inputs {
+			if input.Default {
+				defaults = append(defaults, input.Name)
+			}
+		}
+		if len(defaults) != len(tt.defaults) {
+			t.Fatalf("Test number %d, expect: %v, have: %v", i, tt.defaults, defaults)
+		}
+		for j := range defaults {
+			if defaults[j] != tt.defaults[j] {
+				t.Fatalf("Test number %d, expect: %v, have: %v", i, tt.defaults, defaults)
+			}
+		}
+	}
+}
+
+func TestStageExplain(t *testing.T) {
+	stage := testStage()
+	stage.Explain = true
+	stage.Zones[1].Plots[0].Trees[0].Fraction = nil
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Trace.Output.Equal(result.MintedOCC) {
+		t.Fatalf("expect: %s, have: %s", result.MintedOCC, result.Trace.Output)
+	}
+	zones := result.Trace.Find("MonitoringZone")
+	if len(zones) != 2 || zones[1].Subject != "zone-2" || !zones[1].Output.Equal(result.Zones[1].Carbon) {
+		t.Fatalf("expect zone-2 with output %s", result.Zones[1].Carbon)
+	}
+	trees := zones[1].Find("CarbonPerTree")
+	if len(trees) != 7 {
+		t.Fatalf("expect: 7, have: %d", len(trees))
+	}
+	fraction, ok := trees[0].Value("fraction")
+	if !ok || !fraction.Equal(decimal.NewFromFloat(0.47)) || !trees[0].Inputs[0].Default {
+		t.Fatalf("expect default fraction 0.47, have: %v", trees[0].Inputs[0])
+	}
+	data, err := json.Marshal(result.Trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Trace
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Find("CarbonPerTree")) != 14 {
+		t.Fatalf("expect: %d, have: %d", 14, len(decoded.Find("CarbonPerTree")))
+	}
+
+	stage.Explain = false
+	result, err = stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Trace != nil {
+		t.Fatalf("Trace should be recorded only in explain mode")
+	}
+}
@@ -0,0 +1,261 @@
+package carbon_calc
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Rules violated by the discount schedule
+const (
+	RuleAscending = "must be in ascending order"
+	RuleLastStep  = "must be set for every step except the last one"
+)
+
+// Two-sided confidence level of TDistribution
+const defaultConfidence = 0.9
+
+// Discount for uncertainty up to maxUncertainty (fraction, inclusive), nil
+// maxUncertainty for the last unbounded step
+type DiscountStep struct {
+	MaxUncertainty *float64 `json:"maxUncertainty,omitempty" yaml:"maxUncertainty,omitempty"`
+	Discount       float64  `json:"discount" yaml:"discount"`
+}
+
+// Rule of the uncertainty deduction of the carbon standard
+type DiscountSchedule interface {
+	// Name recorded in the stage result to show which schedule was applied
+	String() string
+	// Fraction of the total carbon deducted for the uncertainty (fraction)
+	Deduction(uncertainty decimal.Decimal) decimal.Decimal
+}
+
+// Carbon standard the project is registered under, selects the discount
+// schedule
+type Standard uint8
+
+const (
+	StandardCDM Standard = iota
+	StandardVM0047
+	StandardGoldStandard
+)
+
+var standardNames = map[Standard]string{
+	StandardCDM:          "cdm-ar-tool14",
+	StandardVM0047:       "vm0047",
+	StandardGoldStandard: "gold-standard-ar",
+}
+
+func (s Standard) String() string {
+	return enumName(standardNames, s)
+}
+
+func (s Standard) MarshalText() ([]byte, error) {
+	return marshalEnum(standardNames, s)
+}
+
+func (s *Standard) UnmarshalText(text []byte) error {
+	return unmarshalEnum(standardNames, text, s)
+}
+
+// Built-in discount schedule of the standard:
+// CDM AR-TOOL14 - DefaultDiscountTable
+// VM0047 - deduction of the uncertainty exceeding 10%
+// Gold Standard A/R - carbon at the lower bound of the confidence interval if
+// the uncertainty exceeds 10%
+func (s Standard) DiscountSchedule() DiscountSchedule {
+	switch s {
+	case StandardVM0047:
+		return LinearDiscount{Name: s.String(), Threshold: 0.1}
+	case StandardGoldStandard:
+		return DiscountTable{
+			Name: s.String(),
+			Steps: []DiscountStep{
+				{MaxUncertainty: maxUncertainty(0.1), Discount: 0},
+				{Discount: 1},
+			},
+		}
+	default:
+		return DefaultDiscountTable()
+	}
+}
+
+// Schedule of the uncertainty discount ordered by uncertainty, the deduction is
+// the uncertainty multiplied by the discount of its step
+// name - recorded in the stage result to show which schedule was applied
+type DiscountTable struct {
+	Name  string         `json:"name" yaml:"name"`
+	Steps []DiscountStep `json:"steps" yaml:"steps"`
+}
+
+func maxUncertainty(v float64) *float64 {
+	return &v
+}
+
+// Discount steps of CDM AR-TOOL14: no discount up to 10% of uncertainty, 25%
+// up to 15%, 50% up to 20%, 75% up to 30% and the whole uncertainty above
+func DefaultDiscountTable() DiscountTable {
+	return DiscountTable{
+		Name: "cdm-ar-tool14",
+		Steps: []DiscountStep{
+			{MaxUncertainty: maxUncertainty(0.1), Discount: 0},
+			{MaxUncertainty: maxUncertainty(0.15), Discount: 0.25},
+			{MaxUncertainty: maxUncertainty(0.2), Discount: 0.5},
+			{MaxUncertainty: maxUncertainty(0.3), Discount: 0.75},
+			{Discount: 1},
+		},
+	}
+}
+
+// Discount of the step the uncertainty belongs to, 1 if uncertainty is above
+// all steps
+func (t DiscountTable) Discount(uncertainty decimal.Decimal) decimal.Decimal {
+	value := uncertainty.InexactFloat64()
+	for _, step := range t.Steps {
+		if step.MaxUncertainty == nil || value <= *step.MaxUncertainty {
+			return decimal.NewFromFloat(step.Discount)
+		}
+	}
+	return decimal.New(1, 0)
+}
+
+func (t DiscountTable) String() string {
+	return t.Name
+}
+
+// Uncertainty multiplied by its discount
+func (t DiscountTable) Deduction(uncertainty decimal.Decimal) decimal.Decimal {
+	return uncertainty.Mul(t.Discount(uncertainty))
+}
+
+func (v *validator) validateDiscountTable(field string, t DiscountTable) {
+	v.check(len(t.Steps) > 0, field+" steps", len(t.Steps), RuleRequired, nil)
+	previous := 0.0
+	for i, step := range t.Steps {
+		name := fmt.Sprintf("%s steps[%d]", field, i)
+		if step.MaxUncertainty == nil {
+			v.check(i == len(t.Steps)-1, name+" maxUncertainty", "nil", RuleLastStep, nil)
+		} else {
+			v.check(*step.MaxUncertainty >= previous, name+" maxUncertainty", *step.MaxUncertainty, RuleAscending, nil)
+			previous = *step.MaxUncertainty
+		}
+		v.check(step.Discount >= 0 && step.Discount <= 1, name+" discount", step.Discount, RuleFraction, nil)
+	}
+}
+
+// Check the steps of the table, returns ValidationErrors with all problems
+// found
+func (t DiscountTable) Validate() error {
+	v := &validator{}
+	v.validateDiscountTable("discount", t)
+	return v.errs.err()
+}
+
+// Linear deduction of the uncertainty exceeding the threshold
+// threshold - uncertainty without deduction (fraction)
+// cap - maximum deduction (fraction), 0 if the deduction is not capped
+type LinearDiscount struct {
+	Name      string  `json:"name" yaml:"name"`
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	Cap       float64 `json:"cap,omitempty" yaml:"cap,omitempty"`
+}
+
+func (l LinearDiscount) String() string {
+	return l.Name
+}
+
+// Uncertainty minus the threshold, not negative and not above the cap
+func (l LinearDiscount) Deduction(uncertainty decimal.Decimal) decimal.Decimal {
+	deduction := decimal.Max(decimal.Zero, uncertainty.Sub(decimal.NewFromFloat(l.Threshold)))
+	if l.Cap != 0 {
+		deduction = decimal.Min(deduction, decimal.NewFromFloat(l.Cap))
+	}
+	return deduction
+}
+
+// Check the threshold and the cap, returns ValidationErrors with all problems
+// found
+func (l LinearDiscount) Validate() error {
+	v := &validator{}
+	v.validateLinearDiscount("discount", l)
+	return v.errs.err()
+}
+
+func (v *validator) validateLinearDiscount(field string, l LinearDiscount) {
+	v.check(l.Threshold >= 0 && l.Threshold <= 1, field+" threshold", l.Threshold, RuleFraction, nil)
+	v.check(l.Cap >= 0 && l.Cap <= 1, field+" cap", l.Cap, RuleFraction, nil)
+}
+
+// Check the custom discount schedule if it can be validated
+func (v *validator) validateSchedule(field string, schedule DiscountSchedule) {
+	switch s := schedule.(type) {
+	case DiscountTable:
+		v.validateDiscountTable(field, s)
+	case LinearDiscount:
+		v.validateLinearDiscount(field, s)
+	case interface{ Validate() error }:
+		v.merge(field, schedule.String(), s.Validate())
+	}
+}
+
+// Discount of the uncertainty equivalent to the deduction of the schedule,
+// 0 if there is no uncertainty
+func scheduleDiscount(schedule DiscountSchedule, uncertainty decimal.Decimal) decimal.Decimal {
+	if table, ok := schedule.(DiscountTable); ok {
+		return table.Discount(uncertainty)
+	}
+	if uncertainty.IsZero() {
+		return decimal.Zero
+	}
+	return schedule.Deduction(uncertainty).Div(uncertainty)
+}
+
+// Confidence interval of the mean carbon stock of all monitoring zones
+// mean, lower, upper - area weighted mean carbon stock and its bounds
+// (t CO2-e/ha)
+// uncertainty - half-width of the interval as a fraction of the mean, see
+// UncertaintyCarbonStored
+type UncertaintyInterval struct {
+	Confidence    float64         `json:"confidence"`
+	TDistribution decimal.Decimal `json:"tDistribution"`
+	Mean          decimal.Decimal `json:"mean"`
+	Lower         decimal.Decimal `json:"lower"`
+	Upper         decimal.Decimal `json:"upper"`
+	Uncertainty   decimal.Decimal `json:"uncertainty"`
+}
+
+// Calculate the confidence interval of the carbon stock for the confidence
+// level with the degrees of freedom of the zones, see TDistribution
+// confidence - two-sided confidence level, 0 if you want to get default value
+// 0.9
+// tArea - area of all monitoring zones (sum of all areas of monitoring zones)
+// zones - array of zones with area and array of carbon in each plot
+func UncertaintyCarbonStoredInterval(confidence float64, tArea decimal.Decimal, zones []CarbonedZone) (UncertaintyInterval, error) {
+	return uncertaintyCarbonStoredInterval(nil, confidence, tArea, zones)
+}
+
+func uncertaintyCarbonStoredInterval(tr *Trace, confidence float64, tArea decimal.Decimal, zones []CarbonedZone) (UncertaintyInterval, error) {
+	if confidence == 0 {
+		confidence = defaultConfidence
+	}
+	if err := validateCarbonedZones(tArea, zones); err != nil {
+		return UncertaintyInterval{}, err
+	}
+	numPlots := 0
+	mean := decimal.Zero
+	for _, zone := range zones {
+		numPlots += len(zone.Plots)
+		nI := decimal.NewFromInt(int64(len(zone.Plots)))
+		mean = mean.Add(zone.Area.Div(tArea).Mul(SumDecimal(zone.Plots).Div(nI)))
+	}
+	interval := UncertaintyInterval{
+		Confidence:    confidence,
+		TDistribution: tDistribution(tr, float64(numPlots-len(zones)), confidence),
+		Mean:          mean,
+	}
+	interval.Uncertainty = uncertaintyCarbonStored(tr, interval.TDistribution, tArea, zones)
+	halfWidth := interval.Uncertainty.Mul(mean.Abs())
+	interval.Lower = mean.Sub(halfWidth)
+	interval.Upper = mean.Add(halfWidth)
+	return interval, nil
+}
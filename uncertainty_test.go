@@ -0,0 +1,249 @@
+package carbon_calc
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTDistributionConfidence(t *testing.T) {
+	type Test struct {
+		freedom, confidence float64
+		result              float64 // precision = 3
+	}
+	tests := []Test{
+		{3, 0.9, 2.353},
+		{3, 0.95, 3.182},
+		{30, 0.95, 2.042},
+		{10, 0.99, 3.169},
+		{10, 0.8, 1.372},
+	}
+	for i, tt := range tests {
+		result := TDistributionConfidence(tt.freedom, tt.confidence)
+		if !result.Round(3).Equal(decimal.NewFromFloat(tt.result)) {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, tt.result, result)
+		}
+	}
+	if !TDistribution(7).Equal(TDistributionConfidence(7, 0.9)) {
+		t.Fatalf("TDistribution should use 90%% confidence")
+	}
+}
+
+func TestDiscountTable(t *testing.T) {
+	table := DiscountTable{
+		Name: "custom",
+		Steps: []DiscountStep{
+			{MaxUncertainty: maxUncertainty(0.05), Discount: 0},
+			{MaxUncertainty: maxUncertainty(0.2), Discount: 0.5},
+		},
+	}
+	type Test struct {
+		uncertainty float64
+		result      float64
+	}
+	tests := []Test{
+		{0.05, 0},
+		{0.06, 0.5},
+		{0.2, 0.5},
+		{0.25, 1},
+	}
+	for i, tt := range tests {
+		result := table.Discount(decimal.NewFromFloat(tt.uncertainty))
+		if result.InexactFloat64() != tt.result {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, tt.result, result)
+		}
+	}
+	if err := DefaultDiscountTable().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	table.Steps = append(table.Steps, DiscountStep{MaxUncertainty: maxUncertainty(0.1), Discount: 1.5}, DiscountStep{Discount: 1}, DiscountStep{Discount: 1})
+	var errs ValidationErrors
+	if err := table.Validate(); !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expect 3 ValidationErrors, have: %v", err)
+	}
+	fields := []string{"discount steps[2] maxUncertainty", "discount steps[2] discount", "discount steps[3] maxUncertainty"}
+	for i, field := range fields {
+		if errs[i].Field != field {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, field, errs[i].Field)
+		}
+	}
+}
+
+func TestUncertaintyCarbonStoredInterval(t *testing.T) {
+	zones := []CarbonedZone{
+		{Area: decimal.New(3, 0), Plots: []decimal.Decimal{decimal.New(10, 0), decimal.New(14, 0), decimal.New(12, 0)}},
+		{Area: decimal.New(1, 0), Plots: []decimal.Decimal{decimal.New(20, 0), decimal.New(24, 0)}},
+	}
+	for _, confidence := range []float64{0, 0.9, 0.95} {
+		interval, err := UncertaintyCarbonStoredInterval(confidence, decimal.New(4, 0), zones)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectConfidence := confidence
+		if confidence == 0 {
+			expectConfidence = 0.9
+		}
+		tDelta := TDistributionConfidence(3, expectConfidence)
+		uncertainty := UncertaintyCarbonStored(tDelta, decimal.New(4, 0), zones)
+		if interval.Confidence != expectConfidence || !interval.TDistribution.Equal(tDelta) || !interval.Uncertainty.Equal(uncertainty) {
+			t.Fatalf("expect: %f, %s, %s, have: %v", expectConfidence, tDelta, uncertainty, interval)
+		}
+		// 0.75 * 12 + 0.25 * 22
+		if !interval.Mean.Equal(decimal.NewFromFloat(14.5)) {
+			t.Fatalf("expect: 14.5, have: %s", interval.Mean)
+		}
+		halfWidth := interval.Upper.Sub(interval.Lower).Div(decimal.New(2, 0))
+		if !halfWidth.Div(interval.Mean).Round(9).Equal(uncertainty.Round(9)) {
+			t.Fatalf("Half-width should be the uncertainty of the mean: %v", interval)
+		}
+	}
+	if _, err := UncertaintyCarbonStoredInterval(0.9, decimal.New(4, 0), zones[:0]); err != ErrNoMonitoringZones {
+		t.Fatalf("expect: %v, have: %v", ErrNoMonitoringZones, err)
+	}
+}
+
+func TestStageConfidence(t *testing.T) {
+	result, err := testStage().Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Confidence != 0.9 || result.DiscountSchedule != DefaultDiscountTable().Name {
+		t.Fatalf("Wrong defaults: %f, %s", result.Confidence, result.DiscountSchedule)
+	}
+
+	stage := testStage()
+	stage.Confidence = 0.95
+	stage.Discount = &DiscountTable{Name: "lenient", Steps: []DiscountStep{{MaxUncertainty: maxUncertainty(2), Discount: 0.1}, {Discount: 1}}}
+	strict, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strict.Confidence != 0.95 || strict.DiscountSchedule != "lenient" {
+		t.Fatalf("expect: 0.95 lenient, have: %f %s", strict.Confidence, strict.DiscountSchedule)
+	}
+	if !strict.Uncertainty.GreaterThan(result.Uncertainty) {
+		t.Fatalf("95%% uncertainty should be larger than 90%%: %s, %s", strict.Uncertainty, result.Uncertainty)
+	}
+	if !strict.UncertaintyDiscount.Equal(decimal.NewFromFloat(0.1)) {
+		t.Fatalf("expect: 0.1, have: %s", strict.UncertaintyDiscount)
+	}
+	conservative := strict.TotalCarbon.Mul(decimal.New(1, 0).Sub(strict.Uncertainty.Mul(decimal.NewFromFloat(0.1))))
+	if !strict.ConservativeCarbon.Equal(conservative) {
+		t.Fatalf("expect: %s, have: %s", conservative, strict.ConservativeCarbon)
+	}
+	lower := strict.TotalCarbon.Mul(decimal.New(1, 0).Sub(strict.Uncertainty))
+	if !strict.CarbonLower.Round(9).Equal(lower.Round(9)) || !strict.CarbonUpper.GreaterThan(strict.TotalCarbon) {
+		t.Fatalf("Wrong bounds: %s, %s", strict.CarbonLower, strict.CarbonUpper)
+	}
+
+	stage.Confidence = 1
+	stage.Discount.Steps[0].Discount = -1
+	var errs ValidationErrors
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expect 2 ValidationErrors, have: %v", err)
+	}
+}
+
+func TestDiscountSchedule(t *testing.T) {
+	type Test struct {
+		standard    Standard
+		uncertainty float64
+		result      float64
+	}
+	tests := []Test{
+		{StandardCDM, 0.1, 0},
+		{StandardCDM, 0.12, 0.03},
+		{StandardCDM, 0.4, 0.4},
+		{StandardVM0047, 0.08, 0},
+		{StandardVM0047, 0.1, 0},
+		{StandardVM0047, 0.25, 0.15},
+		{StandardGoldStandard, 0.1, 0},
+		{StandardGoldStandard, 0.12, 0.12},
+	}
+	for i, tt := range tests {
+		schedule := tt.standard.DiscountSchedule()
+		if schedule.String() != tt.standard.String() {
+			t.Fatalf("Test number %d, expect: %s, have: %s", i, tt.standard, schedule)
+		}
+		result := schedule.Deduction(decimal.NewFromFloat(tt.uncertainty))
+		if result.InexactFloat64() != tt.result {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, tt.result, result)
+		}
+		conservative := ConservativeTotalCarbonSchedule(schedule, decimal.New(100, 0), decimal.NewFromFloat(tt.uncertainty))
+		if conservative.InexactFloat64() != 100*(1-tt.result) {
+			t.Fatalf("Test number %d, expect: %f, have: %s", i, 100*(1-tt.result), conservative)
+		}
+	}
+
+	capped := LinearDiscount{Name: "capped", Threshold: 0.05, Cap: 0.2}
+	if result := capped.Deduction(decimal.NewFromFloat(0.5)); !result.Equal(decimal.NewFromFloat(0.2)) {
+		t.Fatalf("expect: 0.2, have: %s", result)
+	}
+	capped.Threshold = -0.1
+	var errs ValidationErrors
+	if err := capped.Validate(); !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("expect 1 ValidationError, have: %v", err)
+	}
+	var standard Standard
+	if err := standard.UnmarshalText([]byte("vm0047")); err != nil || standard != StandardVM0047 {
+		t.Fatalf("expect: %s, have: %s, %v", StandardVM0047, standard, err)
+	}
+}
+
+func TestStageStandard(t *testing.T) {
+	stage := testStage()
+	stage.Standard = StandardVM0047
+	result, err := stage.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deduction := decimal.Max(decimal.Zero, result.Uncertainty.Sub(decimal.NewFromFloat(0.1)))
+	if result.DiscountSchedule != "vm0047" || !result.UncertaintyDeduction.Equal(deduction) {
+		t.Fatalf("expect: vm0047 %s, have: %s %s", deduction, result.DiscountSchedule, result.UncertaintyDeduction)
+	}
+	if !result.ConservativeCarbon.Equal(result.TotalCarbon.Mul(decimal.New(1, 0).Sub(deduction))) {
+		t.Fatalf("Wrong conservative carbon: %s", result.ConservativeCarbon)
+	}
+
+	// Custom schedule takes precedence over the standard and the table
+	stage.Discount = &DiscountTable{Name: "table", Steps: []DiscountStep{{Discount: 1}}}
+	stage.Schedule = LinearDiscount{Name: "custom", Cap: 0.01}
+	if result, err = stage.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	if result.DiscountSchedule != "custom" || !result.UncertaintyDeduction.Equal(decimal.NewFromFloat(0.01)) {
+		t.Fatalf("expect: custom 0.01, have: %s %s", result.DiscountSchedule, result.UncertaintyDeduction)
+	}
+
+	// Custom schedule is validated
+	var errs ValidationErrors
+	stage.Schedule = LinearDiscount{Name: "custom", Threshold: math.NaN(), Cap: 0.01}
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "schedule threshold" {
+		t.Fatalf("expect schedule threshold %s, have: %v", RuleFraction, err)
+	}
+	stage.Schedule = nil
+
+	// Linear discount selected in JSON must not be combined with the table
+	if err := json.Unmarshal([]byte(`{"linearDiscount": {"name": "linear", "threshold": 0.05, "cap": 0.02}}`), &stage); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Rule != RuleExclusive {
+		t.Fatalf("expect %s, have: %v", RuleExclusive, err)
+	}
+	stage.Discount = nil
+	if result, err = stage.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	if result.DiscountSchedule != "linear" || !result.UncertaintyDeduction.Equal(decimal.NewFromFloat(0.02)) {
+		t.Fatalf("expect: linear 0.02, have: %s %s", result.DiscountSchedule, result.UncertaintyDeduction)
+	}
+
+	stage.Standard = Standard(10)
+	if _, err := stage.Calculate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Rule != RuleKnown {
+		t.Fatalf("expect %s, have: %v", RuleKnown, err)
+	}
+}
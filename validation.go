@@ -0,0 +1,460 @@
+package carbon_calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Rules violated by the calculation inputs
+const (
+	RulePositive    = "must be positive"
+	RuleNonNegative = "must not be negative"
+	RuleFraction    = "must be between 0 and 1"
+	RuleRequired    = "must not be empty"
+	RuleUnique      = "must be unique"
+	RuleMinPlots    = "must contain at least two sample plots"
+	RuleKnown       = "must be a known value"
+	RuleCalculated  = "must be empty when the value is calculated"
+	RuleExclusive   = "must not be combined with the discount table"
+)
+
+// Invalid input of the calculation
+// zone, plot, tree - identifiers of the monitoring zone, plot and tree the
+// field belongs to, empty if field belongs to the upper level
+// err - sentinel error of the rule if any, e.g. ErrNoPlots
+type ValidationError struct {
+	Zone  string `json:"zone,omitempty"`
+	Plot  string `json:"plot,omitempty"`
+	Tree  string `json:"tree,omitempty"`
+	Field string `json:"field"`
+	Value string `json:"value"`
+	Rule  string `json:"rule"`
+	Err   error  `json:"-"`
+}
+
+func (e *ValidationError) Error() string {
+	var path []string
+	if e.Zone != "" {
+		path = append(path, "zone "+e.Zone)
+	}
+	if e.Plot != "" {
+		path = append(path, "plot "+e.Plot)
+	}
+	if e.Tree != "" {
+		path = append(path, "tree "+e.Tree)
+	}
+	path = append(path, e.Field)
+	return fmt.Sprintf("%s %s, have: %s", strings.Join(path, ", "), e.Rule, e.Value)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// All problems found in the dataset
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Every problem as error, e.g. to report them one by one
+func (e ValidationErrors) Errors() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Report if any problem matches the target, so errors.Is finds the sentinel
+// errors of the rules, e.g. errors.Is(err, ErrNoPlots)
+func (e ValidationErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Nil if there are no problems
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+type validator struct {
+	errs             ValidationErrors
+	zone, plot, tree string
+}
+
+func (v *validator) check(ok bool, field string, value interface{}, rule string, err error) {
+	if ok {
+		return
+	}
+	v.errs = append(v.errs, &ValidationError{
+		Zone:  v.zone,
+		Plot:  v.plot,
+		Tree:  v.tree,
+		Field: field,
+		Value: fmt.Sprint(value),
+		Rule:  rule,
+		Err:   err,
+	})
+}
+
+func checkKnown[T ~uint8](v *validator, field string, value T, names map[T]string) {
+	_, ok := names[value]
+	v.check(ok, field, enumName(names, value), RuleKnown, nil)
+}
+
+func (v *validator) positive(field string, value decimal.Decimal) {
+	v.check(value.GreaterThan(decimal.Zero), field, value, RulePositive, nil)
+}
+
+func (v *validator) nonNegative(field string, value decimal.Decimal) {
+	v.check(!value.IsNegative(), field, value, RuleNonNegative, nil)
+}
+
+func (v *validator) fraction(field string, value decimal.Decimal) {
+	v.check(!value.IsNegative() && value.LessThanOrEqual(decimal.New(1, 0)), field, value, RuleFraction, nil)
+}
+
+func (v *validator) positiveFinite(field string, value float64) {
+	v.check(value > 0 && !math.IsInf(value, 1), field, value, RulePositive, nil)
+}
+
+// Append the error of the custom Validate method, ValidationErrors are
+// appended as is
+func (v *validator) merge(field string, value interface{}, err error) {
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		v.errs = append(v.errs, errs...)
+	} else if err != nil {
+		v.check(false, field, value, err.Error(), err)
+	}
+}
+
+// Check the option with the rule if the option is provided
+func (v *validator) optional(rule func(string, decimal.Decimal), field string, value *decimal.Decimal) {
+	if value != nil {
+		rule(field, *value)
+	}
+}
+
+// Trees under 1.3 m are valid input, they are excluded from the calculation
+func (v *validator) validateTree(tree Tree) {
+	v.positive("radius", tree.Radius)
+	v.nonNegative("height", tree.Height)
+	v.optional(v.fraction, "fraction", tree.Fraction)
+	v.optional(v.nonNegative, "form", tree.Form)
+	v.optional(v.nonNegative, "density", tree.Density)
+	v.optional(v.nonNegative, "biomass", tree.Biomass)
+	v.optional(v.nonNegative, "ratio", tree.Ratio)
+}
+
+func (v *validator) validatePlot(plot Plot) {
+	v.positive("area", plot.Area)
+	for _, tree := range plot.Trees {
+		v.tree = tree.ID
+		v.validateTree(tree)
+	}
+	for _, tree := range plot.StandingDead {
+		v.tree = tree.ID
+		v.validateTree(tree.Tree)
+		checkKnown(v, "decay", tree.Decay, decayClassNames)
+	}
+	v.tree = ""
+	if line := plot.LyingDeadWood; line != nil {
+		v.positive("lyingDeadWood length", line.Length)
+		v.nonNegative("lyingDeadWood density", line.Density)
+		for i, piece := range line.Pieces {
+			v.nonNegative(fmt.Sprintf("lyingDeadWood pieces[%d] diameter", i), piece.Diameter)
+			checkKnown(v, fmt.Sprintf("lyingDeadWood pieces[%d] decay", i), piece.Decay, decayClassNames)
+		}
+	}
+	for i, sample := range plot.Litter {
+		v.positive(fmt.Sprintf("litter[%d] area", i), sample.Area)
+		v.nonNegative(fmt.Sprintf("litter[%d] dryMass", i), sample.DryMass)
+	}
+}
+
+func (v *validator) validateZone(zone MonitoringZone) {
+	v.positive("area", zone.Area)
+	v.check(zone.AbovegroundBiomass >= 0, "abovegroundBiomass", zone.AbovegroundBiomass, RuleNonNegative, nil)
+	checkKnown(v, "forestType", zone.ForestType, forestTypeNames)
+	checkKnown(v, "species", zone.Species, treeSpeciesNames)
+	checkKnown(v, "rainfall", zone.Rainfall, rainfallTypeNames)
+	if zone.EcologicalZone != nil {
+		checkKnown(v, "ecologicalZone", *zone.EcologicalZone, ecologicalZoneNames)
+	}
+	if zone.Equation == nil && zone.EquationName != "" {
+		_, err := AllometricEquationByName(zone.EquationName)
+		v.check(err == nil, "equation", zone.EquationName, RuleKnown, nil)
+	}
+	checkKnown(v, "deadWood", zone.DeadWood, poolModeNames)
+	checkKnown(v, "litter", zone.Litter, poolModeNames)
+	if shrubs := zone.Shrubs; shrubs != nil {
+		v.fraction("shrubs crownCover", shrubs.CrownCover)
+		v.fraction("shrubs baselineCrownCover", shrubs.BaselineCrownCover)
+		v.positive("shrubs forestBiomass", shrubs.ForestBiomass)
+		v.nonNegative("shrubs ratio", shrubs.Ratio)
+		v.nonNegative("shrubs rootShoot", shrubs.RootShoot)
+		v.fraction("shrubs fraction", shrubs.Fraction)
+	}
+	if soil := zone.Soil; soil != nil {
+		checkKnown(v, "soil climate", soil.Climate, climateRegionNames)
+		checkKnown(v, "soil soilType", soil.SoilType, soilTypeNames)
+		if soil.Reference.IsZero() {
+			v.positive("soil reference", ReferenceSOC(soil.Climate, soil.SoilType))
+		} else {
+			v.positive("soil reference", soil.Reference)
+		}
+		v.nonNegative("soil landUse", soil.LandUse)
+		v.nonNegative("soil management", soil.Management)
+		v.nonNegative("soil input", soil.Input)
+	}
+	if model := zone.BaselineModel; model != nil {
+		v.check(zone.Baseline.IsZero(), "baseline", zone.Baseline, RuleCalculated, nil)
+		v.check(len(model.Plots) > 0, "baselineModel plots", len(model.Plots), RuleRequired, ErrNoPlots)
+		v.nonNegative("baselineModel growthRate", model.GrowthRate)
+		v.nonNegative("baselineModel maxBiomass", model.MaxBiomass)
+		v.nonNegative("baselineModel nonTreeRemovals", model.NonTreeRemovals)
+		v.fraction("baselineModel fraction", model.Fraction)
+		if model.Growth != nil {
+			v.validateGrowth("baselineModel growth", model.Growth)
+		} else if model.ChapmanRichards != nil {
+			v.validateGrowth("baselineModel chapmanRichards", *model.ChapmanRichards)
+		}
+		baselinePlots := map[string]bool{}
+		for _, plot := range model.Plots {
+			// Baseline plots are reported with the prefix to tell them from
+			// the plots of the project
+			v.plot = "baseline " + plot.ID
+			v.check(!baselinePlots[plot.ID], "id", plot.ID, RuleUnique, nil)
+			baselinePlots[plot.ID] = true
+			v.validatePlot(plot)
+		}
+		v.plot = ""
+	}
+	v.check(len(zone.Plots) > 0, "plots", len(zone.Plots), RuleRequired, ErrNoPlots)
+	v.check(len(zone.Plots) != 1, "plots", len(zone.Plots), RuleMinPlots, nil)
+	plots := map[string]bool{}
+	for _, plot := range zone.Plots {
+		v.plot = plot.ID
+		v.check(!plots[plot.ID], "id", plot.ID, RuleUnique, nil)
+		plots[plot.ID] = true
+		v.validatePlot(plot)
+	}
+	v.plot = ""
+}
+
+// Equation names must be known and the scientific names must not repeat after
+// normalization
+func (v *validator) validateEquations(s Stage) {
+	keys := map[string]bool{}
+	for _, name := range sortedKeys(s.Equations) {
+		key := scientificNameKey(name)
+		v.check(key != "", "equations", name, RuleRequired, nil)
+		v.check(!keys[key], "equations", name, RuleUnique, nil)
+		keys[key] = true
+	}
+	names := map[string]bool{}
+	for _, name := range sortedKeys(s.EquationNames) {
+		key := scientificNameKey(name)
+		v.check(key != "", "equations", name, RuleRequired, nil)
+		v.check(!names[key], "equations", name, RuleUnique, nil)
+		names[key] = true
+		_, err := AllometricEquationByName(s.EquationNames[name])
+		v.check(err == nil, "equations "+name, s.EquationNames[name], RuleKnown, nil)
+	}
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *validator) validateFertilizer(fertilizer Fertilizer, prefix string) {
+	v.nonNegative(prefix+"applications", fertilizer.Applications)
+	v.nonNegative(prefix+"massSynthFertz", fertilizer.MassSynthFertz)
+	v.optional(v.nonNegative, prefix+"nContSynthFertz", fertilizer.NContSynthFertz)
+	v.nonNegative(prefix+"massOrgFertz", fertilizer.MassOrgFertz)
+	v.optional(v.nonNegative, prefix+"nContOrgFertz", fertilizer.NContOrgFertz)
+	v.optional(v.fraction, prefix+"nitrOxdEmissSOC", fertilizer.NitrOxdEmissSOC)
+	v.optional(v.fraction, prefix+"allFractSynth", fertilizer.AllFractSynth)
+	v.optional(v.fraction, prefix+"allFractOrg", fertilizer.AllFractOrg)
+	v.optional(v.fraction, prefix+"nitrOxdEmissWS", fertilizer.NitrOxdEmissWS)
+	v.optional(v.fraction, prefix+"nFractSoil", fertilizer.NFractSoil)
+	v.optional(v.fraction, prefix+"nitrOxdEmissLR", fertilizer.NitrOxdEmissLR)
+	v.optional(v.nonNegative, prefix+"gWarmingPotentl", fertilizer.GWarmingPotentl)
+}
+
+func (v *validator) validateBurning(burning BurningEvent, prefix string) {
+	checkKnown(v, prefix+"forestType", burning.ForestType, forestTypeNames)
+	v.nonNegative(prefix+"area", burning.Area)
+	v.nonNegative(prefix+"biomass", burning.Biomass)
+	v.fraction(prefix+"combustionFactor", burning.CombustionFactor)
+	v.nonNegative(prefix+"emissionFactorCH4", burning.EmissionFactorCH4)
+	v.nonNegative(prefix+"emissionFactorN2O", burning.EmissionFactorN2O)
+	v.nonNegative(prefix+"gwpCH4", burning.GWPCH4)
+	v.nonNegative(prefix+"gwpN2O", burning.GWPN2O)
+}
+
+// Check the burning inputs, returns ValidationErrors with all problems found
+func (b BurningEvent) Validate() error {
+	v := &validator{}
+	v.validateBurning(b, "")
+	return v.errs.err()
+}
+
+func (v *validator) validateFuel(fuel FuelConsumption, prefix string) {
+	checkKnown(v, prefix+"fuel", fuel.Fuel, fuelTypeNames)
+	v.nonNegative(prefix+"volume", fuel.Volume)
+	v.nonNegative(prefix+"mass", fuel.Mass)
+	v.nonNegative(prefix+"netCalorificValue", fuel.NetCalorificValue)
+	v.nonNegative(prefix+"density", fuel.Density)
+	v.nonNegative(prefix+"emissionFactorCO2", fuel.EmissionFactorCO2)
+	v.nonNegative(prefix+"emissionFactorCH4", fuel.EmissionFactorCH4)
+	v.nonNegative(prefix+"emissionFactorN2O", fuel.EmissionFactorN2O)
+	v.nonNegative(prefix+"gwpCH4", fuel.GWPCH4)
+	v.nonNegative(prefix+"gwpN2O", fuel.GWPN2O)
+}
+
+// Check the fuel consumption inputs, returns ValidationErrors with all problems
+// found
+func (f FuelConsumption) Validate() error {
+	v := &validator{}
+	v.validateFuel(f, "")
+	return v.errs.err()
+}
+
+func (v *validator) validateDisplacement(displacement Displacement, prefix string) {
+	v.nonNegative(prefix+"area", displacement.Area)
+	v.fraction(prefix+"forestFraction", displacement.ForestFraction)
+	v.nonNegative(prefix+"biomass", displacement.Biomass)
+	v.nonNegative(prefix+"rootShoot", displacement.RootShoot)
+	v.fraction(prefix+"fraction", displacement.Fraction)
+}
+
+func (v *validator) validateFuelwood(collection FuelwoodCollection, prefix string) {
+	v.nonNegative(prefix+"volume", collection.Volume)
+	v.nonNegative(prefix+"density", collection.Density)
+	v.fraction(prefix+"nonRenewable", collection.NonRenewable)
+	v.fraction(prefix+"fraction", collection.Fraction)
+}
+
+// Check the displacement inputs, returns ValidationErrors with all problems
+// found
+func (d Displacement) Validate() error {
+	v := &validator{}
+	v.validateDisplacement(d, "")
+	return v.errs.err()
+}
+
+// Check the fuelwood inputs, returns ValidationErrors with all problems found
+func (f FuelwoodCollection) Validate() error {
+	v := &validator{}
+	v.validateFuelwood(f, "")
+	return v.errs.err()
+}
+
+// Check the fertilizer inputs, returns ValidationErrors with all problems found
+func (f Fertilizer) Validate() error {
+	v := &validator{}
+	v.validateFertilizer(f, "")
+	return v.errs.err()
+}
+
+// Check the tree inputs, returns ValidationErrors with all problems found
+func (t Tree) Validate() error {
+	v := &validator{tree: t.ID}
+	v.validateTree(t)
+	return v.errs.err()
+}
+
+// Check the plot and its trees, returns ValidationErrors with all problems
+// found
+func (p Plot) Validate() error {
+	v := &validator{plot: p.ID}
+	v.validatePlot(p)
+	return v.errs.err()
+}
+
+// Check the monitoring zone, its plots and trees, returns ValidationErrors with
+// all problems found
+func (z MonitoringZone) Validate() error {
+	v := &validator{zone: z.ID}
+	v.validateZone(z)
+	return v.errs.err()
+}
+
+// Check every input of the stage, returns ValidationErrors with all problems
+// found in the dataset rather than failing on the first
+func (s Stage) Validate() error {
+	v := &validator{}
+	v.check(len(s.Zones) > 0, "zones", len(s.Zones), RuleRequired, ErrNoMonitoringZones)
+	v.nonNegative("deltaTime", s.DeltaTime)
+	v.fraction("leakage", s.Leakage)
+	calculated := len(s.Displacements) > 0 || len(s.Fuelwood) > 0
+	v.check(!calculated || s.Leakage.IsZero(), "leakage", s.Leakage, RuleCalculated, nil)
+	for _, displacement := range s.Displacements {
+		v.validateDisplacement(displacement, "displacement ")
+	}
+	for _, collection := range s.Fuelwood {
+		v.validateFuelwood(collection, "fuelwood ")
+	}
+	v.nonNegative("otherEmissions", s.OtherEmissions)
+	v.optional(v.fraction, "bufferPercent", s.BufferPercent)
+	v.optional(v.fraction, "holdersPercent", s.HoldersPercent)
+	v.check(s.Confidence >= 0 && s.Confidence < 1, "confidence", s.Confidence, RuleFraction, nil)
+	checkKnown(v, "standard", s.Standard, standardNames)
+	if s.Parameters != nil {
+		v.validateParameterSet("parameters ", s.Parameters)
+	}
+	checkKnown(v, "gwp", s.GWP, gwpSetNames)
+	if s.Discount != nil {
+		v.validateDiscountTable("discount", *s.Discount)
+	}
+	if s.LinearDiscount != nil {
+		v.check(s.Discount == nil, "linearDiscount", s.LinearDiscount.String(), RuleExclusive, nil)
+		v.validateLinearDiscount("linearDiscount", *s.LinearDiscount)
+	}
+	if s.Schedule != nil {
+		v.validateSchedule("schedule", s.Schedule)
+	}
+	v.validateEquations(s)
+	for _, fertilizer := range s.Fertilizers {
+		v.validateFertilizer(fertilizer, "fertilizer ")
+	}
+	for _, burning := range s.Burning {
+		v.validateBurning(burning, "burning ")
+	}
+	for _, fuel := range s.Fuels {
+		v.validateFuel(fuel, "fuel ")
+	}
+	zones := map[string]bool{}
+	for _, zone := range s.Zones {
+		v.zone = zone.ID
+		v.check(!zones[zone.ID], "id", zone.ID, RuleUnique, nil)
+		zones[zone.ID] = true
+		v.validateZone(zone)
+	}
+	return v.errs.err()
+}
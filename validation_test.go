@@ -0,0 +1,122 @@
+package carbon_calc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestStageValidate(t *testing.T) {
+	if err := testStage().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	stage := testStage()
+	stage.Leakage = decimal.NewFromFloat(1.5)
+	stage.Zones[0].Plots[1].Area = decimal.Zero
+	stage.Zones[0].Plots[2].Trees[0].Radius = decimal.NewFromFloat(-0.05)
+	stage.Zones[1].Plots[0].Trees[2].Fraction = Optional(decimal.NewFromFloat(47))
+	stage.Zones[1].Plots = stage.Zones[1].Plots[:1]
+	stage.Zones = append(stage.Zones, MonitoringZone{ID: "zone-1", Area: decimal.New(2, 0)})
+
+	type Test struct {
+		zone, plot, tree, field, rule string
+	}
+	tests := []Test{
+		{"", "", "", "leakage", RuleFraction},
+		{"zone-1", "plot-2", "", "area", RulePositive},
+		{"zone-1", "plot-3", "tree-6", "radius", RulePositive},
+		{"zone-2", "", "", "plots", RuleMinPlots},
+		{"zone-2", "plot-4", "tree-11", "fraction", RuleFraction},
+		{"zone-1", "", "", "id", RuleUnique},
+		{"zone-1", "", "", "plots", RuleRequired},
+	}
+	err := stage.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expect ValidationErrors, have: %v", err)
+	}
+	if len(errs) != len(tests) {
+		t.Fatalf("expect: %d, have: %d\n%v", len(tests), len(errs), err)
+	}
+	for i, tt := range tests {
+		e := errs[i]
+		if e.Zone != tt.zone || e.Plot != tt.plot || e.Tree != tt.tree || e.Field != tt.field || e.Rule != tt.rule {
+			t.Fatalf("Test number %d, expect: %v, have: %v", i, tt, e)
+		}
+	}
+	if !errors.Is(err, ErrNoPlots) || !errors.Is(fmt.Errorf("stage: %w", err), ErrNoPlots) {
+		t.Fatalf("expect: %v", ErrNoPlots)
+	}
+	if errors.Is(err, ErrZeroPlotArea) {
+		t.Fatalf("unexpected: %v", ErrZeroPlotArea)
+	}
+	if have := errs.Errors(); len(have) != len(errs) || have[0] != error(errs[0]) {
+		t.Fatalf("expect: %v, have: %v", errs, have)
+	}
+	if _, err := stage.Calculate(); !errors.As(err, &errs) {
+		t.Fatalf("expect ValidationErrors, have: %v", err)
+	}
+}
+
+func TestZoneEnumsValidate(t *testing.T) {
+	unknown := EcologicalZone(100)
+	tests := []struct {
+		change func(zone *MonitoringZone)
+		field  string
+	}{
+		{func(zone *MonitoringZone) { zone.ForestType = ForestType(10) }, "forestType"},
+		{func(zone *MonitoringZone) { zone.Species = TreeSpecies(10) }, "species"},
+		{func(zone *MonitoringZone) { zone.Rainfall = RainfallType(10) }, "rainfall"},
+		{func(zone *MonitoringZone) { zone.EcologicalZone = &unknown }, "ecologicalZone"},
+	}
+	for i, tt := range tests {
+		stage := testStage()
+		tt.change(&stage.Zones[0])
+		var errs ValidationErrors
+		if err := stage.Validate(); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != tt.field || errs[0].Rule != RuleKnown {
+			t.Fatalf("Test number %d, expect %s %s, have: %v", i, tt.field, RuleKnown, err)
+		}
+	}
+}
+
+func TestTreeValidate(t *testing.T) {
+	type Test struct {
+		tree   Tree
+		fields []string
+	}
+	tests := []Test{
+		{testTree("tree-1", 0.05, 5), nil},
+		{testTree("tree-2", 0.02, 1.2), nil},
+		{testTree("tree-3", 0, -1), []string{"radius", "height"}},
+		{Tree{ID: "tree-4", Radius: decimal.NewFromFloat(0.05), Height: decimal.New(5, 0), Density: Optional(decimal.New(-1, 0)), Ratio: Optional(decimal.New(-1, 0))}, []string{"density", "ratio"}},
+	}
+	for i, tt := range tests {
+		err := tt.tree.Validate()
+		if tt.fields == nil {
+			if err != nil {
+				t.Fatalf("Test number %d, %v", i, err)
+			}
+			continue
+		}
+		var errs ValidationErrors
+		if !errors.As(err, &errs) || len(errs) != len(tt.fields) {
+			t.Fatalf("Test number %d, expect: %v, have: %v", i, tt.fields, err)
+		}
+		for j, field := range tt.fields {
+			if errs[j].Field != field || errs[j].Tree != tt.tree.ID {
+				t.Fatalf("Test number %d, expect: %s, have: %s", i, field, errs[j].Field)
+			}
+		}
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &ValidationError{Zone: "zone-1", Plot: "plot-2", Field: "area", Value: "0", Rule: RulePositive}
+	expect := "zone zone-1, plot plot-2, area must be positive, have: 0"
+	if err.Error() != expect {
+		t.Fatalf("expect: %s, have: %s", expect, err.Error())
+	}
+}